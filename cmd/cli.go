@@ -0,0 +1,330 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/aminjonshermatov/wallet/pkg/types"
+	"github.com/aminjonshermatov/wallet/pkg/wallet"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const usage = `usage: wallet [-dir DIR] [-json] COMMAND [ARGS]
+
+The wallet is loaded from DIR, changed by the command and saved back.
+
+commands:
+  register PHONE                      register an account
+  deposit ACCOUNT AMOUNT              deposit to an account
+  pay ACCOUNT AMOUNT CATEGORY         pay from an account
+  reject PAYMENT                      reject a payment
+  repeat PAYMENT                      repeat a payment
+  favorite add PAYMENT NAME           make a favorite of a payment
+  favorite pay FAVORITE               pay from a favorite
+  favorite list ACCOUNT               list account favorites
+  history ACCOUNT                     list account payments, oldest first
+  sum [GOROUTINES]                    sum all payments
+  export DIR                          export the wallet to another directory
+  import DIR                          import another directory into the wallet
+  verify                              check checksums of DIR without loading it
+  migrate                             upgrade dump files of DIR to the current version
+`
+
+var errUsage = errors.New("invalid arguments")
+
+// command changes or reads the service and returns what to print.
+// Commands that change the service set save, so the wallet is exported back.
+// Commands with inspect work on the directory itself and don't load the wallet.
+type command struct {
+	args	int
+	save	bool
+	run		func(svc *wallet.Service, args []string) (interface{}, error)
+	inspect	func(dir string, args []string) (interface{}, error)
+}
+
+// failure is output that makes the command exit with 1 after it is printed.
+type failure interface {
+	failed() bool
+}
+
+var commands = map[string]command{
+	"register": {args: 1, save: true, run: func(svc *wallet.Service, args []string) (interface{}, error) {
+		account, err := svc.RegisterAccount(types.Phone(args[0]))
+		if err != nil {
+			return nil, err
+		}
+		return newAccountOutput(account), nil
+	}},
+	"deposit": {args: 2, save: true, run: func(svc *wallet.Service, args []string) (interface{}, error) {
+		accountID, err := parseAccountID(args[0])
+		if err != nil {
+			return nil, err
+		}
+		amount, err := parseAmount(args[1])
+		if err != nil {
+			return nil, err
+		}
+
+		err = svc.Deposit(accountID, amount)
+		if err != nil {
+			return nil, err
+		}
+
+		account, err := svc.FindAccountByID(accountID)
+		if err != nil {
+			return nil, err
+		}
+		return newAccountOutput(account), nil
+	}},
+	"pay": {args: 3, save: true, run: func(svc *wallet.Service, args []string) (interface{}, error) {
+		accountID, err := parseAccountID(args[0])
+		if err != nil {
+			return nil, err
+		}
+		amount, err := parseAmount(args[1])
+		if err != nil {
+			return nil, err
+		}
+
+		payment, err := svc.Pay(accountID, amount, types.PaymentCategory(args[2]))
+		if err != nil {
+			return nil, err
+		}
+		return newPaymentOutput(payment), nil
+	}},
+	"reject": {args: 1, save: true, run: func(svc *wallet.Service, args []string) (interface{}, error) {
+		err := svc.Reject(args[0])
+		if err != nil {
+			return nil, err
+		}
+
+		payment, err := svc.FindPaymentByID(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return newPaymentOutput(payment), nil
+	}},
+	"repeat": {args: 1, save: true, run: func(svc *wallet.Service, args []string) (interface{}, error) {
+		payment, err := svc.Repeat(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return newPaymentOutput(payment), nil
+	}},
+	"favorite add": {args: 2, save: true, run: func(svc *wallet.Service, args []string) (interface{}, error) {
+		favorite, err := svc.FavoritePayment(args[0], args[1])
+		if err != nil {
+			return nil, err
+		}
+		return newFavoriteOutput(favorite), nil
+	}},
+	"favorite pay": {args: 1, save: true, run: func(svc *wallet.Service, args []string) (interface{}, error) {
+		payment, err := svc.PayFromFavorite(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return newPaymentOutput(payment), nil
+	}},
+	"favorite list": {args: 1, run: func(svc *wallet.Service, args []string) (interface{}, error) {
+		accountID, err := parseAccountID(args[0])
+		if err != nil {
+			return nil, err
+		}
+
+		favorites, err := svc.AccountFavorites(accountID)
+		if err != nil {
+			return nil, err
+		}
+		res := make(favoritesOutput, 0, len(favorites))
+		for i := range favorites {
+			res = append(res, newFavoriteOutput(&favorites[i]))
+		}
+		return res, nil
+	}},
+	"history": {args: 1, run: func(svc *wallet.Service, args []string) (interface{}, error) {
+		accountID, err := parseAccountID(args[0])
+		if err != nil {
+			return nil, err
+		}
+
+		payments, err := svc.ExportAccountHistoryBetween(accountID, time.Time{}, time.Time{})
+		if err != nil {
+			return nil, err
+		}
+		res := make(paymentsOutput, 0, len(payments))
+		for i := range payments {
+			res = append(res, newPaymentOutput(&payments[i]))
+		}
+		return res, nil
+	}},
+	"sum": {args: -1, run: func(svc *wallet.Service, args []string) (interface{}, error) {
+		goroutines := 1
+		if len(args) > 1 {
+			return nil, errUsage
+		}
+		if len(args) == 1 {
+			var err error
+			goroutines, err = strconv.Atoi(args[0])
+			if err != nil || goroutines < 1 {
+				return nil, fmt.Errorf("%w: goroutines must be a positive number", errUsage)
+			}
+		}
+		return sumOutput{Sum: svc.SumPayments(goroutines)}, nil
+	}},
+	"export": {args: 1, run: func(svc *wallet.Service, args []string) (interface{}, error) {
+		err := svc.Export(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return messageOutput{Message: "exported to " + args[0]}, nil
+	}},
+	"import": {args: 1, save: true, run: func(svc *wallet.Service, args []string) (interface{}, error) {
+		err := svc.Import(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return messageOutput{Message: "imported from " + args[0]}, nil
+	}},
+	"migrate": {args: 0, inspect: func(dir string, args []string) (interface{}, error) {
+		migrated, err := wallet.Migrate(dir)
+		if err != nil {
+			return nil, err
+		}
+		if len(migrated) == 0 {
+			return messageOutput{Message: "up to date"}, nil
+		}
+		return messageOutput{Message: "migrated " + strings.Join(migrated, ", ")}, nil
+	}},
+	"verify": {args: 0, inspect: func(dir string, args []string) (interface{}, error) {
+		corruptions, err := wallet.Verify(dir)
+		if err != nil {
+			return nil, err
+		}
+		res := make(verifyOutput, 0, len(corruptions))
+		for _, corruption := range corruptions {
+			res = append(res, corruptionOutput(corruption))
+		}
+		return res, nil
+	}},
+}
+
+// run executes the command line and returns the exit code:
+// 0 on success, 1 when the command failed and 2 on invalid usage.
+func run(args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("wallet", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		_, _ = fmt.Fprint(stderr, usage)
+	}
+	dir := flags.String("dir", "data", "wallet data directory")
+	asJSON := flags.Bool("json", false, "print results as JSON")
+	err := flags.Parse(args)
+	if err != nil {
+		return 2
+	}
+
+	name, cmd, cmdArgs, ok := findCommand(flags.Args())
+	if !ok || (cmd.args >= 0 && len(cmdArgs) != cmd.args) {
+		flags.Usage()
+		return 2
+	}
+
+	if cmd.inspect != nil {
+		res, err := cmd.inspect(*dir, cmdArgs)
+		if err != nil {
+			return fail(stderr, *asJSON, err)
+		}
+		err = printResult(stdout, *asJSON, res)
+		if err != nil {
+			return fail(stderr, *asJSON, err)
+		}
+		if f, ok := res.(failure); ok && f.failed() {
+			return 1
+		}
+		return 0
+	}
+
+	svc := wallet.NewService()
+	err = svc.Import(*dir)
+	if err != nil {
+		return fail(stderr, *asJSON, fmt.Errorf("can't load %s: %w", *dir, err))
+	}
+
+	res, err := cmd.run(svc, cmdArgs)
+	if errors.Is(err, errUsage) {
+		_, _ = fmt.Fprintf(stderr, "wallet %s: %v\n", name, err)
+		flags.Usage()
+		return 2
+	}
+	if err != nil {
+		return fail(stderr, *asJSON, err)
+	}
+
+	if cmd.save {
+		err = svc.Export(*dir)
+		if err != nil {
+			return fail(stderr, *asJSON, fmt.Errorf("can't save %s: %w", *dir, err))
+		}
+	}
+
+	err = printResult(stdout, *asJSON, res)
+	if err != nil {
+		return fail(stderr, *asJSON, err)
+	}
+	return 0
+}
+
+// findCommand matches one or two words of args with a command name.
+func findCommand(args []string) (string, command, []string, bool) {
+	if len(args) >= 2 {
+		if cmd, ok := commands[args[0]+" "+args[1]]; ok {
+			return args[0] + " " + args[1], cmd, args[2:], true
+		}
+	}
+	if len(args) >= 1 {
+		if cmd, ok := commands[args[0]]; ok {
+			return args[0], cmd, args[1:], true
+		}
+	}
+	return "", command{}, nil, false
+}
+
+func fail(stderr io.Writer, asJSON bool, err error) int {
+	if asJSON {
+		_ = json.NewEncoder(stderr).Encode(errorOutput{Error: err.Error()})
+	} else {
+		_, _ = fmt.Fprintf(stderr, "error: %v\n", err)
+	}
+	return 1
+}
+
+func printResult(w io.Writer, asJSON bool, res interface{}) error {
+	if asJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(res)
+	}
+
+	_, err := fmt.Fprintln(w, res)
+	return err
+}
+
+func parseAccountID(value string) (int64, error) {
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid account id %q", errUsage, value)
+	}
+	return id, nil
+}
+
+func parseAmount(value string) (types.Money, error) {
+	amount, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid amount %q", errUsage, value)
+	}
+	return types.Money(amount), nil
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runCLI runs the command line against dir and returns the exit code and outputs.
+func runCLI(t *testing.T, dir string, args ...string) (int, string, string) {
+	t.Helper()
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	code := run(append([]string{"-dir", dir}, args...), stdout, stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRun_persistsBetweenRuns(t *testing.T) {
+	dir := t.TempDir()
+
+	code, out, errOut := runCLI(t, dir, "register", "+992000000001")
+	if code != 0 || !strings.Contains(out, "account 1 +992000000001") {
+		t.Fatalf("register: code %d, out %q, err %q", code, out, errOut)
+	}
+
+	code, out, errOut = runCLI(t, dir, "deposit", "1", "1000")
+	if code != 0 || !strings.Contains(out, "balance 1000") {
+		t.Fatalf("deposit: code %d, out %q, err %q", code, out, errOut)
+	}
+
+	code, out, errOut = runCLI(t, dir, "-json", "pay", "1", "300", "food")
+	if code != 0 {
+		t.Fatalf("pay: code %d, err %q", code, errOut)
+	}
+	var payment paymentOutput
+	err := json.Unmarshal([]byte(out), &payment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payment.Amount != 300 || payment.Category != "food" || payment.Status != "INPROGRESS" {
+		t.Fatalf("pay: invalid payment %+v", payment)
+	}
+
+	code, _, errOut = runCLI(t, dir, "favorite", "add", payment.ID, "osh")
+	if code != 0 {
+		t.Fatalf("favorite add: code %d, err %q", code, errOut)
+	}
+
+	code, _, errOut = runCLI(t, dir, "repeat", payment.ID)
+	if code != 0 {
+		t.Fatalf("repeat: code %d, err %q", code, errOut)
+	}
+
+	code, out, errOut = runCLI(t, dir, "reject", payment.ID)
+	if code != 0 || !strings.Contains(out, "FAIL") {
+		t.Fatalf("reject: code %d, out %q, err %q", code, out, errOut)
+	}
+
+	code, out, errOut = runCLI(t, dir, "-json", "history", "1")
+	if code != 0 {
+		t.Fatalf("history: code %d, err %q", code, errOut)
+	}
+	var history []paymentOutput
+	err = json.Unmarshal([]byte(out), &history)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("history: got %d payments, want 2", len(history))
+	}
+
+	code, out, errOut = runCLI(t, dir, "sum", "2")
+	if code != 0 || strings.TrimSpace(out) != "sum 300" {
+		t.Fatalf("sum: code %d, out %q, err %q", code, out, errOut)
+	}
+}
+
+func TestRun_exportImport(t *testing.T) {
+	src := t.TempDir()
+	dst := t.TempDir()
+	other := t.TempDir()
+
+	code, _, errOut := runCLI(t, src, "register", "+992000000001")
+	if code != 0 {
+		t.Fatalf("register: code %d, err %q", code, errOut)
+	}
+	code, _, errOut = runCLI(t, src, "export", other)
+	if code != 0 {
+		t.Fatalf("export: code %d, err %q", code, errOut)
+	}
+	code, _, errOut = runCLI(t, dst, "import", other)
+	if code != 0 {
+		t.Fatalf("import: code %d, err %q", code, errOut)
+	}
+
+	code, out, errOut := runCLI(t, dst, "deposit", "1", "10")
+	if code != 0 || !strings.Contains(out, "balance 10") {
+		t.Fatalf("deposit after import: code %d, out %q, err %q", code, out, errOut)
+	}
+}
+
+func TestRun_errors(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct{
+		name	string
+		args	[]string
+		code	int
+	}{
+		{"no command", nil, 2},
+		{"unknown command", []string{"transfer"}, 2},
+		{"missing arguments", []string{"deposit", "1"}, 2},
+		{"invalid amount", []string{"deposit", "1", "ten"}, 2},
+		{"account not found", []string{"deposit", "1", "10"}, 1},
+		{"invalid phone", []string{"register", "abc"}, 1},
+	}
+
+	for _, tt := range tests {
+		code, _, _ := runCLI(t, dir, tt.args...)
+		if code != tt.code {
+			t.Errorf("%s: got exit code %d, want %d", tt.name, code, tt.code)
+		}
+	}
+
+	code, _, errOut := runCLI(t, dir, "-json", "deposit", "1", "10")
+	var res errorOutput
+	err := json.Unmarshal([]byte(errOut), &res)
+	if code != 1 || err != nil || res.Error == "" {
+		t.Errorf("json error: code %d, err %v, out %q", code, err, errOut)
+	}
+}
+
+func TestRun_verify(t *testing.T) {
+	dir := t.TempDir()
+
+	code, _, errOut := runCLI(t, dir, "register", "+992000000001")
+	if code != 0 {
+		t.Fatalf("register: code %d, err %q", code, errOut)
+	}
+
+	code, out, errOut := runCLI(t, dir, "verify")
+	if code != 0 || strings.TrimSpace(out) != "ok" {
+		t.Fatalf("verify: code %d, out %q, err %q", code, out, errOut)
+	}
+
+	err := os.WriteFile(filepath.Join(dir, "MANIFEST"), []byte("{\"generation\":"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, out, _ = runCLI(t, dir, "-json", "verify")
+	var res verifyOutput
+	err = json.Unmarshal([]byte(out), &res)
+	if code != 1 || err != nil || len(res) != 1 || res[0].File != "MANIFEST" {
+		t.Errorf("verify corrupted: code %d, err %v, out %q", code, err, out)
+	}
+}
+
+func TestRun_migrate(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "accounts.dump"), []byte("1;+992000000001;9000\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, out, errOut := runCLI(t, dir, "migrate")
+	if code != 0 || strings.TrimSpace(out) != "migrated accounts.dump" {
+		t.Fatalf("migrate: code %d, out %q, err %q", code, out, errOut)
+	}
+
+	code, out, errOut = runCLI(t, dir, "migrate")
+	if code != 0 || strings.TrimSpace(out) != "up to date" {
+		t.Fatalf("migrate again: code %d, out %q, err %q", code, out, errOut)
+	}
+
+	code, out, errOut = runCLI(t, dir, "deposit", "1", "1000")
+	if code != 0 || !strings.Contains(out, "balance 10000") {
+		t.Fatalf("deposit: code %d, out %q, err %q", code, out, errOut)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"github.com/aminjonshermatov/wallet/pkg/grpcserver"
+	"github.com/aminjonshermatov/wallet/pkg/wallet"
+	"google.golang.org/grpc"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func main() {
+	addr := flag.String("addr", ":9998", "address to listen on")
+	dir := flag.String("dir", "", "directory to import the wallet from on start and export it to on stop")
+	walPath := flag.String("wal", "", "write-ahead log replayed on start and truncated on stop, requires -dir")
+	flag.Parse()
+
+	err := run(*addr, *dir, *walPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// run serves the wallet until SIGINT or SIGTERM and checkpoints it to dir on stop,
+// it returns after the log is closed.
+func run(addr string, dir string, walPath string) error {
+	svc, wal, err := wallet.Restore(dir, walPath)
+	if err != nil {
+		return err
+	}
+	if wal != nil {
+		defer wal.Close()
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	server := grpc.NewServer()
+	grpcserver.Register(server, svc)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		<-signals
+		server.GracefulStop()
+	}()
+
+	log.Printf("listening on %s", addr)
+	err = server.Serve(listener)
+	if err != nil {
+		return err
+	}
+
+	if dir == "" {
+		return nil
+	}
+	return svc.Checkpoint(dir)
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"github.com/aminjonshermatov/wallet/pkg/types"
+	"github.com/aminjonshermatov/wallet/pkg/wallet"
+	"strings"
+	"time"
+)
+
+// Outputs are printed with fmt in the human mode and encoded as JSON in the JSON mode.
+
+type accountOutput struct {
+	ID			int64				`json:"id"`
+	Phone		types.Phone			`json:"phone"`
+	Balance		types.Money			`json:"balance"`
+	Status		types.AccountStatus	`json:"status"`
+	CreditLimit	types.Money			`json:"creditLimit"`
+}
+
+func newAccountOutput(account *types.Account) accountOutput {
+	return accountOutput{
+		ID:				account.ID,
+		Phone:			account.Phone,
+		Balance:		account.Balance,
+		Status:			account.Status,
+		CreditLimit:	account.CreditLimit,
+	}
+}
+
+func (o accountOutput) String() string {
+	return fmt.Sprintf("account %d %s balance %d %s", o.ID, o.Phone, o.Balance, o.Status)
+}
+
+type paymentOutput struct {
+	ID			string					`json:"id"`
+	AccountID	int64					`json:"accountId"`
+	Amount		types.Money				`json:"amount"`
+	Refunded	types.Money				`json:"refunded"`
+	Category	types.PaymentCategory	`json:"category"`
+	Status		types.PaymentStatus		`json:"status"`
+	CreatedAt	time.Time				`json:"createdAt"`
+}
+
+func newPaymentOutput(payment *types.Payment) paymentOutput {
+	return paymentOutput{
+		ID:			payment.ID,
+		AccountID:	payment.AccountID,
+		Amount:		payment.Amount,
+		Refunded:	payment.Refunded,
+		Category:	payment.Category,
+		Status:		payment.Status,
+		CreatedAt:	payment.CreatedAt,
+	}
+}
+
+func (o paymentOutput) String() string {
+	return fmt.Sprintf("payment %s account %d amount %d %s %s", o.ID, o.AccountID, o.Amount, o.Category, o.Status)
+}
+
+type paymentsOutput []paymentOutput
+
+func (o paymentsOutput) String() string {
+	if len(o) == 0 {
+		return "no payments"
+	}
+
+	lines := make([]string, 0, len(o))
+	for _, payment := range o {
+		lines = append(lines, payment.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+type favoriteOutput struct {
+	ID			string					`json:"id"`
+	AccountID	int64					`json:"accountId"`
+	Name		string					`json:"name"`
+	Amount		types.Money				`json:"amount"`
+	Category	types.PaymentCategory	`json:"category"`
+}
+
+func newFavoriteOutput(favorite *types.Favorite) favoriteOutput {
+	return favoriteOutput{
+		ID:			favorite.ID,
+		AccountID:	favorite.AccountID,
+		Name:		favorite.Name,
+		Amount:		favorite.Amount,
+		Category:	favorite.Category,
+	}
+}
+
+func (o favoriteOutput) String() string {
+	return fmt.Sprintf("favorite %s %q account %d amount %d %s", o.ID, o.Name, o.AccountID, o.Amount, o.Category)
+}
+
+type favoritesOutput []favoriteOutput
+
+func (o favoritesOutput) String() string {
+	if len(o) == 0 {
+		return "no favorites"
+	}
+
+	lines := make([]string, 0, len(o))
+	for _, favorite := range o {
+		lines = append(lines, favorite.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+type sumOutput struct {
+	Sum	types.Money	`json:"sum"`
+}
+
+func (o sumOutput) String() string {
+	return fmt.Sprintf("sum %d", o.Sum)
+}
+
+type messageOutput struct {
+	Message	string	`json:"message"`
+}
+
+func (o messageOutput) String() string {
+	return o.Message
+}
+
+type corruptionOutput struct {
+	File	string	`json:"file"`
+	Line	int		`json:"line,omitempty"`
+	Reason	string	`json:"reason"`
+}
+
+type verifyOutput []corruptionOutput
+
+func (o verifyOutput) String() string {
+	if len(o) == 0 {
+		return "ok"
+	}
+
+	lines := make([]string, 0, len(o))
+	for _, corruption := range o {
+		lines = append(lines, wallet.Corruption(corruption).String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (o verifyOutput) failed() bool {
+	return len(o) != 0
+}
+
+type errorOutput struct {
+	Error	string	`json:"error"`
+}
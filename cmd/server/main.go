@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"github.com/aminjonshermatov/wallet/pkg/server"
+	"github.com/aminjonshermatov/wallet/pkg/wallet"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+func main() {
+	addr := flag.String("addr", ":9999", "address to listen on")
+	dir := flag.String("dir", "", "directory to import the wallet from on start and export it to on stop")
+	walPath := flag.String("wal", "", "write-ahead log replayed on start and truncated on stop, requires -dir")
+	flag.Parse()
+
+	err := run(*addr, *dir, *walPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// run serves the wallet until SIGINT or SIGTERM and checkpoints it to dir on stop,
+// it returns after the log is closed.
+func run(addr string, dir string, walPath string) error {
+	svc, wal, err := wallet.Restore(dir, walPath)
+	if err != nil {
+		return err
+	}
+	if wal != nil {
+		defer wal.Close()
+	}
+
+	srv := &http.Server{
+		Addr:		addr,
+		Handler:	server.NewServer(svc),
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10 * time.Second)
+		defer cancel()
+		err := srv.Shutdown(shutdownCtx)
+		if err != nil {
+			log.Print(err)
+		}
+	}()
+
+	log.Printf("listening on %s", addr)
+	err = srv.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	if dir == "" {
+		return nil
+	}
+	return svc.Checkpoint(dir)
+}
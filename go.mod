@@ -2,4 +2,8 @@ module github.com/aminjonshermatov/wallet
 
 go 1.16
 
-require github.com/google/uuid v1.2.0 // indirect
+require (
+	github.com/google/uuid v1.2.0
+	google.golang.org/grpc v1.47.0
+	google.golang.org/protobuf v1.28.1
+)
@@ -0,0 +1,468 @@
+// Package grpcserver exposes wallet.Service as the gRPC WalletService defined in proto/wallet/v1/wallet.proto.
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"github.com/aminjonshermatov/wallet/pkg/types"
+	"github.com/aminjonshermatov/wallet/pkg/wallet"
+	"github.com/aminjonshermatov/wallet/pkg/walletpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"time"
+)
+
+type Server struct {
+	walletpb.UnimplementedWalletServiceServer
+	svc	*wallet.Service
+}
+
+func NewServer(svc *wallet.Service) *Server {
+	return &Server{svc: svc}
+}
+
+// Register adds the WalletService backed by svc to the gRPC server.
+func Register(server *grpc.Server, svc *wallet.Service) {
+	walletpb.RegisterWalletServiceServer(server, NewServer(svc))
+}
+
+func (s *Server) RegisterAccount(ctx context.Context, req *walletpb.RegisterAccountRequest) (*walletpb.Account, error) {
+	account, err := s.svc.RegisterAccount(types.Phone(req.GetPhone()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return accountToProto(account), nil
+}
+
+func (s *Server) FindAccountByID(ctx context.Context, req *walletpb.FindAccountByIDRequest) (*walletpb.Account, error) {
+	account, err := s.svc.FindAccountByID(req.GetAccountId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return accountToProto(account), nil
+}
+
+func (s *Server) Deposit(ctx context.Context, req *walletpb.DepositRequest) (*walletpb.Account, error) {
+	var err error
+	if req.GetIdempotencyKey() != "" {
+		err = s.svc.DepositWithKey(req.GetIdempotencyKey(), req.GetAccountId(), types.Money(req.GetAmount()))
+	} else {
+		err = s.svc.Deposit(req.GetAccountId(), types.Money(req.GetAmount()))
+	}
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return s.FindAccountByID(ctx, &walletpb.FindAccountByIDRequest{AccountId: req.GetAccountId()})
+}
+
+func (s *Server) FreezeAccount(ctx context.Context, req *walletpb.FreezeAccountRequest) (*walletpb.Account, error) {
+	err := s.svc.Freeze(req.GetAccountId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return s.FindAccountByID(ctx, &walletpb.FindAccountByIDRequest{AccountId: req.GetAccountId()})
+}
+
+func (s *Server) UnfreezeAccount(ctx context.Context, req *walletpb.UnfreezeAccountRequest) (*walletpb.Account, error) {
+	err := s.svc.Unfreeze(req.GetAccountId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return s.FindAccountByID(ctx, &walletpb.FindAccountByIDRequest{AccountId: req.GetAccountId()})
+}
+
+func (s *Server) CloseAccount(ctx context.Context, req *walletpb.CloseAccountRequest) (*walletpb.CloseAccountResponse, error) {
+	var paidOut types.Money
+	var err error
+	if req.GetPayout() {
+		paidOut, err = s.svc.CloseWithPayout(req.GetAccountId())
+	} else {
+		err = s.svc.Close(req.GetAccountId())
+	}
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	account, err := s.FindAccountByID(ctx, &walletpb.FindAccountByIDRequest{AccountId: req.GetAccountId()})
+	if err != nil {
+		return nil, err
+	}
+	return &walletpb.CloseAccountResponse{Account: account, PaidOut: int64(paidOut)}, nil
+}
+
+func (s *Server) SetCreditLimit(ctx context.Context, req *walletpb.SetCreditLimitRequest) (*walletpb.Account, error) {
+	err := s.svc.SetCreditLimit(req.GetAccountId(), types.Money(req.GetCreditLimit()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return s.FindAccountByID(ctx, &walletpb.FindAccountByIDRequest{AccountId: req.GetAccountId()})
+}
+
+func (s *Server) Pay(ctx context.Context, req *walletpb.PayRequest) (*walletpb.Payment, error) {
+	amount := types.Money(req.GetAmount())
+	category := types.PaymentCategory(req.GetCategory())
+
+	var payment *types.Payment
+	var err error
+	if req.GetIdempotencyKey() != "" {
+		payment, err = s.svc.PayWithKey(req.GetIdempotencyKey(), req.GetAccountId(), amount, category)
+	} else {
+		payment, err = s.svc.Pay(req.GetAccountId(), amount, category)
+	}
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return paymentToProto(payment), nil
+}
+
+func (s *Server) FindPaymentByID(ctx context.Context, req *walletpb.FindPaymentByIDRequest) (*walletpb.Payment, error) {
+	payment, err := s.svc.FindPaymentByID(req.GetPaymentId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return paymentToProto(payment), nil
+}
+
+func (s *Server) Reject(ctx context.Context, req *walletpb.RejectRequest) (*walletpb.Payment, error) {
+	err := s.svc.Reject(req.GetPaymentId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return s.FindPaymentByID(ctx, &walletpb.FindPaymentByIDRequest{PaymentId: req.GetPaymentId()})
+}
+
+func (s *Server) Repeat(ctx context.Context, req *walletpb.RepeatRequest) (*walletpb.Payment, error) {
+	var payment *types.Payment
+	var err error
+	if req.GetIdempotencyKey() != "" {
+		payment, err = s.svc.RepeatWithKey(req.GetIdempotencyKey(), req.GetPaymentId())
+	} else {
+		payment, err = s.svc.Repeat(req.GetPaymentId())
+	}
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return paymentToProto(payment), nil
+}
+
+func (s *Server) Confirm(ctx context.Context, req *walletpb.ConfirmRequest) (*walletpb.Payment, error) {
+	err := s.svc.Confirm(req.GetPaymentId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return s.FindPaymentByID(ctx, &walletpb.FindPaymentByIDRequest{PaymentId: req.GetPaymentId()})
+}
+
+func (s *Server) Refund(ctx context.Context, req *walletpb.RefundRequest) (*walletpb.Payment, error) {
+	payment, err := s.svc.Refund(req.GetPaymentId(), types.Money(req.GetAmount()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return paymentToProto(payment), nil
+}
+
+func (s *Server) Transfer(ctx context.Context, req *walletpb.TransferRequest) (*walletpb.Payment, error) {
+	payment, err := s.svc.Transfer(req.GetFromAccountId(), req.GetToAccountId(), types.Money(req.GetAmount()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return paymentToProto(payment), nil
+}
+
+func (s *Server) FindPaymentsByAccount(ctx context.Context, req *walletpb.FindPaymentsByAccountRequest) (*walletpb.FindPaymentsByAccountResponse, error) {
+	goroutines := int(req.GetGoroutines())
+	if goroutines < 0 {
+		return nil, status.Error(codes.InvalidArgument, "goroutines must not be negative")
+	}
+	if goroutines == 0 {
+		goroutines = 1
+	}
+
+	payments, err := s.svc.FilterPayments(req.GetAccountId(), goroutines)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	res := &walletpb.FindPaymentsByAccountResponse{Payments: make([]*walletpb.Payment, 0, len(payments))}
+	for i := range payments {
+		res.Payments = append(res.Payments, paymentToProto(&payments[i]))
+	}
+	return res, nil
+}
+
+func (s *Server) FavoritePayment(ctx context.Context, req *walletpb.FavoritePaymentRequest) (*walletpb.Favorite, error) {
+	favorite, err := s.svc.FavoritePayment(req.GetPaymentId(), req.GetName())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return favoriteToProto(favorite), nil
+}
+
+func (s *Server) FindFavoriteByID(ctx context.Context, req *walletpb.FindFavoriteByIDRequest) (*walletpb.Favorite, error) {
+	favorite, err := s.svc.FindFavoriteByID(req.GetFavoriteId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return favoriteToProto(favorite), nil
+}
+
+func (s *Server) ListFavorites(ctx context.Context, req *walletpb.ListFavoritesRequest) (*walletpb.ListFavoritesResponse, error) {
+	favorites, err := s.svc.AccountFavorites(req.GetAccountId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	res := &walletpb.ListFavoritesResponse{Favorites: make([]*walletpb.Favorite, 0, len(favorites))}
+	for i := range favorites {
+		res.Favorites = append(res.Favorites, favoriteToProto(&favorites[i]))
+	}
+	return res, nil
+}
+
+func (s *Server) PayFromFavorite(ctx context.Context, req *walletpb.PayFromFavoriteRequest) (*walletpb.Payment, error) {
+	var payment *types.Payment
+	var err error
+	if req.GetIdempotencyKey() != "" {
+		payment, err = s.svc.PayFromFavoriteWithKey(req.GetIdempotencyKey(), req.GetFavoriteId())
+	} else {
+		payment, err = s.svc.PayFromFavorite(req.GetFavoriteId())
+	}
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return paymentToProto(payment), nil
+}
+
+func (s *Server) RenameFavorite(ctx context.Context, req *walletpb.RenameFavoriteRequest) (*walletpb.Favorite, error) {
+	favorite, err := s.svc.RenameFavorite(req.GetFavoriteId(), req.GetName())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return favoriteToProto(favorite), nil
+}
+
+func (s *Server) UpdateFavoriteAmount(ctx context.Context, req *walletpb.UpdateFavoriteAmountRequest) (*walletpb.Favorite, error) {
+	favorite, err := s.svc.UpdateFavoriteAmount(req.GetFavoriteId(), types.Money(req.GetAmount()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return favoriteToProto(favorite), nil
+}
+
+func (s *Server) ReorderFavorites(ctx context.Context, req *walletpb.ReorderFavoritesRequest) (*walletpb.ListFavoritesResponse, error) {
+	err := s.svc.ReorderFavorites(req.GetAccountId(), req.GetFavoriteIds())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return s.ListFavorites(ctx, &walletpb.ListFavoritesRequest{AccountId: req.GetAccountId()})
+}
+
+func (s *Server) RemoveFavorite(ctx context.Context, req *walletpb.RemoveFavoriteRequest) (*walletpb.RemoveFavoriteResponse, error) {
+	err := s.svc.RemoveFavorite(req.GetFavoriteId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &walletpb.RemoveFavoriteResponse{}, nil
+}
+
+func (s *Server) SetSpendingLimit(ctx context.Context, req *walletpb.SetSpendingLimitRequest) (*walletpb.SpendingLimit, error) {
+	limit := wallet.SpendingLimit{
+		AccountID:	req.GetAccountId(),
+		Period:		wallet.LimitPeriod(req.GetPeriod()),
+		Category:	types.PaymentCategory(req.GetCategory()),
+		Amount:		types.Money(req.GetAmount()),
+	}
+	err := s.svc.SetSpendingLimit(limit.AccountID, limit.Period, limit.Category, limit.Amount)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return spendingLimitToProto(limit), nil
+}
+
+func (s *Server) RemoveSpendingLimit(ctx context.Context, req *walletpb.RemoveSpendingLimitRequest) (*walletpb.RemoveSpendingLimitResponse, error) {
+	err := s.svc.RemoveSpendingLimit(req.GetAccountId(), wallet.LimitPeriod(req.GetPeriod()), types.PaymentCategory(req.GetCategory()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &walletpb.RemoveSpendingLimitResponse{}, nil
+}
+
+func (s *Server) ListSpendingLimits(ctx context.Context, req *walletpb.ListSpendingLimitsRequest) (*walletpb.ListSpendingLimitsResponse, error) {
+	limits, err := s.svc.SpendingLimits(req.GetAccountId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	res := &walletpb.ListSpendingLimitsResponse{Limits: make([]*walletpb.SpendingLimit, 0, len(limits))}
+	for _, limit := range limits {
+		res.Limits = append(res.Limits, spendingLimitToProto(limit))
+	}
+	return res, nil
+}
+
+func (s *Server) ExportAccountHistory(ctx context.Context, req *walletpb.ExportAccountHistoryRequest) (*walletpb.ExportAccountHistoryResponse, error) {
+	from, err := timeFromProto(req.GetFrom())
+	if err != nil {
+		return nil, err
+	}
+	to, err := timeFromProto(req.GetTo())
+	if err != nil {
+		return nil, err
+	}
+
+	payments, err := s.svc.ExportAccountHistoryBetween(req.GetAccountId(), from, to)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	res := &walletpb.ExportAccountHistoryResponse{Payments: make([]*walletpb.Payment, 0, len(payments))}
+	for i := range payments {
+		res.Payments = append(res.Payments, paymentToProto(&payments[i]))
+	}
+	return res, nil
+}
+
+func (s *Server) SumPayments(ctx context.Context, req *walletpb.SumPaymentsRequest) (*walletpb.SumPaymentsResponse, error) {
+	goroutines := int(req.GetGoroutines())
+	if goroutines < 0 {
+		return nil, status.Error(codes.InvalidArgument, "goroutines must not be negative")
+	}
+	if goroutines == 0 {
+		goroutines = 1
+	}
+
+	return &walletpb.SumPaymentsResponse{Sum: int64(s.svc.SumPayments(goroutines))}, nil
+}
+
+func (s *Server) SumPaymentsWithProgress(req *walletpb.SumPaymentsWithProgressRequest, stream walletpb.WalletService_SumPaymentsWithProgressServer) error {
+	ch := s.svc.SumPaymentsWithProgress()
+	for progress := range ch {
+		err := stream.Send(&walletpb.Progress{Part: int32(progress.Part), Result: int64(progress.Result)})
+		if err != nil {
+			// the parts left must still be received, or their goroutines never finish
+			go func() {
+				for range ch {
+				}
+			}()
+			return err
+		}
+	}
+	return nil
+}
+
+// toStatus maps errors of wallet.Service to gRPC status codes.
+func toStatus(err error) error {
+	code := codes.Internal
+	switch {
+	case errors.Is(err, wallet.ErrAccountNotFound),
+		errors.Is(err, wallet.ErrPaymentNotFound),
+		errors.Is(err, wallet.ErrFavoriteNotFound),
+		errors.Is(err, wallet.ErrLimitNotFound):
+		code = codes.NotFound
+
+	case errors.Is(err, wallet.ErrAmountMustBePositive),
+		errors.Is(err, wallet.ErrInvalidPhone),
+		errors.Is(err, wallet.ErrInvalidFavoriteName),
+		errors.Is(err, wallet.ErrInvalidCategory),
+		errors.Is(err, wallet.ErrInvalidIdempotencyKey),
+		errors.Is(err, wallet.ErrSameAccount),
+		errors.Is(err, wallet.ErrInvalidCreditLimit),
+		errors.Is(err, wallet.ErrInvalidFavoriteOrder),
+		errors.Is(err, wallet.ErrInvalidLimitPeriod):
+		code = codes.InvalidArgument
+
+	case errors.Is(err, wallet.ErrPhoneRegistered),
+		errors.Is(err, wallet.ErrFavoriteNameTaken):
+		code = codes.AlreadyExists
+
+	case errors.Is(err, wallet.ErrNotEnoughBalance),
+		errors.Is(err, wallet.ErrInvalidTransition),
+		errors.Is(err, wallet.ErrIdempotencyKeyReused),
+		errors.Is(err, wallet.ErrRefundExceedsAmount),
+		errors.Is(err, wallet.ErrAccountFrozen),
+		errors.Is(err, wallet.ErrAccountClosed),
+		errors.Is(err, wallet.ErrAccountNotEmpty),
+		errors.Is(err, wallet.ErrCreditLimitInUse),
+		errors.Is(err, wallet.ErrTransferNotRefundable):
+		code = codes.FailedPrecondition
+
+	case errors.Is(err, wallet.ErrLimitExceeded):
+		code = codes.ResourceExhausted
+	}
+
+	return status.Error(code, err.Error())
+}
+
+func accountToProto(account *types.Account) *walletpb.Account {
+	return &walletpb.Account{
+		Id:				account.ID,
+		Phone:			string(account.Phone),
+		Balance:		int64(account.Balance),
+		Status:			string(account.Status),
+		CreditLimit:	int64(account.CreditLimit),
+		UsedCredit:		int64(account.UsedCredit()),
+	}
+}
+
+func paymentToProto(payment *types.Payment) *walletpb.Payment {
+	return &walletpb.Payment{
+		Id:			payment.ID,
+		AccountId:	payment.AccountID,
+		Amount:		int64(payment.Amount),
+		Category:	string(payment.Category),
+		Status:		string(payment.Status),
+		Kind:		string(payment.Kind),
+		LinkedId:	payment.LinkedID,
+		CreatedAt:	timeToProto(payment.CreatedAt),
+		UpdatedAt:	timeToProto(payment.UpdatedAt),
+		Refunded:	int64(payment.Refunded),
+	}
+}
+
+func favoriteToProto(favorite *types.Favorite) *walletpb.Favorite {
+	return &walletpb.Favorite{
+		Id:			favorite.ID,
+		AccountId:	favorite.AccountID,
+		Name:		favorite.Name,
+		Amount:		int64(favorite.Amount),
+		Category:	string(favorite.Category),
+		Position:	int32(favorite.Position),
+	}
+}
+
+func spendingLimitToProto(limit wallet.SpendingLimit) *walletpb.SpendingLimit {
+	return &walletpb.SpendingLimit{
+		AccountId:	limit.AccountID,
+		Period:		string(limit.Period),
+		Category:	string(limit.Category),
+		Amount:		int64(limit.Amount),
+	}
+}
+
+func timeToProto(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+func timeFromProto(t *timestamppb.Timestamp) (time.Time, error) {
+	if t == nil {
+		return time.Time{}, nil
+	}
+
+	err := t.CheckValid()
+	if err != nil {
+		return time.Time{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return t.AsTime(), nil
+}
@@ -0,0 +1,486 @@
+package grpcserver
+
+import (
+	"context"
+	"github.com/aminjonshermatov/wallet/pkg/wallet"
+	"github.com/aminjonshermatov/wallet/pkg/walletpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"io"
+	"net"
+	"testing"
+)
+
+// newTestClient serves svc over an in-process bufconn listener.
+func newTestClient(t *testing.T, svc *wallet.Service) walletpb.WalletServiceClient {
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	Register(server, svc)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return walletpb.NewWalletServiceClient(conn)
+}
+
+func TestServer_endToEnd(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t, wallet.NewService())
+
+	account, err := client.RegisterAccount(ctx, &walletpb.RegisterAccountRequest{Phone: "+992 00 000 0001"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.GetPhone() != "+992000000001" || account.GetStatus() != "ACTIVE" {
+		t.Fatalf("invalid account %v", account)
+	}
+
+	account, err = client.Deposit(ctx, &walletpb.DepositRequest{AccountId: account.GetId(), Amount: 1_000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.GetBalance() != 1_000 {
+		t.Fatalf("invalid balance after deposit %v", account)
+	}
+
+	payment, err := client.Pay(ctx, &walletpb.PayRequest{AccountId: account.GetId(), Amount: 300, Category: "food"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payment.GetStatus() != "INPROGRESS" || payment.GetCreatedAt() == nil {
+		t.Fatalf("invalid payment %v", payment)
+	}
+
+	repeated, err := client.Repeat(ctx, &walletpb.RepeatRequest{PaymentId: payment.GetId()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rejected, err := client.Reject(ctx, &walletpb.RejectRequest{PaymentId: repeated.GetId()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rejected.GetStatus() != "FAIL" || rejected.GetRefunded() != 300 {
+		t.Fatalf("invalid rejected payment %v", rejected)
+	}
+
+	favorite, err := client.FavoritePayment(ctx, &walletpb.FavoritePaymentRequest{PaymentId: payment.GetId(), Name: "osh"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.PayFromFavorite(ctx, &walletpb.PayFromFavoriteRequest{FavoriteId: favorite.GetId(), IdempotencyKey: "fav-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.PayFromFavorite(ctx, &walletpb.PayFromFavoriteRequest{FavoriteId: favorite.GetId(), IdempotencyKey: "fav-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	favorites, err := client.ListFavorites(ctx, &walletpb.ListFavoritesRequest{AccountId: account.GetId()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(favorites.GetFavorites()) != 1 || favorites.GetFavorites()[0].GetName() != "osh" {
+		t.Fatalf("invalid favorites %v", favorites)
+	}
+
+	history, err := client.ExportAccountHistory(ctx, &walletpb.ExportAccountHistoryRequest{AccountId: account.GetId()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history.GetPayments()) != 3 {
+		t.Fatalf("invalid history %v", history)
+	}
+
+	sum, err := client.SumPayments(ctx, &walletpb.SumPaymentsRequest{Goroutines: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum.GetSum() != 600 {
+		t.Fatalf("invalid sum %v", sum)
+	}
+
+	account, err = client.FindAccountByID(ctx, &walletpb.FindAccountByIDRequest{AccountId: account.GetId()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.GetBalance() != 400 {
+		t.Fatalf("invalid balance %v", account)
+	}
+}
+
+func TestServer_accountsAndTransfers(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t, wallet.NewService())
+
+	from, err := client.RegisterAccount(ctx, &walletpb.RegisterAccountRequest{Phone: "+992000000001"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	to, err := client.RegisterAccount(ctx, &walletpb.RegisterAccountRequest{Phone: "+992000000002"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Deposit(ctx, &walletpb.DepositRequest{AccountId: from.GetId(), Amount: 1_000})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	from, err = client.SetCreditLimit(ctx, &walletpb.SetCreditLimitRequest{AccountId: from.GetId(), CreditLimit: 500})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from.GetCreditLimit() != 500 {
+		t.Fatalf("invalid credit limit %v", from)
+	}
+
+	transfer, err := client.Transfer(ctx, &walletpb.TransferRequest{FromAccountId: from.GetId(), ToAccountId: to.GetId(), Amount: 1_200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transfer.GetKind() != "TRANSFER_OUT" || transfer.GetLinkedId() == "" {
+		t.Fatalf("invalid transfer %v", transfer)
+	}
+	transfer, err = client.Confirm(ctx, &walletpb.ConfirmRequest{PaymentId: transfer.GetId()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transfer.GetStatus() != "OK" {
+		t.Fatalf("invalid confirmed transfer %v", transfer)
+	}
+
+	payment, err := client.Pay(ctx, &walletpb.PayRequest{AccountId: to.GetId(), Amount: 200, Category: "food"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payment, err = client.Refund(ctx, &walletpb.RefundRequest{PaymentId: payment.GetId(), Amount: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payment.GetRefunded() != 50 {
+		t.Fatalf("invalid refunded payment %v", payment)
+	}
+
+	payments, err := client.FindPaymentsByAccount(ctx, &walletpb.FindPaymentsByAccountRequest{AccountId: to.GetId(), Goroutines: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(payments.GetPayments()) != 2 {
+		t.Fatalf("invalid payments %v", payments)
+	}
+
+	from, err = client.FreezeAccount(ctx, &walletpb.FreezeAccountRequest{AccountId: from.GetId()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from.GetStatus() != "FROZEN" {
+		t.Fatalf("invalid frozen account %v", from)
+	}
+	from, err = client.UnfreezeAccount(ctx, &walletpb.UnfreezeAccountRequest{AccountId: from.GetId()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from.GetStatus() != "ACTIVE" {
+		t.Fatalf("invalid unfrozen account %v", from)
+	}
+
+	closed, err := client.CloseAccount(ctx, &walletpb.CloseAccountRequest{AccountId: to.GetId(), Payout: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if closed.GetPaidOut() != 1_050 || closed.GetAccount().GetStatus() != "CLOSED" || closed.GetAccount().GetBalance() != 0 {
+		t.Fatalf("invalid closed account %v", closed)
+	}
+}
+
+func TestServer_favorites(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t, wallet.NewService())
+
+	account, err := client.RegisterAccount(ctx, &walletpb.RegisterAccountRequest{Phone: "+992000000001"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Deposit(ctx, &walletpb.DepositRequest{AccountId: account.GetId(), Amount: 1_000})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := make([]string, 0, 3)
+	for _, name := range []string{"osh", "taxi", "phone"} {
+		payment, err := client.Pay(ctx, &walletpb.PayRequest{AccountId: account.GetId(), Amount: 100, Category: "food"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		favorite, err := client.FavoritePayment(ctx, &walletpb.FavoritePaymentRequest{PaymentId: payment.GetId(), Name: name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, favorite.GetId())
+	}
+
+	renamed, err := client.RenameFavorite(ctx, &walletpb.RenameFavoriteRequest{FavoriteId: ids[0], Name: "plov"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renamed.GetName() != "plov" {
+		t.Fatalf("invalid renamed favorite %v", renamed)
+	}
+	updated, err := client.UpdateFavoriteAmount(ctx, &walletpb.UpdateFavoriteAmountRequest{FavoriteId: ids[1], Amount: 250})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.GetAmount() != 250 || updated.GetName() != "taxi" {
+		t.Fatalf("invalid updated favorite %v", updated)
+	}
+
+	favorites, err := client.ReorderFavorites(ctx, &walletpb.ReorderFavoritesRequest{AccountId: account.GetId(), FavoriteIds: []string{ids[2], ids[0], ids[1]}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(favorites.GetFavorites()) != 3 || favorites.GetFavorites()[0].GetId() != ids[2] || favorites.GetFavorites()[1].GetName() != "plov" {
+		t.Fatalf("invalid order %v", favorites)
+	}
+
+	_, err = client.RemoveFavorite(ctx, &walletpb.RemoveFavoriteRequest{FavoriteId: ids[2]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	favorites, err = client.ListFavorites(ctx, &walletpb.ListFavoritesRequest{AccountId: account.GetId()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(favorites.GetFavorites()) != 2 || favorites.GetFavorites()[0].GetId() != ids[0] {
+		t.Fatalf("invalid favorites after remove %v", favorites)
+	}
+}
+
+func TestServer_spendingLimits(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t, wallet.NewService())
+
+	account, err := client.RegisterAccount(ctx, &walletpb.RegisterAccountRequest{Phone: "+992000000001"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Deposit(ctx, &walletpb.DepositRequest{AccountId: account.GetId(), Amount: 1_000})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	limit, err := client.SetSpendingLimit(ctx, &walletpb.SetSpendingLimitRequest{AccountId: account.GetId(), Period: "DAILY", Category: "food", Amount: 300})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limit.GetPeriod() != "DAILY" || limit.GetCategory() != "food" || limit.GetAmount() != 300 {
+		t.Fatalf("invalid limit %v", limit)
+	}
+	_, err = client.SetSpendingLimit(ctx, &walletpb.SetSpendingLimitRequest{AccountId: account.GetId(), Period: "MONTHLY", Amount: 900})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	limits, err := client.ListSpendingLimits(ctx, &walletpb.ListSpendingLimitsRequest{AccountId: account.GetId()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(limits.GetLimits()) != 2 {
+		t.Fatalf("invalid limits %v", limits)
+	}
+
+	_, err = client.Pay(ctx, &walletpb.PayRequest{AccountId: account.GetId(), Amount: 400, Category: "food"})
+	if got := status.Code(err); got != codes.ResourceExhausted {
+		t.Fatalf("Pay(): got code %v, want %v (error %v)", got, codes.ResourceExhausted, err)
+	}
+
+	_, err = client.RemoveSpendingLimit(ctx, &walletpb.RemoveSpendingLimitRequest{AccountId: account.GetId(), Period: "DAILY", Category: "food"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Pay(ctx, &walletpb.PayRequest{AccountId: account.GetId(), Amount: 400, Category: "food"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	limits, err = client.ListSpendingLimits(ctx, &walletpb.ListSpendingLimitsRequest{AccountId: account.GetId()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(limits.GetLimits()) != 1 || limits.GetLimits()[0].GetPeriod() != "MONTHLY" {
+		t.Fatalf("invalid limits after remove %v", limits)
+	}
+}
+
+func TestServer_SumPaymentsWithProgress(t *testing.T) {
+	ctx := context.Background()
+	svc := wallet.NewService()
+	account, err := svc.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = svc.Deposit(account.ID, 1_000_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 250_000; i++ {
+		_, err = svc.Pay(account.ID, 2, "food")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	client := newTestClient(t, svc)
+
+	stream, err := client.SumPaymentsWithProgress(ctx, &walletpb.SumPaymentsWithProgressRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := make(map[int32]bool)
+	total := int64(0)
+	for {
+		progress, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts[progress.GetPart()] = true
+		total += progress.GetResult()
+	}
+
+	if len(parts) != 3 || total != 500_000 {
+		t.Errorf("SumPaymentsWithProgress(): got %d parts with total %d, want 3 parts with total 500000", len(parts), total)
+	}
+}
+
+func TestServer_errors(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t, wallet.NewService())
+
+	account, err := client.RegisterAccount(ctx, &walletpb.RegisterAccountRequest{Phone: "+992000000001"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct{
+		name	string
+		call	func() error
+		want	codes.Code
+	}{
+		{"duplicate phone", func() error {
+			_, err := client.RegisterAccount(ctx, &walletpb.RegisterAccountRequest{Phone: "992000000001"})
+			return err
+		}, codes.AlreadyExists},
+		{"invalid phone", func() error {
+			_, err := client.RegisterAccount(ctx, &walletpb.RegisterAccountRequest{Phone: "abc"})
+			return err
+		}, codes.InvalidArgument},
+		{"account not found", func() error {
+			_, err := client.FindAccountByID(ctx, &walletpb.FindAccountByIDRequest{AccountId: 100})
+			return err
+		}, codes.NotFound},
+		{"deposit zero", func() error {
+			_, err := client.Deposit(ctx, &walletpb.DepositRequest{AccountId: account.GetId()})
+			return err
+		}, codes.InvalidArgument},
+		{"not enough balance", func() error {
+			_, err := client.Pay(ctx, &walletpb.PayRequest{AccountId: account.GetId(), Amount: 1, Category: "food"})
+			return err
+		}, codes.FailedPrecondition},
+		{"payment not found", func() error {
+			_, err := client.Reject(ctx, &walletpb.RejectRequest{PaymentId: "missing"})
+			return err
+		}, codes.NotFound},
+		{"favorite not found", func() error {
+			_, err := client.PayFromFavorite(ctx, &walletpb.PayFromFavoriteRequest{FavoriteId: "missing"})
+			return err
+		}, codes.NotFound},
+		{"negative goroutines", func() error {
+			_, err := client.SumPayments(ctx, &walletpb.SumPaymentsRequest{Goroutines: -1})
+			return err
+		}, codes.InvalidArgument},
+		{"transfer to the same account", func() error {
+			_, err := client.Transfer(ctx, &walletpb.TransferRequest{FromAccountId: account.GetId(), ToAccountId: account.GetId(), Amount: 1})
+			return err
+		}, codes.InvalidArgument},
+		{"negative credit limit", func() error {
+			_, err := client.SetCreditLimit(ctx, &walletpb.SetCreditLimitRequest{AccountId: account.GetId(), CreditLimit: -1})
+			return err
+		}, codes.InvalidArgument},
+		{"confirm missing payment", func() error {
+			_, err := client.Confirm(ctx, &walletpb.ConfirmRequest{PaymentId: "missing"})
+			return err
+		}, codes.NotFound},
+		{"refund missing payment", func() error {
+			_, err := client.Refund(ctx, &walletpb.RefundRequest{PaymentId: "missing", Amount: 1})
+			return err
+		}, codes.NotFound},
+		{"invalid favorite order", func() error {
+			_, err := client.ReorderFavorites(ctx, &walletpb.ReorderFavoritesRequest{AccountId: account.GetId(), FavoriteIds: []string{"missing"}})
+			return err
+		}, codes.InvalidArgument},
+		{"remove missing favorite", func() error {
+			_, err := client.RemoveFavorite(ctx, &walletpb.RemoveFavoriteRequest{FavoriteId: "missing"})
+			return err
+		}, codes.NotFound},
+		{"update missing favorite", func() error {
+			_, err := client.UpdateFavoriteAmount(ctx, &walletpb.UpdateFavoriteAmountRequest{FavoriteId: "missing", Amount: 1})
+			return err
+		}, codes.NotFound},
+		{"invalid limit period", func() error {
+			_, err := client.SetSpendingLimit(ctx, &walletpb.SetSpendingLimitRequest{AccountId: account.GetId(), Period: "YEARLY", Amount: 1})
+			return err
+		}, codes.InvalidArgument},
+		{"limit of missing account", func() error {
+			_, err := client.SetSpendingLimit(ctx, &walletpb.SetSpendingLimitRequest{AccountId: 100, Period: "DAILY", Amount: 1})
+			return err
+		}, codes.NotFound},
+		{"remove missing limit", func() error {
+			_, err := client.RemoveSpendingLimit(ctx, &walletpb.RemoveSpendingLimitRequest{AccountId: account.GetId(), Period: "DAILY"})
+			return err
+		}, codes.NotFound},
+		{"limits of missing account", func() error {
+			_, err := client.ListSpendingLimits(ctx, &walletpb.ListSpendingLimitsRequest{AccountId: 100})
+			return err
+		}, codes.NotFound},
+		{"payments of missing account", func() error {
+			_, err := client.FindPaymentsByAccount(ctx, &walletpb.FindPaymentsByAccountRequest{AccountId: 100})
+			return err
+		}, codes.NotFound},
+		{"freeze missing account", func() error {
+			_, err := client.FreezeAccount(ctx, &walletpb.FreezeAccountRequest{AccountId: 100})
+			return err
+		}, codes.NotFound},
+		{"close account with balance", func() error {
+			_, err := client.Deposit(ctx, &walletpb.DepositRequest{AccountId: account.GetId(), Amount: 1})
+			if err != nil {
+				return err
+			}
+			_, err = client.CloseAccount(ctx, &walletpb.CloseAccountRequest{AccountId: account.GetId()})
+			return err
+		}, codes.FailedPrecondition},
+	}
+
+	for _, tt := range tests {
+		err := tt.call()
+		if got := status.Code(err); got != tt.want {
+			t.Errorf("%s: got code %v, want %v (error %v)", tt.name, got, tt.want, err)
+		}
+	}
+}
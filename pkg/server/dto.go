@@ -0,0 +1,85 @@
+package server
+
+import (
+	"github.com/aminjonshermatov/wallet/pkg/types"
+	"time"
+)
+
+type accountDTO struct {
+	ID			int64				`json:"id"`
+	Phone		types.Phone			`json:"phone"`
+	Balance		types.Money			`json:"balance"`
+	Status		types.AccountStatus	`json:"status"`
+	CreditLimit	types.Money			`json:"creditLimit"`
+	UsedCredit	types.Money			`json:"usedCredit"`
+}
+
+func newAccountDTO(account *types.Account) accountDTO {
+	return accountDTO{
+		ID:				account.ID,
+		Phone:			account.Phone,
+		Balance:		account.Balance,
+		Status:			account.Status,
+		CreditLimit:	account.CreditLimit,
+		UsedCredit:		account.UsedCredit(),
+	}
+}
+
+type paymentDTO struct {
+	ID			string					`json:"id"`
+	AccountID	int64					`json:"accountId"`
+	Amount		types.Money				`json:"amount"`
+	Refunded	types.Money				`json:"refunded"`
+	Category	types.PaymentCategory	`json:"category"`
+	Status		types.PaymentStatus		`json:"status"`
+	Kind		types.PaymentKind		`json:"kind,omitempty"`
+	LinkedID	string					`json:"linkedId,omitempty"`
+	CreatedAt	*time.Time				`json:"createdAt,omitempty"`
+	UpdatedAt	*time.Time				`json:"updatedAt,omitempty"`
+}
+
+func newPaymentDTO(payment *types.Payment) paymentDTO {
+	return paymentDTO{
+		ID:			payment.ID,
+		AccountID:	payment.AccountID,
+		Amount:		payment.Amount,
+		Refunded:	payment.Refunded,
+		Category:	payment.Category,
+		Status:		payment.Status,
+		Kind:		payment.Kind,
+		LinkedID:	payment.LinkedID,
+		CreatedAt:	timeOrNil(payment.CreatedAt),
+		UpdatedAt:	timeOrNil(payment.UpdatedAt),
+	}
+}
+
+type favoriteDTO struct {
+	ID			string					`json:"id"`
+	AccountID	int64					`json:"accountId"`
+	Name		string					`json:"name"`
+	Amount		types.Money				`json:"amount"`
+	Category	types.PaymentCategory	`json:"category"`
+	Position	int						`json:"position"`
+}
+
+func newFavoriteDTO(favorite *types.Favorite) favoriteDTO {
+	return favoriteDTO{
+		ID:			favorite.ID,
+		AccountID:	favorite.AccountID,
+		Name:		favorite.Name,
+		Amount:		favorite.Amount,
+		Category:	favorite.Category,
+		Position:	favorite.Position,
+	}
+}
+
+type errorDTO struct {
+	Error	string	`json:"error"`
+}
+
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
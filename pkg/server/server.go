@@ -0,0 +1,443 @@
+// Package server exposes wallet.Service as an HTTP/JSON API.
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/aminjonshermatov/wallet/pkg/types"
+	"github.com/aminjonshermatov/wallet/pkg/wallet"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errNotFound = errors.New("not found")
+var errMethodNotAllowed = errors.New("method not allowed")
+var errBadRequest = errors.New("bad request")
+
+type Server struct {
+	svc	*wallet.Service
+	mux	*http.ServeMux
+}
+
+// NewServer routes:
+//
+//	POST /accounts                    register an account
+//	GET  /accounts/{id}               find an account
+//	POST /accounts/{id}/deposits      deposit to an account
+//	GET  /accounts/{id}/payments      account history, ?from=&to= in RFC 3339
+//	GET  /accounts/{id}/favorites     account favorites
+//	POST /payments                    pay, Idempotency-Key header makes retries safe
+//	GET  /payments/sum                sum of all payments, ?goroutines=
+//	GET  /payments/{id}               find a payment
+//	POST /payments/{id}/reject        reject a payment
+//	POST /payments/{id}/repeat        repeat a payment
+//	POST /favorites                   make a favorite of a payment
+//	GET  /favorites/{id}              find a favorite
+//	POST /favorites/{id}/pay          pay from a favorite
+func NewServer(svc *wallet.Service) *Server {
+	s := &Server{svc: svc, mux: http.NewServeMux()}
+	s.mux.HandleFunc("/accounts", s.handleAccounts)
+	s.mux.HandleFunc("/accounts/", s.handleAccount)
+	s.mux.HandleFunc("/payments", s.handlePayments)
+	s.mux.HandleFunc("/payments/", s.handlePayment)
+	s.mux.HandleFunc("/favorites", s.handleFavorites)
+	s.mux.HandleFunc("/favorites/", s.handleFavorite)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) handleAccounts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, errMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Phone	string	`json:"phone"`
+	}
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	account, err := s.svc.RegisterAccount(types.Phone(req.Phone))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, newAccountDTO(account))
+}
+
+func (s *Server) handleAccount(w http.ResponseWriter, r *http.Request) {
+	parts := pathParts(r.URL.Path, "/accounts/")
+	if len(parts) == 0 || len(parts) > 2 {
+		writeError(w, errNotFound)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		writeError(w, errNotFound)
+		return
+	}
+
+	action := ""
+	if len(parts) == 2 {
+		action = parts[1]
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		account, err := s.svc.FindAccountByID(accountID)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, newAccountDTO(account))
+
+	case action == "deposits" && r.Method == http.MethodPost:
+		var req struct {
+			Amount	types.Money	`json:"amount"`
+		}
+		if !readJSON(w, r, &req) {
+			return
+		}
+
+		key := r.Header.Get("Idempotency-Key")
+		if key != "" {
+			err = s.svc.DepositWithKey(key, accountID, req.Amount)
+		} else {
+			err = s.svc.Deposit(accountID, req.Amount)
+		}
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		account, err := s.svc.FindAccountByID(accountID)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, newAccountDTO(account))
+
+	case action == "payments" && r.Method == http.MethodGet:
+		from, err := parseTime(r.URL.Query().Get("from"))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		to, err := parseTime(r.URL.Query().Get("to"))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		payments, err := s.svc.ExportAccountHistoryBetween(accountID, from, to)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		res := make([]paymentDTO, 0, len(payments))
+		for i := range payments {
+			res = append(res, newPaymentDTO(&payments[i]))
+		}
+		writeJSON(w, http.StatusOK, res)
+
+	case action == "favorites" && r.Method == http.MethodGet:
+		favorites, err := s.svc.AccountFavorites(accountID)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		res := make([]favoriteDTO, 0, len(favorites))
+		for i := range favorites {
+			res = append(res, newFavoriteDTO(&favorites[i]))
+		}
+		writeJSON(w, http.StatusOK, res)
+
+	case action == "" || action == "deposits" || action == "payments" || action == "favorites":
+		writeError(w, errMethodNotAllowed)
+
+	default:
+		writeError(w, errNotFound)
+	}
+}
+
+func (s *Server) handlePayments(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, errMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		AccountID	int64					`json:"accountId"`
+		Amount		types.Money				`json:"amount"`
+		Category	types.PaymentCategory	`json:"category"`
+	}
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	var payment *types.Payment
+	var err error
+	key := r.Header.Get("Idempotency-Key")
+	if key != "" {
+		payment, err = s.svc.PayWithKey(key, req.AccountID, req.Amount, req.Category)
+	} else {
+		payment, err = s.svc.Pay(req.AccountID, req.Amount, req.Category)
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, newPaymentDTO(payment))
+}
+
+func (s *Server) handlePayment(w http.ResponseWriter, r *http.Request) {
+	parts := pathParts(r.URL.Path, "/payments/")
+	if len(parts) == 1 && parts[0] == "sum" {
+		s.handleSum(w, r)
+		return
+	}
+	if len(parts) == 0 || len(parts) > 2 {
+		writeError(w, errNotFound)
+		return
+	}
+
+	paymentID := parts[0]
+	action := ""
+	if len(parts) == 2 {
+		action = parts[1]
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		payment, err := s.svc.FindPaymentByID(paymentID)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, newPaymentDTO(payment))
+
+	case action == "reject" && r.Method == http.MethodPost:
+		err := s.svc.Reject(paymentID)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		payment, err := s.svc.FindPaymentByID(paymentID)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, newPaymentDTO(payment))
+
+	case action == "repeat" && r.Method == http.MethodPost:
+		var payment *types.Payment
+		var err error
+		key := r.Header.Get("Idempotency-Key")
+		if key != "" {
+			payment, err = s.svc.RepeatWithKey(key, paymentID)
+		} else {
+			payment, err = s.svc.Repeat(paymentID)
+		}
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, newPaymentDTO(payment))
+
+	case action == "" || action == "reject" || action == "repeat":
+		writeError(w, errMethodNotAllowed)
+
+	default:
+		writeError(w, errNotFound)
+	}
+}
+
+func (s *Server) handleSum(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, errMethodNotAllowed)
+		return
+	}
+
+	goroutines := 1
+	if value := r.URL.Query().Get("goroutines"); value != "" {
+		var err error
+		goroutines, err = strconv.Atoi(value)
+		if err != nil || goroutines < 1 {
+			writeError(w, errBadRequest)
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, struct {
+		Sum	types.Money	`json:"sum"`
+	}{Sum: s.svc.SumPayments(goroutines)})
+}
+
+func (s *Server) handleFavorites(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, errMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		PaymentID	string	`json:"paymentId"`
+		Name		string	`json:"name"`
+	}
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	favorite, err := s.svc.FavoritePayment(req.PaymentID, req.Name)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, newFavoriteDTO(favorite))
+}
+
+func (s *Server) handleFavorite(w http.ResponseWriter, r *http.Request) {
+	parts := pathParts(r.URL.Path, "/favorites/")
+	if len(parts) == 0 || len(parts) > 2 {
+		writeError(w, errNotFound)
+		return
+	}
+
+	favoriteID := parts[0]
+	action := ""
+	if len(parts) == 2 {
+		action = parts[1]
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		favorite, err := s.svc.FindFavoriteByID(favoriteID)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, newFavoriteDTO(favorite))
+
+	case action == "pay" && r.Method == http.MethodPost:
+		var payment *types.Payment
+		var err error
+		key := r.Header.Get("Idempotency-Key")
+		if key != "" {
+			payment, err = s.svc.PayFromFavoriteWithKey(key, favoriteID)
+		} else {
+			payment, err = s.svc.PayFromFavorite(favoriteID)
+		}
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, newPaymentDTO(payment))
+
+	case action == "" || action == "pay":
+		writeError(w, errMethodNotAllowed)
+
+	default:
+		writeError(w, errNotFound)
+	}
+}
+
+// pathParts splits the path after prefix into its non empty segments.
+func pathParts(path string, prefix string) []string {
+	res := make([]string, 0, 2)
+	for _, part := range strings.Split(strings.TrimPrefix(path, prefix), "/") {
+		if part != "" {
+			res = append(res, part)
+		}
+	}
+	return res
+}
+
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, errBadRequest
+	}
+	return t, nil
+}
+
+func readJSON(w http.ResponseWriter, r *http.Request, dst interface{}) bool {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(dst)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorDTO{Error: "invalid request body: " + err.Error()})
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(value)
+	if err != nil {
+		log.Print(err)
+	}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := StatusCode(err)
+	message := err.Error()
+	if status == http.StatusInternalServerError {
+		log.Print(err)
+		message = http.StatusText(status)
+	}
+	writeJSON(w, status, errorDTO{Error: message})
+}
+
+// StatusCode maps errors of wallet.Service to HTTP status codes.
+func StatusCode(err error) int {
+	switch {
+	case errors.Is(err, errNotFound),
+		errors.Is(err, wallet.ErrAccountNotFound),
+		errors.Is(err, wallet.ErrPaymentNotFound),
+		errors.Is(err, wallet.ErrFavoriteNotFound):
+		return http.StatusNotFound
+
+	case errors.Is(err, errMethodNotAllowed):
+		return http.StatusMethodNotAllowed
+
+	case errors.Is(err, errBadRequest),
+		errors.Is(err, wallet.ErrAmountMustBePositive),
+		errors.Is(err, wallet.ErrInvalidPhone),
+		errors.Is(err, wallet.ErrInvalidFavoriteName),
+		errors.Is(err, wallet.ErrInvalidCategory),
+		errors.Is(err, wallet.ErrInvalidIdempotencyKey),
+		errors.Is(err, wallet.ErrSameAccount):
+		return http.StatusBadRequest
+
+	case errors.Is(err, wallet.ErrPhoneRegistered),
+		errors.Is(err, wallet.ErrFavoriteNameTaken),
+		errors.Is(err, wallet.ErrInvalidTransition),
+		errors.Is(err, wallet.ErrIdempotencyKeyReused):
+		return http.StatusConflict
+
+	case errors.Is(err, wallet.ErrAccountFrozen),
+		errors.Is(err, wallet.ErrAccountClosed):
+		return http.StatusForbidden
+
+	case errors.Is(err, wallet.ErrNotEnoughBalance),
+		errors.Is(err, wallet.ErrLimitExceeded),
+		errors.Is(err, wallet.ErrRefundExceedsAmount):
+		return http.StatusUnprocessableEntity
+	}
+
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,234 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aminjonshermatov/wallet/pkg/wallet"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type testClient struct {
+	t		*testing.T
+	server	*httptest.Server
+}
+
+func newTestClient(t *testing.T) *testClient {
+	srv := httptest.NewServer(NewServer(wallet.NewService()))
+	t.Cleanup(srv.Close)
+	return &testClient{t: t, server: srv}
+}
+
+// do sends body as JSON and decodes the response into dst when it is not nil.
+func (c *testClient) do(method string, path string, body interface{}, dst interface{}, headers ...string) int {
+	c.t.Helper()
+
+	var data []byte
+	if body != nil {
+		var err error
+		data, err = json.Marshal(body)
+		if err != nil {
+			c.t.Fatal(err)
+		}
+	}
+
+	req, err := http.NewRequest(method, c.server.URL + path, bytes.NewReader(data))
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	for i := 0; i + 1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i + 1])
+	}
+
+	resp, err := c.server.Client().Do(req)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if dst != nil {
+		err = json.NewDecoder(resp.Body).Decode(dst)
+		if err != nil {
+			c.t.Fatalf("%s %s: can't decode response: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func (c *testClient) expect(want int, method string, path string, body interface{}, dst interface{}, headers ...string) {
+	c.t.Helper()
+
+	if got := c.do(method, path, body, dst, headers...); got != want {
+		c.t.Fatalf("%s %s: got status %d, want %d", method, path, got, want)
+	}
+}
+
+func TestServer_endToEnd(t *testing.T) {
+	c := newTestClient(t)
+
+	var account accountDTO
+	c.expect(http.StatusCreated, "POST", "/accounts", map[string]interface{}{"phone": "+992 00 000 0001"}, &account)
+	if account.Phone != "+992000000001" || account.Status != "ACTIVE" {
+		t.Fatalf("invalid account %v", account)
+	}
+	accountPath := fmt.Sprintf("/accounts/%d", account.ID)
+
+	c.expect(http.StatusOK, "POST", accountPath + "/deposits", map[string]interface{}{"amount": 1_000}, &account)
+	if account.Balance != 1_000 {
+		t.Fatalf("invalid balance after deposit %v", account)
+	}
+
+	var payment paymentDTO
+	c.expect(http.StatusCreated, "POST", "/payments", map[string]interface{}{
+		"accountId":	account.ID,
+		"amount":		300,
+		"category":		"food",
+	}, &payment)
+	if payment.Amount != 300 || payment.Status != "INPROGRESS" || payment.CreatedAt == nil {
+		t.Fatalf("invalid payment %v", payment)
+	}
+
+	var repeated paymentDTO
+	c.expect(http.StatusCreated, "POST", "/payments/" + payment.ID + "/repeat", nil, &repeated)
+	if repeated.ID == payment.ID || repeated.Amount != payment.Amount {
+		t.Fatalf("invalid repeated payment %v", repeated)
+	}
+
+	var rejected paymentDTO
+	c.expect(http.StatusOK, "POST", "/payments/" + payment.ID + "/reject", nil, &rejected)
+	if rejected.Status != "FAIL" {
+		t.Fatalf("invalid rejected payment %v", rejected)
+	}
+	c.expect(http.StatusConflict, "POST", "/payments/" + payment.ID + "/reject", nil, nil)
+
+	var favorite favoriteDTO
+	c.expect(http.StatusCreated, "POST", "/favorites", map[string]interface{}{
+		"paymentId":	payment.ID,
+		"name":			"osh",
+	}, &favorite)
+	c.expect(http.StatusOK, "GET", "/favorites/" + favorite.ID, nil, &favorite)
+	if favorite.Name != "osh" || favorite.Amount != 300 {
+		t.Fatalf("invalid favorite %v", favorite)
+	}
+
+	var fromFavorite paymentDTO
+	c.expect(http.StatusCreated, "POST", "/favorites/" + favorite.ID + "/pay", nil, &fromFavorite)
+
+	var favorites []favoriteDTO
+	c.expect(http.StatusOK, "GET", accountPath + "/favorites", nil, &favorites)
+	if len(favorites) != 1 || favorites[0].ID != favorite.ID {
+		t.Fatalf("invalid favorites %v", favorites)
+	}
+
+	var history []paymentDTO
+	c.expect(http.StatusOK, "GET", accountPath + "/payments", nil, &history)
+	if len(history) != 3 {
+		t.Fatalf("invalid history %v", history)
+	}
+
+	var sum struct {
+		Sum	int64	`json:"sum"`
+	}
+	c.expect(http.StatusOK, "GET", "/payments/sum?goroutines=2", nil, &sum)
+	if sum.Sum != 600 {
+		t.Fatalf("invalid sum %v", sum)
+	}
+
+	c.expect(http.StatusOK, "GET", accountPath, nil, &account)
+	if account.Balance != 400 {
+		t.Fatalf("invalid balance %v", account)
+	}
+
+	var found paymentDTO
+	c.expect(http.StatusOK, "GET", "/payments/" + fromFavorite.ID, nil, &found)
+	if found.ID != fromFavorite.ID || found.Amount != fromFavorite.Amount || !found.CreatedAt.Equal(*fromFavorite.CreatedAt) {
+		t.Fatalf("GET payment: got %v, want %v", found, fromFavorite)
+	}
+}
+
+func TestServer_idempotencyKey(t *testing.T) {
+	c := newTestClient(t)
+
+	var account accountDTO
+	c.expect(http.StatusCreated, "POST", "/accounts", map[string]interface{}{"phone": "+992000000001"}, &account)
+	c.expect(http.StatusOK, "POST", fmt.Sprintf("/accounts/%d/deposits", account.ID), map[string]interface{}{"amount": 1_000}, nil)
+
+	body := map[string]interface{}{"accountId": account.ID, "amount": 100, "category": "food"}
+	var first, second paymentDTO
+	c.expect(http.StatusCreated, "POST", "/payments", body, &first, "Idempotency-Key", "key-1")
+	c.expect(http.StatusCreated, "POST", "/payments", body, &second, "Idempotency-Key", "key-1")
+	if first.ID != second.ID {
+		t.Errorf("retry must return the original payment, got %v, want %v", second.ID, first.ID)
+	}
+
+	body["amount"] = 200
+	c.expect(http.StatusConflict, "POST", "/payments", body, nil, "Idempotency-Key", "key-1")
+}
+
+func TestServer_errors(t *testing.T) {
+	c := newTestClient(t)
+
+	var account accountDTO
+	c.expect(http.StatusCreated, "POST", "/accounts", map[string]interface{}{"phone": "+992000000001"}, &account)
+
+	tests := []struct{
+		name	string
+		method	string
+		path	string
+		body	interface{}
+		want	int
+	}{
+		{"duplicate phone", "POST", "/accounts", map[string]interface{}{"phone": "992000000001"}, http.StatusConflict},
+		{"invalid phone", "POST", "/accounts", map[string]interface{}{"phone": "abc"}, http.StatusBadRequest},
+		{"invalid body", "POST", "/accounts", "phone", http.StatusBadRequest},
+		{"unknown field", "POST", "/accounts", map[string]interface{}{"name": "x"}, http.StatusBadRequest},
+		{"account not found", "GET", "/accounts/100", nil, http.StatusNotFound},
+		{"invalid account id", "GET", "/accounts/abc", nil, http.StatusNotFound},
+		{"deposit zero", "POST", fmt.Sprintf("/accounts/%d/deposits", account.ID), map[string]interface{}{"amount": 0}, http.StatusBadRequest},
+		{"not enough balance", "POST", "/payments", map[string]interface{}{"accountId": account.ID, "amount": 1, "category": "food"}, http.StatusUnprocessableEntity},
+		{"pay unknown account", "POST", "/payments", map[string]interface{}{"accountId": 100, "amount": 1, "category": "food"}, http.StatusNotFound},
+		{"payment not found", "GET", "/payments/missing", nil, http.StatusNotFound},
+		{"reject not found", "POST", "/payments/missing/reject", nil, http.StatusNotFound},
+		{"repeat not found", "POST", "/payments/missing/repeat", nil, http.StatusNotFound},
+		{"favorite not found", "POST", "/favorites/missing/pay", nil, http.StatusNotFound},
+		{"favorite of unknown payment", "POST", "/favorites", map[string]interface{}{"paymentId": "missing", "name": "osh"}, http.StatusNotFound},
+		{"invalid goroutines", "GET", "/payments/sum?goroutines=0", nil, http.StatusBadRequest},
+		{"method not allowed", "GET", "/payments", nil, http.StatusMethodNotAllowed},
+		{"unknown action", "POST", "/payments/missing/cancel", nil, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		var res errorDTO
+		got := c.do(tt.method, tt.path, tt.body, &res)
+		if got != tt.want {
+			t.Errorf("%s: got status %d, want %d", tt.name, got, tt.want)
+		}
+		if res.Error == "" {
+			t.Errorf("%s: error message must not be empty", tt.name)
+		}
+	}
+}
+
+func TestStatusCode(t *testing.T) {
+	tests := []struct{
+		err		error
+		want	int
+	}{
+		{wallet.ErrAccountNotFound, http.StatusNotFound},
+		{wallet.ErrNotEnoughBalance, http.StatusUnprocessableEntity},
+		{&wallet.LimitError{}, http.StatusUnprocessableEntity},
+		{&wallet.TransitionError{}, http.StatusConflict},
+		{&wallet.PhoneError{}, http.StatusBadRequest},
+		{wallet.ErrAccountFrozen, http.StatusForbidden},
+		{errors.New("disk is full"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		if got := StatusCode(tt.err); got != tt.want {
+			t.Errorf("StatusCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
@@ -1,5 +1,7 @@
 package types
 
+import "time"
+
 type Money int64
 
 type PaymentCategory string
@@ -10,6 +12,15 @@ const (
 	PaymentStatusOk			PaymentStatus = "OK"
 	PaymentStatusFail		PaymentStatus = "FAIL"
 	PaymentStatusInProgress	PaymentStatus = "INPROGRESS"
+	PaymentStatusRefunded	PaymentStatus = "REFUNDED"
+)
+
+type PaymentKind string
+
+const (
+	PaymentKindRegular		PaymentKind = ""
+	PaymentKindTransferOut	PaymentKind = "TRANSFER_OUT"
+	PaymentKindTransferIn	PaymentKind = "TRANSFER_IN"
 )
 
 type Payment struct {
@@ -18,14 +29,47 @@ type Payment struct {
 	Amount		Money
 	Category	PaymentCategory
 	Status		PaymentStatus
+	Kind		PaymentKind
+	LinkedID	string
+	CreatedAt	time.Time
+	UpdatedAt	time.Time
+	Refunded	Money
+}
+
+// Charged is the part of the amount that was not given back to the account.
+func (p Payment) Charged() Money {
+	return p.Amount - p.Refunded
 }
 
 type Phone string
 
+type AccountStatus string
+
+const (
+	AccountStatusActive	AccountStatus = "ACTIVE"
+	AccountStatusFrozen	AccountStatus = "FROZEN"
+	AccountStatusClosed	AccountStatus = "CLOSED"
+)
+
 type Account struct {
-	ID		int64
-	Phone	Phone
-	Balance	Money
+	ID			int64
+	Phone		Phone
+	Balance		Money
+	Status		AccountStatus
+	CreditLimit	Money
+}
+
+// UsedCredit is the part of the credit limit the account has spent.
+func (a Account) UsedCredit() Money {
+	if a.Balance < 0 {
+		return -a.Balance
+	}
+	return 0
+}
+
+// Available is the amount the account may spend including its credit limit.
+func (a Account) Available() Money {
+	return a.Balance + a.CreditLimit
 }
 
 type Favorite struct {
@@ -34,9 +78,31 @@ type Favorite struct {
 	Name		string
 	Amount		Money
 	Category	PaymentCategory
+	Position	int
 }
 
 type Progress struct {
 	Part 	int
 	Result	Money
 }
+
+var paymentTransitions = map[PaymentStatus][]PaymentStatus{
+	PaymentStatusInProgress:	{PaymentStatusOk, PaymentStatusFail, PaymentStatusRefunded},
+	PaymentStatusOk:			{PaymentStatusRefunded},
+}
+
+// CanTransitionTo reports whether a payment in status s may be moved to next.
+func (s PaymentStatus) CanTransitionTo(next PaymentStatus) bool {
+	for _, status := range paymentTransitions[s] {
+		if status == next {
+			return true
+		}
+	}
+	return false
+}
+
+type PaymentTransition struct {
+	From	PaymentStatus
+	To		PaymentStatus
+	At		time.Time
+}
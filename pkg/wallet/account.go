@@ -0,0 +1,127 @@
+package wallet
+
+import (
+	"errors"
+	"github.com/aminjonshermatov/wallet/pkg/types"
+)
+
+var ErrAccountFrozen = errors.New("account is frozen")
+var ErrAccountClosed = errors.New("account is closed")
+var ErrAccountNotEmpty = errors.New("account balance must be zero to close it")
+
+// Freeze blocks deposits and payments of the account until Unfreeze.
+// Rejects and refunds still return money to a frozen account.
+func (s *Service) Freeze(accountID int64) error {
+	return s.setAccountStatus(accountID, types.AccountStatusFrozen)
+}
+
+// Unfreeze makes a frozen account active again.
+func (s *Service) Unfreeze(accountID int64) error {
+	return s.setAccountStatus(accountID, types.AccountStatusActive)
+}
+
+// Close closes an account with zero balance, a closed account can't be reopened.
+func (s *Service) Close(accountID int64) error {
+	s.lock()
+	defer s.mu.Unlock()
+
+	if err := s.begin(walClose, walArgs{AccountID: accountID}); err != nil {
+		return err
+	}
+	defer s.end()
+
+	account, err := s.findAccountByID(accountID)
+	if err != nil {
+		return err
+	}
+
+	if account.Status == types.AccountStatusClosed {
+		return ErrAccountClosed
+	}
+
+	if account.Balance != 0 {
+		return ErrAccountNotEmpty
+	}
+
+	account.Status = types.AccountStatusClosed
+	return s.accounts.Save(account)
+}
+
+// CloseWithPayout pays the remaining balance out of the wallet and closes the account.
+// It returns the amount paid out.
+func (s *Service) CloseWithPayout(accountID int64) (types.Money, error) {
+	s.lock()
+	defer s.mu.Unlock()
+
+	if err := s.begin(walCloseWithPayout, walArgs{AccountID: accountID}); err != nil {
+		return 0, err
+	}
+	defer s.end()
+
+	account, err := s.findAccountByID(accountID)
+	if err != nil {
+		return 0, err
+	}
+
+	if account.Status == types.AccountStatusClosed {
+		return 0, ErrAccountClosed
+	}
+
+	if account.Balance < 0 {
+		return 0, ErrAccountNotEmpty
+	}
+
+	payout := account.Balance
+	if payout > 0 {
+		err = s.post(LedgerOperationPayout, WalletLedgerAccount(accountID), LedgerPayouts, payout, "")
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	account.Balance = 0
+	account.Status = types.AccountStatusClosed
+	err = s.accounts.Save(account)
+	if err != nil {
+		return 0, err
+	}
+
+	return payout, nil
+}
+
+func (s *Service) setAccountStatus(accountID int64, status types.AccountStatus) error {
+	s.lock()
+	defer s.mu.Unlock()
+
+	if err := s.begin(walSetAccountStatus, walArgs{AccountID: accountID, Status: status}); err != nil {
+		return err
+	}
+	defer s.end()
+
+	account, err := s.findAccountByID(accountID)
+	if err != nil {
+		return err
+	}
+
+	if account.Status == types.AccountStatusClosed {
+		return ErrAccountClosed
+	}
+
+	if account.Status == status {
+		return nil
+	}
+
+	account.Status = status
+	return s.accounts.Save(account)
+}
+
+// checkActive returns the error matching the account status if it can't move money.
+func checkActive(account *types.Account) error {
+	switch account.Status {
+	case types.AccountStatusFrozen:
+		return ErrAccountFrozen
+	case types.AccountStatusClosed:
+		return ErrAccountClosed
+	}
+	return nil
+}
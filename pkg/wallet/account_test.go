@@ -0,0 +1,195 @@
+package wallet
+
+import (
+	"github.com/aminjonshermatov/wallet/pkg/types"
+	"testing"
+)
+
+func TestService_RegisterAccount_active(t *testing.T) {
+	s := newTestService()
+	account, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.Status != types.AccountStatusActive {
+		t.Errorf("RegisterAccount(): account must be active, got %v", account.Status)
+	}
+}
+
+func TestService_Freeze(t *testing.T) {
+	s := newTestService()
+	account, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	favorite, err := s.FavoritePayment(payments[0].ID, "osh")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.Freeze(account.ID)
+	if err != nil {
+		t.Fatalf("Freeze(): error = %v", err)
+	}
+
+	err = s.Deposit(account.ID, 100)
+	if err != ErrAccountFrozen {
+		t.Errorf("Deposit(): must return ErrAccountFrozen, returned = %v", err)
+	}
+	_, err = s.Pay(account.ID, 100, "food")
+	if err != ErrAccountFrozen {
+		t.Errorf("Pay(): must return ErrAccountFrozen, returned = %v", err)
+	}
+	_, err = s.Repeat(payments[0].ID)
+	if err != ErrAccountFrozen {
+		t.Errorf("Repeat(): must return ErrAccountFrozen, returned = %v", err)
+	}
+	_, err = s.PayFromFavorite(favorite.ID)
+	if err != ErrAccountFrozen {
+		t.Errorf("PayFromFavorite(): must return ErrAccountFrozen, returned = %v", err)
+	}
+
+	err = s.Reject(payments[0].ID)
+	if err != nil {
+		t.Errorf("Reject(): must work on a frozen account, returned = %v", err)
+	}
+
+	err = s.Unfreeze(account.ID)
+	if err != nil {
+		t.Fatalf("Unfreeze(): error = %v", err)
+	}
+	_, err = s.Pay(account.ID, 100, "food")
+	if err != nil {
+		t.Errorf("Pay(): must work after Unfreeze, returned = %v", err)
+	}
+}
+
+func TestService_Close(t *testing.T) {
+	s := newTestService()
+	account, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Deposit(account.ID, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.Close(account.ID)
+	if err != ErrAccountNotEmpty {
+		t.Fatalf("Close(): must return ErrAccountNotEmpty, returned = %v", err)
+	}
+
+	payment, err := s.Pay(account.ID, 100, "food")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Close(account.ID)
+	if err != nil {
+		t.Fatalf("Close(): error = %v", err)
+	}
+
+	err = s.Deposit(account.ID, 100)
+	if err != ErrAccountClosed {
+		t.Errorf("Deposit(): must return ErrAccountClosed, returned = %v", err)
+	}
+	_, err = s.Pay(account.ID, 100, "food")
+	if err != ErrAccountClosed {
+		t.Errorf("Pay(): must return ErrAccountClosed, returned = %v", err)
+	}
+	err = s.Reject(payment.ID)
+	if err != ErrAccountClosed {
+		t.Errorf("Reject(): must return ErrAccountClosed, returned = %v", err)
+	}
+	for _, op := range []func(int64) error{s.Freeze, s.Unfreeze, s.Close} {
+		err = op(account.ID)
+		if err != ErrAccountClosed {
+			t.Errorf("must return ErrAccountClosed, returned = %v", err)
+		}
+	}
+}
+
+func TestService_CloseWithPayout(t *testing.T) {
+	s := newTestService()
+	from, to := s.addTransferAccounts(t)
+
+	err := s.Freeze(from.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payout, err := s.CloseWithPayout(from.ID)
+	if err != nil {
+		t.Fatalf("CloseWithPayout(): error = %v", err)
+	}
+	if payout != 1_000 {
+		t.Errorf("CloseWithPayout(): got %v, want %v", payout, 1_000)
+	}
+	s.checkBalance(t, from.ID, 0)
+
+	_, err = s.Transfer(to.ID, from.ID, 10)
+	if err != ErrAccountClosed {
+		t.Errorf("Transfer(): must return ErrAccountClosed, returned = %v", err)
+	}
+
+	discrepancies, err := s.Reconcile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(discrepancies) != 0 {
+		t.Errorf("Reconcile(): must be empty, got %v", discrepancies)
+	}
+}
+
+func TestService_AccountStatus_exportImport(t *testing.T) {
+	s := newTestService()
+	frozen, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed, err := s.RegisterAccount("+992000000002")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Freeze(frozen.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Close(closed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	err = s.Export(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	imported := NewService()
+	err = imported.Import(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for id, want := range map[int64]types.AccountStatus{
+		frozen.ID:	types.AccountStatusFrozen,
+		closed.ID:	types.AccountStatusClosed,
+	} {
+		got, err := imported.FindAccountByID(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Status != want {
+			t.Errorf("Import(): got status %v, want %v", got.Status, want)
+		}
+	}
+}
+
+func TestDecodeAccount_withoutStatus(t *testing.T) {
+	account, err := decodeAccount("1;+992000000001;100")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.Status != types.AccountStatusActive {
+		t.Errorf("decodeAccount(): legacy record must be active, got %v", account.Status)
+	}
+}
@@ -0,0 +1,206 @@
+package wallet
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// checksumPrefix starts the last column of a dump line written by Export,
+// the column holds the CRC-32 of the rest of the line in hex.
+const checksumPrefix = ";#"
+const checksumLength = 8
+
+func sealRecord(line string) string {
+	return line + checksumPrefix + fmt.Sprintf("%08x", crc32.ChecksumIEEE([]byte(line)))
+}
+
+// recordChecker checks and strips the checksums of the lines of one dump file.
+// Lines of dumps written before checksums have none, but once a line of the file
+// has a checksum every line must have one.
+type recordChecker struct {
+	line	int
+	sealed	bool
+}
+
+func (c *recordChecker) open(line string) (string, error) {
+	c.line++
+
+	i := len(line) - len(checksumPrefix) - checksumLength
+	if i < 0 || line[i:i + len(checksumPrefix)] != checksumPrefix {
+		if c.sealed {
+			return "", fmt.Errorf("%w: line %d has no checksum", ErrChecksumMismatch, c.line)
+		}
+		return line, nil
+	}
+
+	sum, err := strconv.ParseUint(line[i + len(checksumPrefix):], 16, 32)
+	if err != nil {
+		return "", fmt.Errorf("%w: line %d: invalid checksum", ErrChecksumMismatch, c.line)
+	}
+	c.sealed = true
+
+	record := line[:i]
+	if crc32.ChecksumIEEE([]byte(record)) != uint32(sum) {
+		return "", fmt.Errorf("%w: line %d", ErrChecksumMismatch, c.line)
+	}
+	return record, nil
+}
+
+// fileChecksum returns the SHA-256 of the file in hex.
+func fileChecksum(path string) (sum string, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if cerr := file.Close(); cerr != nil {
+			if err == nil {
+				err = cerr
+			}
+		}
+	}()
+
+	hash := sha256.New()
+	_, err = io.Copy(hash, file)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Corruption is a damaged file or line of an export directory.
+type Corruption struct {
+	File	string
+	// Line is 1-based, 0 means the whole file.
+	Line	int
+	Reason	string
+}
+
+func (c Corruption) String() string {
+	if c.Line == 0 {
+		return c.File + ": " + c.Reason
+	}
+	return c.File + ":" + strconv.Itoa(c.Line) + ": " + c.Reason
+}
+
+// Verify checks an export directory against the checksums of its manifest and dump lines
+// and returns what is corrupted, nil if nothing is. Lines of dumps written without checksums
+// are reported when they can't be decoded. The error is about reading the directory.
+func Verify(dir string) ([]Corruption, error) {
+	m, err := readManifest(dir)
+	if errors.Is(err, ErrCorruptedSnapshot) {
+		return []Corruption{{File: manifestName, Reason: err.Error()}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var corruptions []Corruption
+	path := dir
+	files := make([]string, 0, len(dumpFiles))
+	if m != nil {
+		path = generationPath(dir, m.Generation)
+		corruptions, err = m.corruptions(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range m.Files {
+			files = append(files, file.Name)
+		}
+	} else {
+		files = append(files, dumpFiles...)
+	}
+
+	for _, name := range files {
+		if dumpSchemas[name] == nil {
+			continue
+		}
+
+		lines, err := verifyLines(filepath.Join(path, name), name)
+		if err != nil {
+			return nil, err
+		}
+		corruptions = append(corruptions, lines...)
+	}
+	return corruptions, nil
+}
+
+func verifyFile(dir string, file manifestFile) (*Corruption, error) {
+	path := filepath.Join(dir, file.Name)
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return &Corruption{File: file.Name, Reason: "file is missing"}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() != file.Size {
+		return &Corruption{File: file.Name, Reason: fmt.Sprintf("size %d, want %d", info.Size(), file.Size)}, nil
+	}
+
+	if file.Checksum == "" {
+		return nil, nil
+	}
+	sum, err := fileChecksum(path)
+	if err != nil {
+		return nil, err
+	}
+	if sum != file.Checksum {
+		return &Corruption{File: file.Name, Reason: ErrChecksumMismatch.Error()}, nil
+	}
+	return nil, nil
+}
+
+// verifyLines reports the lines of the dump file that fail the checksum or can't be decoded,
+// a missing file has no lines.
+func verifyLines(path string, name string) (corruptions []Corruption, err error) {
+	src, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if cerr := src.Close(); cerr != nil {
+			if err == nil {
+				err = cerr
+			}
+		}
+	}()
+
+	dump := newDumpReader(name)
+	reader := bufio.NewReader(src)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if line == "" && err == io.EOF {
+			return corruptions, nil
+		}
+
+		record, header, rerr := dump.read(strings.TrimSuffix(line, "\n"))
+		if rerr == nil && !header {
+			_, rerr = dump.schema.upgrade(record)
+		}
+		if rerr != nil {
+			corruptions = append(corruptions, Corruption{File: name, Line: dump.checker.line, Reason: rerr.Error()})
+		}
+
+		if err == io.EOF {
+			return corruptions, nil
+		}
+	}
+}
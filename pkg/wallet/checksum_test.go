@@ -0,0 +1,168 @@
+package wallet
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// flipByte changes one byte of the line of the file.
+func flipByte(t *testing.T, path string, line int) {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := bytes.SplitAfter(content, []byte("\n"))
+	lines[line - 1][0] ^= 0x01
+	err = os.WriteFile(path, bytes.Join(lines, nil), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestService_Export_checksums(t *testing.T) {
+	dir := t.TempDir()
+	s := newTestService()
+	_, _, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Export(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := readManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range m.Files {
+		if len(file.Checksum) != 64 {
+			t.Errorf("Export(): %s has no checksum in the manifest", file.Name)
+		}
+	}
+
+	content, err := os.ReadFile(filepath.Join(currentGeneration(t, dir), "accounts.dump"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(string(content), "\n"), "\n") {
+		checker := &recordChecker{}
+		_, err = checker.open(line)
+		if err != nil || !checker.sealed {
+			t.Errorf("Export(): line %q has no valid checksum, err = %v", line, err)
+		}
+	}
+
+	corruptions, err := Verify(dir)
+	if err != nil || corruptions != nil {
+		t.Errorf("Verify(): got %v, %v, want no corruptions", corruptions, err)
+	}
+}
+
+func TestService_Import_corruptedLine(t *testing.T) {
+	dir := t.TempDir()
+	s := newTestService()
+	_, _, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Export(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flipByte(t, filepath.Join(currentGeneration(t, dir), "accounts.dump"), 2)
+
+	err = NewService().Import(dir)
+	if !errors.Is(err, ErrCorruptedSnapshot) {
+		t.Errorf("Import(): must return ErrCorruptedSnapshot, returned = %v", err)
+	}
+
+	corruptions, err := Verify(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(corruptions) != 2 || corruptions[0].Line != 0 || corruptions[1].Line != 2 || corruptions[1].File != "accounts.dump" {
+		t.Errorf("Verify(): got %v, want the file and its line 2", corruptions)
+	}
+}
+
+func TestService_Import_corruptedLegacyLine(t *testing.T) {
+	dir := t.TempDir()
+	s := newTestService()
+	_, _, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ExportPayments(s.Service, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flipByte(t, filepath.Join(dir, "payments.dump"), 1)
+
+	err = NewService().Import(dir)
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Import(): must return ErrChecksumMismatch, returned = %v", err)
+	}
+
+	corruptions, err := Verify(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Corruption{{File: "payments.dump", Line: 1, Reason: "checksum mismatch: line 1"}}
+	if !reflect.DeepEqual(corruptions, want) {
+		t.Errorf("Verify(): got %v, want %v", corruptions, want)
+	}
+}
+
+func TestVerify_linesWithoutChecksums(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "accounts.dump"), []byte("1;+992000000001;100\n2;+992000000002\n3;+992000000003;x\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	corruptions, err := Verify(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(corruptions) != 2 || corruptions[0].Line != 2 || corruptions[1].Line != 3 {
+		t.Errorf("Verify(): got %v, want lines 2 and 3", corruptions)
+	}
+}
+
+func TestRecordChecker_open(t *testing.T) {
+	sealed := sealRecord("1;+992000000001;100")
+
+	tests := []struct{
+		name	string
+		lines	[]string
+		wantErr	bool
+	}{
+		{"sealed", []string{sealed, sealed}, false},
+		{"legacy", []string{"1;+992000000001;100"}, false},
+		{"legacy after sealed", []string{sealed, "1;+992000000001;100"}, true},
+		{"invalid checksum", []string{"1;+992000000001;100;#zzzzzzzz"}, true},
+		{"wrong checksum", []string{"1;+992000000001;101" + sealed[len(sealed) - 10:]}, true},
+	}
+
+	for _, tt := range tests {
+		checker := &recordChecker{}
+		var err error
+		for _, line := range tt.lines {
+			_, err = checker.open(line)
+			if err != nil {
+				break
+			}
+		}
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: got error %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
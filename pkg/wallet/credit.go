@@ -0,0 +1,41 @@
+package wallet
+
+import (
+	"errors"
+	"github.com/aminjonshermatov/wallet/pkg/types"
+)
+
+var ErrInvalidCreditLimit = errors.New("credit limit must not be negative")
+var ErrCreditLimitInUse = errors.New("credit limit can't be lower than the used credit")
+
+// SetCreditLimit lets the account balance go negative down to -limit.
+// A zero limit turns the overdraft off.
+func (s *Service) SetCreditLimit(accountID int64, limit types.Money) error {
+	if limit < 0 {
+		return ErrInvalidCreditLimit
+	}
+
+	s.lock()
+	defer s.mu.Unlock()
+
+	if err := s.begin(walSetCreditLimit, walArgs{AccountID: accountID, Amount: limit}); err != nil {
+		return err
+	}
+	defer s.end()
+
+	account, err := s.findAccountByID(accountID)
+	if err != nil {
+		return err
+	}
+
+	if account.Status == types.AccountStatusClosed {
+		return ErrAccountClosed
+	}
+
+	if limit < account.UsedCredit() {
+		return ErrCreditLimitInUse
+	}
+
+	account.CreditLimit = limit
+	return s.accounts.Save(account)
+}
@@ -0,0 +1,128 @@
+package wallet
+
+import (
+	"testing"
+)
+
+func TestService_SetCreditLimit_overdraft(t *testing.T) {
+	s := newTestService()
+	account, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Deposit(account.ID, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.Pay(account.ID, 300, "food")
+	if err != ErrNotEnoughBalance {
+		t.Fatalf("Pay(): must return ErrNotEnoughBalance without a limit, returned = %v", err)
+	}
+
+	err = s.SetCreditLimit(account.ID, 500)
+	if err != nil {
+		t.Fatalf("SetCreditLimit(): error = %v", err)
+	}
+	_, err = s.Pay(account.ID, 300, "food")
+	if err != nil {
+		t.Fatalf("Pay(): error = %v", err)
+	}
+
+	got, err := s.FindAccountByID(account.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Balance != -200 || got.UsedCredit() != 200 || got.Available() != 300 {
+		t.Errorf("FindAccountByID(): invalid credit state, account = %v", got)
+	}
+
+	_, err = s.Pay(account.ID, 301, "food")
+	if err != ErrNotEnoughBalance {
+		t.Errorf("Pay(): must return ErrNotEnoughBalance over the limit, returned = %v", err)
+	}
+
+	err = s.SetCreditLimit(account.ID, 199)
+	if err != ErrCreditLimitInUse {
+		t.Errorf("SetCreditLimit(): must return ErrCreditLimitInUse, returned = %v", err)
+	}
+	err = s.SetCreditLimit(account.ID, -1)
+	if err != ErrInvalidCreditLimit {
+		t.Errorf("SetCreditLimit(): must return ErrInvalidCreditLimit, returned = %v", err)
+	}
+	err = s.Close(account.ID)
+	if err != ErrAccountNotEmpty {
+		t.Errorf("Close(): must return ErrAccountNotEmpty with used credit, returned = %v", err)
+	}
+
+	discrepancies, err := s.Reconcile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(discrepancies) != 0 {
+		t.Errorf("Reconcile(): must be empty, got %v", discrepancies)
+	}
+}
+
+func TestService_SetCreditLimit_transfer(t *testing.T) {
+	s := newTestService()
+	from, to := s.addTransferAccounts(t)
+
+	err := s.SetCreditLimit(from.ID, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.Transfer(from.ID, to.ID, 1_500)
+	if err != nil {
+		t.Fatalf("Transfer(): error = %v", err)
+	}
+	s.checkBalance(t, from.ID, -500)
+
+	_, err = s.Transfer(from.ID, to.ID, 1)
+	if err != ErrNotEnoughBalance {
+		t.Errorf("Transfer(): must return ErrNotEnoughBalance over the limit, returned = %v", err)
+	}
+}
+
+func TestService_SetCreditLimit_exportImport(t *testing.T) {
+	s := newTestService()
+	account, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.SetCreditLimit(account.ID, 1_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.Pay(account.ID, 400, "food")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	err = s.Export(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	imported := NewService()
+	err = imported.Import(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := imported.FindAccountByID(account.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.CreditLimit != 1_000 || got.UsedCredit() != 400 {
+		t.Errorf("Import(): invalid credit state, account = %v", got)
+	}
+
+	discrepancies, err := imported.Reconcile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(discrepancies) != 0 {
+		t.Errorf("Reconcile(): must be empty, got %v", discrepancies)
+	}
+}
@@ -0,0 +1,372 @@
+package wallet
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/aminjonshermatov/wallet/pkg/types"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+var ErrInvalidDelimiter = errors.New("invalid csv delimiter")
+
+var accountColumns = []string{"id", "phone", "balance", "status", "creditLimit"}
+var paymentColumns = []string{"id", "accountId", "amount", "category", "status", "kind", "linkedId", "createdAt", "updatedAt", "refunded"}
+var favoriteColumns = []string{"id", "accountId", "name", "amount", "category", "position"}
+
+// validDelimiter mirrors the delimiters encoding/csv accepts.
+func validDelimiter(r rune) bool {
+	return r != 0 && r != '"' && r != '\r' && r != '\n' && utf8.ValidRune(r) && r != utf8.RuneError
+}
+
+func encodeCSVAccount(account *types.Account) []string {
+	return []string{
+		strconv.FormatInt(account.ID, 10),
+		string(account.Phone),
+		strconv.FormatInt(int64(account.Balance), 10),
+		string(account.Status),
+		strconv.FormatInt(int64(account.CreditLimit), 10),
+	}
+}
+
+func decodeCSVAccount(row csvRow) (*types.Account, error) {
+	id, err := row.int64("id")
+	if err != nil {
+		return nil, err
+	}
+	balance, err := row.int64("balance")
+	if err != nil {
+		return nil, err
+	}
+	creditLimit, err := row.int64("creditLimit")
+	if err != nil {
+		return nil, err
+	}
+
+	account := &types.Account{
+		ID:				id,
+		Phone:			types.Phone(row.get("phone")),
+		Balance:		types.Money(balance),
+		Status:			types.AccountStatus(row.get("status")),
+		CreditLimit:	types.Money(creditLimit),
+	}
+	if account.Status == "" {
+		account.Status = types.AccountStatusActive
+	}
+	return account, nil
+}
+
+func encodeCSVPayment(payment *types.Payment) []string {
+	return []string{
+		payment.ID,
+		strconv.FormatInt(payment.AccountID, 10),
+		strconv.FormatInt(int64(payment.Amount), 10),
+		string(payment.Category),
+		string(payment.Status),
+		string(payment.Kind),
+		payment.LinkedID,
+		encodeCSVTime(payment.CreatedAt),
+		encodeCSVTime(payment.UpdatedAt),
+		strconv.FormatInt(int64(payment.Refunded), 10),
+	}
+}
+
+func decodeCSVPayment(row csvRow) (*types.Payment, error) {
+	accountID, err := row.int64("accountId")
+	if err != nil {
+		return nil, err
+	}
+	amount, err := row.int64("amount")
+	if err != nil {
+		return nil, err
+	}
+	refunded, err := row.int64("refunded")
+	if err != nil {
+		return nil, err
+	}
+	createdAt, err := decodeCSVTime(row.get("createdAt"))
+	if err != nil {
+		return nil, &columnError{column: "createdAt", err: err}
+	}
+	updatedAt, err := decodeCSVTime(row.get("updatedAt"))
+	if err != nil {
+		return nil, &columnError{column: "updatedAt", err: err}
+	}
+
+	return &types.Payment{
+		ID:			row.get("id"),
+		AccountID:	accountID,
+		Amount:		types.Money(amount),
+		Refunded:	types.Money(refunded),
+		Category:	types.PaymentCategory(row.get("category")),
+		Status:		types.PaymentStatus(row.get("status")),
+		Kind:		types.PaymentKind(row.get("kind")),
+		LinkedID:	row.get("linkedId"),
+		CreatedAt:	createdAt,
+		UpdatedAt:	updatedAt,
+	}, nil
+}
+
+// encodeCSVTime writes RFC 3339 timestamps spreadsheets understand, the zero time is an empty cell.
+func encodeCSVTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+func decodeCSVTime(cell string) (time.Time, error) {
+	if cell == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339Nano, cell)
+}
+
+func encodeCSVFavorite(favorite *types.Favorite) []string {
+	return []string{
+		favorite.ID,
+		strconv.FormatInt(favorite.AccountID, 10),
+		favorite.Name,
+		strconv.FormatInt(int64(favorite.Amount), 10),
+		string(favorite.Category),
+		strconv.Itoa(favorite.Position),
+	}
+}
+
+func decodeCSVFavorite(row csvRow) (*types.Favorite, error) {
+	accountID, err := row.int64("accountId")
+	if err != nil {
+		return nil, err
+	}
+	amount, err := row.int64("amount")
+	if err != nil {
+		return nil, err
+	}
+	position, err := row.int64("position")
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.Favorite{
+		ID:			row.get("id"),
+		AccountID:	accountID,
+		Name:		row.get("name"),
+		Amount:		types.Money(amount),
+		Category:	types.PaymentCategory(row.get("category")),
+		Position:	int(position),
+	}, nil
+}
+
+// csvRow gives access to the cells of a record by the header names,
+// so columns may come in any order and missing columns read as empty cells.
+type csvRow struct {
+	columns	map[string]int
+	record	[]string
+}
+
+func (r csvRow) get(name string) string {
+	i, ok := r.columns[name]
+	if !ok || i >= len(r.record) {
+		return ""
+	}
+	return r.record[i]
+}
+
+// int64 parses a numeric cell, an empty cell is 0.
+func (r csvRow) int64(name string) (int64, error) {
+	cell := r.get(name)
+	if cell == "" {
+		return 0, nil
+	}
+
+	value, err := strconv.ParseInt(cell, 10, 64)
+	if err != nil {
+		return 0, &columnError{column: name, err: fmt.Errorf("%w: %v", ErrMalformedRecord, err)}
+	}
+	return value, nil
+}
+
+func exportCSV(s *Service, dir string, delimiter rune) error {
+	accounts, err := s.accounts.All()
+	if err != nil {
+		return err
+	}
+	records := make([][]string, 0, len(accounts))
+	for _, account := range accounts {
+		records = append(records, encodeCSVAccount(account))
+	}
+	err = writeCSV(dir + "/" + "accounts.csv", delimiter, accountColumns, records)
+	if err != nil {
+		return err
+	}
+
+	payments, err := s.payments.All()
+	if err != nil {
+		return err
+	}
+	records = make([][]string, 0, len(payments))
+	for _, payment := range payments {
+		records = append(records, encodeCSVPayment(payment))
+	}
+	err = writeCSV(dir + "/" + "payments.csv", delimiter, paymentColumns, records)
+	if err != nil {
+		return err
+	}
+
+	favorites, err := s.favorites.All()
+	if err != nil {
+		return err
+	}
+	records = make([][]string, 0, len(favorites))
+	for _, favorite := range favorites {
+		records = append(records, encodeCSVFavorite(favorite))
+	}
+	return writeCSV(dir + "/" + "favorites.csv", delimiter, favoriteColumns, records)
+}
+
+// writeCSV writes the header and the records.
+func writeCSV(path string, delimiter rune, header []string, records [][]string) error {
+	return writeRecords(path, len(records), func(w io.Writer) error {
+		writer := csv.NewWriter(w)
+		writer.Comma = delimiter
+		err := writer.Write(header)
+		if err != nil {
+			return err
+		}
+		return writer.WriteAll(records)
+	})
+}
+
+func importCSV(s *Service, dir string, delimiter rune, report *ImportReport) error {
+	err := readCSV(dir + "/" + "accounts.csv", delimiter, report, func(row csvRow) error {
+		account, err := decodeCSVAccount(row)
+		if err != nil {
+			return err
+		}
+		return s.restoreAccount(account)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = readCSV(dir + "/" + "payments.csv", delimiter, report, func(row csvRow) error {
+		payment, err := decodeCSVPayment(row)
+		if err != nil {
+			return err
+		}
+		return s.restorePayment(payment)
+	})
+	if err != nil {
+		return err
+	}
+
+	return readCSV(dir + "/" + "favorites.csv", delimiter, report, func(row csvRow) error {
+		favorite, err := decodeCSVFavorite(row)
+		if err != nil {
+			return err
+		}
+		return s.restoreFavorite(favorite)
+	})
+}
+
+// readCSV calls read for every record after the header, a missing file has no records.
+// Records must have an id cell, rows that can't be parsed and records read finds invalid are reported.
+func readCSV(path string, delimiter rune, report *ImportReport, read func(row csvRow) error) (err error) {
+	src, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := src.Close(); cerr != nil {
+			if err == nil {
+				err = cerr
+			}
+		}
+	}()
+
+	lines := &lineReader{src: bufio.NewReader(src)}
+	reader := csv.NewReader(lines)
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[name] = i
+	}
+	if _, ok := columns["id"]; !ok {
+		return fmt.Errorf("%w: %s has no id column", ErrMalformedRecord, path)
+	}
+
+	name := filepath.Base(path)
+	for {
+		lines.start = 0
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+
+		// quoted cells may span lines, so records are reported at the line they start on
+		line := lines.start
+		if err == nil {
+			row := csvRow{columns: columns, record: record}
+			if row.get("id") == "" {
+				err = &columnError{column: "id", err: fmt.Errorf("%w: empty id", ErrMalformedRecord)}
+			} else {
+				err = read(row)
+			}
+		}
+
+		err = report.reject(name, line, err)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// lineReader passes the file to a csv.Reader one line per Read, so the reader
+// never buffers past the record it returns and start is the line the record starts on.
+type lineReader struct {
+	src		*bufio.Reader
+	pending	[]byte
+	line	int
+	// start is the first non-empty line read since it was reset, csv skips empty lines.
+	start	int
+}
+
+func (r *lineReader) Read(p []byte) (int, error) {
+	if len(r.pending) == 0 {
+		line, err := r.src.ReadBytes('\n')
+		if len(line) == 0 {
+			return 0, err
+		}
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+
+		r.line++
+		if r.start == 0 && string(line) != "\n" && string(line) != "\r\n" {
+			r.start = r.line
+		}
+		r.pending = line
+	}
+
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
@@ -0,0 +1,132 @@
+package wallet
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestService_Export_csv(t *testing.T) {
+	for _, delimiter := range []rune{',', ';', '\t'} {
+		s := newDumpTestService(t)
+		dir := t.TempDir()
+
+		err := s.Export(dir, WithFormat(FormatCSV), WithDelimiter(delimiter))
+		if err != nil {
+			t.Fatalf("Export(%q): %v", delimiter, err)
+		}
+
+		src, err := os.Open(filepath.Join(currentGeneration(t, dir), "payments.csv"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader := csv.NewReader(src)
+		reader.Comma = delimiter
+		records, err := reader.ReadAll()
+		_ = src.Close()
+		if err != nil {
+			t.Fatalf("Export(%q): payments.csv is not valid csv: %v", delimiter, err)
+		}
+		if strings.Join(records[0], ",") != strings.Join(paymentColumns, ",") {
+			t.Errorf("Export(%q): got header %v, want %v", delimiter, records[0], paymentColumns)
+		}
+		if len(records) != 3 || records[1][3] != "food, \"cafe\" {lunch}" {
+			t.Errorf("Export(%q): got records %q", delimiter, records)
+		}
+
+		imported := NewService()
+		err = imported.Import(dir, WithFormat(FormatCSV), WithDelimiter(delimiter))
+		if err != nil {
+			t.Fatalf("Import(%q): %v", delimiter, err)
+		}
+		assertSameState(t, imported, s.Service)
+	}
+}
+
+func TestService_Import_csvColumnOrder(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "accounts.csv"), []byte("balance,id,phone,note\n100,1,+992000000001,x\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewService()
+	err = s.Import(dir, WithFormat(FormatCSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+	account, err := s.FindAccountByID(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.Balance != 100 || account.Phone != "+992000000001" || account.Status != "ACTIVE" {
+		t.Errorf("Import(): invalid account %v", account)
+	}
+}
+
+func TestService_Import_csvMalformed(t *testing.T) {
+	tests := []struct{
+		name	string
+		content	string
+	}{
+		{"no id column", "phone,balance\n+992000000001,100\n"},
+		{"empty id", "id,phone,balance\n,+992000000001,100\n"},
+		{"invalid number", "id,phone,balance\n1,+992000000001,ten\n"},
+		{"bare quote", "id,phone,balance\n1,\"+992\"000000001,100\n"},
+	}
+
+	for _, tt := range tests {
+		dir := t.TempDir()
+		err := os.WriteFile(filepath.Join(dir, "accounts.csv"), []byte(tt.content), 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = NewService().Import(dir, WithFormat(FormatCSV))
+		if err == nil {
+			t.Errorf("Import(): must fail for %s", tt.name)
+		}
+	}
+}
+
+func TestService_Export_invalidDelimiter(t *testing.T) {
+	for _, delimiter := range []rune{'"', '\n', '\r', 0} {
+		err := NewService().Export(t.TempDir(), WithFormat(FormatCSV), WithDelimiter(delimiter))
+		if err != ErrInvalidDelimiter {
+			t.Errorf("Export(%q): must return ErrInvalidDelimiter, returned = %v", delimiter, err)
+		}
+	}
+}
+
+func TestService_HistoryToFiles_csv(t *testing.T) {
+	s := newDumpTestService(t)
+	payments, err := s.ExportAccountHistory(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	err = s.HistoryToFiles(payments, dir, 1, WithFormat(FormatCSV), WithDelimiter(';'))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, name := range []string{"payments1.csv", "payments2.csv"} {
+		src, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader := csv.NewReader(src)
+		reader.Comma = ';'
+		records, err := reader.ReadAll()
+		_ = src.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) != 2 || records[0][0] != "id" || records[1][0] != payments[i].ID {
+			t.Errorf("HistoryToFiles(): %s got records %q", name, records)
+		}
+	}
+}
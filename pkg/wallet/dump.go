@@ -0,0 +1,188 @@
+package wallet
+
+import (
+	"errors"
+	"github.com/aminjonshermatov/wallet/pkg/types"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrMalformedRecord = errors.New("malformed record")
+
+func encodeAccount(account *types.Account) string {
+	return strconv.FormatInt(account.ID, 10) + ";" +
+		string(account.Phone) + ";" +
+		strconv.FormatInt(int64(account.Balance), 10) + ";" +
+		string(account.Status) + ";" +
+		strconv.FormatInt(int64(account.CreditLimit), 10)
+}
+
+func decodeAccount(line string) (*types.Account, error) {
+	col := strings.Split(line, ";")
+	if len(col) < 3 {
+		return nil, ErrMalformedRecord
+	}
+
+	id, err := strconv.ParseInt(col[0], 10, 64)
+	if err != nil {
+		return nil, &columnError{column: "id", err: err}
+	}
+
+	balance, err := strconv.ParseInt(col[2], 10, 64)
+	if err != nil {
+		return nil, &columnError{column: "balance", err: err}
+	}
+
+	account := &types.Account{
+		ID:			id,
+		Phone:		types.Phone(col[1]),
+		Balance:	types.Money(balance),
+		Status:		types.AccountStatusActive,
+	}
+	if len(col) >= 4 && col[3] != "" {
+		account.Status = types.AccountStatus(col[3])
+	}
+	if len(col) >= 5 {
+		creditLimit, err := decodeOptionalInt(col[4])
+		if err != nil {
+			return nil, &columnError{column: "creditLimit", err: err}
+		}
+		account.CreditLimit = types.Money(creditLimit)
+	}
+
+	return account, nil
+}
+
+func encodePayment(payment *types.Payment) string {
+	return payment.ID + ";" +
+		strconv.FormatInt(payment.AccountID, 10) + ";" +
+		strconv.FormatInt(int64(payment.Amount), 10) + ";" +
+		string(payment.Category) + ";" +
+		string(payment.Status) + ";" +
+		string(payment.Kind) + ";" +
+		payment.LinkedID + ";" +
+		encodeTime(payment.CreatedAt) + ";" +
+		encodeTime(payment.UpdatedAt) + ";" +
+		strconv.FormatInt(int64(payment.Refunded), 10)
+}
+
+func decodePayment(line string) (*types.Payment, error) {
+	col := strings.Split(line, ";")
+	if len(col) < 5 {
+		return nil, ErrMalformedRecord
+	}
+
+	accountID, err := strconv.ParseInt(col[1], 10, 64)
+	if err != nil {
+		return nil, &columnError{column: "accountId", err: err}
+	}
+
+	amount, err := strconv.ParseInt(col[2], 10, 64)
+	if err != nil {
+		return nil, &columnError{column: "amount", err: err}
+	}
+
+	payment := &types.Payment{
+		ID:			col[0],
+		AccountID:	accountID,
+		Amount:		types.Money(amount),
+		Category:	types.PaymentCategory(col[3]),
+		Status:		types.PaymentStatus(col[4]),
+	}
+	if len(col) >= 7 {
+		payment.Kind = types.PaymentKind(col[5])
+		payment.LinkedID = col[6]
+	}
+	if len(col) >= 9 {
+		payment.CreatedAt, err = decodeTime(col[7])
+		if err != nil {
+			return nil, &columnError{column: "createdAt", err: err}
+		}
+		payment.UpdatedAt, err = decodeTime(col[8])
+		if err != nil {
+			return nil, &columnError{column: "updatedAt", err: err}
+		}
+	}
+	if len(col) >= 10 {
+		refunded, err := decodeOptionalInt(col[9])
+		if err != nil {
+			return nil, &columnError{column: "refunded", err: err}
+		}
+		payment.Refunded = types.Money(refunded)
+	}
+
+	return payment, nil
+}
+
+// encodeTime writes unix nanoseconds, the zero time is written as an empty column.
+func encodeTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+func decodeTime(col string) (time.Time, error) {
+	if col == "" {
+		return time.Time{}, nil
+	}
+
+	nanos, err := strconv.ParseInt(col, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(0, nanos), nil
+}
+
+// decodeOptionalInt reads a column added after the first dump version,
+// the column is empty in files written before it was added.
+func decodeOptionalInt(col string) (int64, error) {
+	if col == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(col, 10, 64)
+}
+
+func encodeFavorite(favorite *types.Favorite) string {
+	return favorite.ID + ";" +
+		strconv.FormatInt(favorite.AccountID, 10) + ";" +
+		favorite.Name + ";" +
+		strconv.FormatInt(int64(favorite.Amount), 10) + ";" +
+		string(favorite.Category) + ";" +
+		strconv.Itoa(favorite.Position)
+}
+
+func decodeFavorite(line string) (*types.Favorite, error) {
+	col := strings.Split(line, ";")
+	if len(col) < 5 {
+		return nil, ErrMalformedRecord
+	}
+
+	accountID, err := strconv.ParseInt(col[1], 10, 64)
+	if err != nil {
+		return nil, &columnError{column: "accountId", err: err}
+	}
+
+	amount, err := strconv.ParseInt(col[3], 10, 64)
+	if err != nil {
+		return nil, &columnError{column: "amount", err: err}
+	}
+
+	favorite := &types.Favorite{
+		ID:			col[0],
+		AccountID:	accountID,
+		Name:		col[2],
+		Amount:		types.Money(amount),
+		Category:	types.PaymentCategory(col[4]),
+	}
+	if len(col) >= 6 {
+		position, err := decodeOptionalInt(col[5])
+		if err != nil {
+			return nil, &columnError{column: "position", err: err}
+		}
+		favorite.Position = int(position)
+	}
+
+	return favorite, nil
+}
@@ -0,0 +1,224 @@
+package wallet
+
+import (
+	"errors"
+	"github.com/aminjonshermatov/wallet/pkg/types"
+	"sort"
+	"strings"
+)
+
+var ErrInvalidFavoriteName = errors.New("favorite name must be non empty and must not contain ';' or new lines")
+var ErrFavoriteNameTaken = errors.New("account already has a favorite with this name")
+var ErrInvalidFavoriteOrder = errors.New("order must list every favorite of the account exactly once")
+
+// FindFavoriteByID returns the favorite with the given id.
+func (s *Service) FindFavoriteByID(favoriteID string) (*types.Favorite, error) {
+	s.rlock()
+	defer s.mu.RUnlock()
+
+	return s.favorites.FindByID(favoriteID)
+}
+
+// AccountFavorites returns favorites of the account in the order set by ReorderFavorites.
+func (s *Service) AccountFavorites(accountID int64) ([]types.Favorite, error) {
+	s.rlock()
+	defer s.mu.RUnlock()
+
+	_, err := s.findAccountByID(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	favorites, err := s.accountFavorites(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]types.Favorite, 0, len(favorites))
+	for _, favorite := range favorites {
+		res = append(res, *favorite)
+	}
+	return res, nil
+}
+
+// RenameFavorite changes the favorite name, names are unique per account.
+func (s *Service) RenameFavorite(favoriteID string, name string) (*types.Favorite, error) {
+	s.lock()
+	defer s.mu.Unlock()
+
+	if err := s.begin(walRenameFavorite, walArgs{FavoriteID: favoriteID, Name: name}); err != nil {
+		return nil, err
+	}
+	defer s.end()
+
+	favorite, err := s.favorites.FindByID(favoriteID)
+	if err != nil {
+		return nil, err
+	}
+
+	favorites, err := s.accountFavorites(favorite.AccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkFavoriteName(favorites, favorite.ID, name)
+	if err != nil {
+		return nil, err
+	}
+
+	favorite.Name = name
+	err = s.favorites.Save(favorite)
+	if err != nil {
+		return nil, err
+	}
+
+	return favorite, nil
+}
+
+// UpdateFavoriteAmount changes the amount paid by PayFromFavorite.
+func (s *Service) UpdateFavoriteAmount(favoriteID string, amount types.Money) (*types.Favorite, error) {
+	if amount <= 0 {
+		return nil, ErrAmountMustBePositive
+	}
+
+	s.lock()
+	defer s.mu.Unlock()
+
+	if err := s.begin(walUpdateFavoriteAmount, walArgs{FavoriteID: favoriteID, Amount: amount}); err != nil {
+		return nil, err
+	}
+	defer s.end()
+
+	favorite, err := s.favorites.FindByID(favoriteID)
+	if err != nil {
+		return nil, err
+	}
+
+	favorite.Amount = amount
+	err = s.favorites.Save(favorite)
+	if err != nil {
+		return nil, err
+	}
+
+	return favorite, nil
+}
+
+// ReorderFavorites sets the order of the account favorites,
+// favoriteIDs must contain every favorite of the account exactly once.
+func (s *Service) ReorderFavorites(accountID int64, favoriteIDs []string) error {
+	s.lock()
+	defer s.mu.Unlock()
+
+	if err := s.begin(walReorderFavorites, walArgs{AccountID: accountID, IDs: favoriteIDs}); err != nil {
+		return err
+	}
+	defer s.end()
+
+	_, err := s.findAccountByID(accountID)
+	if err != nil {
+		return err
+	}
+
+	favorites, err := s.accountFavorites(accountID)
+	if err != nil {
+		return err
+	}
+
+	if len(favoriteIDs) != len(favorites) {
+		return ErrInvalidFavoriteOrder
+	}
+
+	positions := make(map[string]int, len(favoriteIDs))
+	for i, id := range favoriteIDs {
+		if _, ok := positions[id]; ok {
+			return ErrInvalidFavoriteOrder
+		}
+		positions[id] = i
+	}
+	for _, favorite := range favorites {
+		if _, ok := positions[favorite.ID]; !ok {
+			return ErrInvalidFavoriteOrder
+		}
+	}
+
+	for _, favorite := range favorites {
+		favorite.Position = positions[favorite.ID]
+		err = s.favorites.Save(favorite)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RemoveFavorite deletes the favorite, the order of the rest is kept.
+func (s *Service) RemoveFavorite(favoriteID string) error {
+	s.lock()
+	defer s.mu.Unlock()
+
+	if err := s.begin(walRemoveFavorite, walArgs{FavoriteID: favoriteID}); err != nil {
+		return err
+	}
+	defer s.end()
+
+	favorite, err := s.favorites.FindByID(favoriteID)
+	if err != nil {
+		return err
+	}
+
+	err = s.favorites.Delete(favorite.ID)
+	if err != nil {
+		return err
+	}
+
+	favorites, err := s.accountFavorites(favorite.AccountID)
+	if err != nil {
+		return err
+	}
+
+	for i, rest := range favorites {
+		if rest.Position != i {
+			rest.Position = i
+			err = s.favorites.Save(rest)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// accountFavorites returns copies of the account favorites ordered by position.
+func (s *Service) accountFavorites(accountID int64) ([]*types.Favorite, error) {
+	favorites, err := s.favorites.FindByAccountID(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(favorites, func(i, j int) bool {
+		return favorites[i].Position < favorites[j].Position
+	})
+	return favorites, nil
+}
+
+func checkFavoriteName(favorites []*types.Favorite, favoriteID string, name string) error {
+	if strings.TrimSpace(name) == "" || strings.ContainsAny(name, ";\r\n") {
+		return ErrInvalidFavoriteName
+	}
+
+	for _, favorite := range favorites {
+		if favorite.ID != favoriteID && favorite.Name == name {
+			return ErrFavoriteNameTaken
+		}
+	}
+	return nil
+}
+
+func nextFavoritePosition(favorites []*types.Favorite) int {
+	if len(favorites) == 0 {
+		return 0
+	}
+	return favorites[len(favorites)-1].Position + 1
+}
@@ -0,0 +1,283 @@
+package wallet
+
+import (
+	"github.com/aminjonshermatov/wallet/pkg/types"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// addFavorites registers an account with one payment and makes favorites of it with the given names.
+func (s *testService) addFavorites(t *testing.T, names ...string) (*types.Account, []*types.Favorite) {
+	account, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	favorites := make([]*types.Favorite, 0, len(names))
+	for _, name := range names {
+		favorite, err := s.FavoritePayment(payments[0].ID, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		favorites = append(favorites, favorite)
+	}
+	return account, favorites
+}
+
+func favoriteNames(favorites []types.Favorite) []string {
+	names := make([]string, 0, len(favorites))
+	for _, favorite := range favorites {
+		names = append(names, favorite.Name)
+	}
+	return names
+}
+
+func TestService_FavoritePayment_validation(t *testing.T) {
+	s := newTestService()
+	_, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"", "  ", "a;b", "a\nb"} {
+		_, err = s.FavoritePayment(payments[0].ID, name)
+		if err != ErrInvalidFavoriteName {
+			t.Errorf("FavoritePayment(%q): must return ErrInvalidFavoriteName, returned = %v", name, err)
+		}
+	}
+
+	_, err = s.FavoritePayment(payments[0].ID, "osh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.FavoritePayment(payments[0].ID, "osh")
+	if err != ErrFavoriteNameTaken {
+		t.Errorf("FavoritePayment(): must return ErrFavoriteNameTaken, returned = %v", err)
+	}
+}
+
+func TestService_AccountFavorites(t *testing.T) {
+	s := newTestService()
+	account, favorites := s.addFavorites(t, "osh", "taxi", "phone")
+
+	got, err := s.AccountFavorites(account.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := favoriteNames(got); !reflect.DeepEqual(names, []string{"osh", "taxi", "phone"}) {
+		t.Errorf("AccountFavorites(): got %v", names)
+	}
+
+	found, err := s.FindFavoriteByID(favorites[1].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *found != *favorites[1] {
+		t.Errorf("FindFavoriteByID(): got %v, want %v", found, favorites[1])
+	}
+
+	_, err = s.FindFavoriteByID("missing")
+	if err != ErrFavoriteNotFound {
+		t.Errorf("FindFavoriteByID(): must return ErrFavoriteNotFound, returned = %v", err)
+	}
+	_, err = s.AccountFavorites(account.ID + 1)
+	if err != ErrAccountNotFound {
+		t.Errorf("AccountFavorites(): must return ErrAccountNotFound, returned = %v", err)
+	}
+}
+
+func TestService_RenameFavorite(t *testing.T) {
+	s := newTestService()
+	_, favorites := s.addFavorites(t, "osh", "taxi")
+
+	got, err := s.RenameFavorite(favorites[0].ID, "plov")
+	if err != nil {
+		t.Fatalf("RenameFavorite(): error = %v", err)
+	}
+	if got.Name != "plov" {
+		t.Errorf("RenameFavorite(): got %v", got)
+	}
+
+	_, err = s.RenameFavorite(favorites[0].ID, "plov")
+	if err != nil {
+		t.Errorf("RenameFavorite(): keeping the same name must succeed, returned = %v", err)
+	}
+	_, err = s.RenameFavorite(favorites[0].ID, "taxi")
+	if err != ErrFavoriteNameTaken {
+		t.Errorf("RenameFavorite(): must return ErrFavoriteNameTaken, returned = %v", err)
+	}
+	_, err = s.RenameFavorite(favorites[0].ID, "")
+	if err != ErrInvalidFavoriteName {
+		t.Errorf("RenameFavorite(): must return ErrInvalidFavoriteName, returned = %v", err)
+	}
+	_, err = s.RenameFavorite("missing", "osh")
+	if err != ErrFavoriteNotFound {
+		t.Errorf("RenameFavorite(): must return ErrFavoriteNotFound, returned = %v", err)
+	}
+}
+
+func TestService_UpdateFavoriteAmount(t *testing.T) {
+	s := newTestService()
+	account, favorites := s.addFavorites(t, "osh")
+
+	_, err := s.UpdateFavoriteAmount(favorites[0].ID, 0)
+	if err != ErrAmountMustBePositive {
+		t.Errorf("UpdateFavoriteAmount(): must return ErrAmountMustBePositive, returned = %v", err)
+	}
+
+	_, err = s.UpdateFavoriteAmount(favorites[0].ID, 7)
+	if err != nil {
+		t.Fatalf("UpdateFavoriteAmount(): error = %v", err)
+	}
+
+	before, err := s.FindAccountByID(account.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payment, err := s.PayFromFavorite(favorites[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payment.Amount != 7 {
+		t.Errorf("PayFromFavorite(): must use the new amount, got %v", payment.Amount)
+	}
+	s.checkBalance(t, account.ID, before.Balance - 7)
+}
+
+func TestService_ReorderFavorites(t *testing.T) {
+	s := newTestService()
+	account, favorites := s.addFavorites(t, "osh", "taxi", "phone")
+
+	err := s.ReorderFavorites(account.ID, []string{favorites[2].ID, favorites[0].ID, favorites[1].ID})
+	if err != nil {
+		t.Fatalf("ReorderFavorites(): error = %v", err)
+	}
+
+	got, err := s.AccountFavorites(account.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := favoriteNames(got); !reflect.DeepEqual(names, []string{"phone", "osh", "taxi"}) {
+		t.Errorf("AccountFavorites(): got %v", names)
+	}
+
+	invalid := [][]string{
+		{favorites[0].ID, favorites[1].ID},
+		{favorites[0].ID, favorites[0].ID, favorites[1].ID},
+		{favorites[0].ID, favorites[1].ID, "missing"},
+	}
+	for _, order := range invalid {
+		err = s.ReorderFavorites(account.ID, order)
+		if err != ErrInvalidFavoriteOrder {
+			t.Errorf("ReorderFavorites(%v): must return ErrInvalidFavoriteOrder, returned = %v", order, err)
+		}
+	}
+}
+
+func TestService_RemoveFavorite(t *testing.T) {
+	s := newTestService()
+	account, favorites := s.addFavorites(t, "osh", "taxi", "phone")
+
+	err := s.RemoveFavorite(favorites[1].ID)
+	if err != nil {
+		t.Fatalf("RemoveFavorite(): error = %v", err)
+	}
+
+	got, err := s.AccountFavorites(account.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := favoriteNames(got); !reflect.DeepEqual(names, []string{"osh", "phone"}) {
+		t.Errorf("AccountFavorites(): got %v", names)
+	}
+	if got[1].Position != 1 {
+		t.Errorf("RemoveFavorite(): positions must stay dense, got %v", got)
+	}
+
+	_, err = s.PayFromFavorite(favorites[1].ID)
+	if err != ErrFavoriteNotFound {
+		t.Errorf("PayFromFavorite(): must return ErrFavoriteNotFound, returned = %v", err)
+	}
+	err = s.RemoveFavorite(favorites[1].ID)
+	if err != ErrFavoriteNotFound {
+		t.Errorf("RemoveFavorite(): must return ErrFavoriteNotFound, returned = %v", err)
+	}
+
+	_, err = s.FavoritePayment(favorites[0].ID, "taxi")
+	if err != ErrPaymentNotFound {
+		t.Errorf("FavoritePayment(): must return ErrPaymentNotFound, returned = %v", err)
+	}
+}
+
+func TestService_Favorites_exportImport(t *testing.T) {
+	s := newTestService()
+	account, favorites := s.addFavorites(t, "osh", "taxi", "phone")
+	err := s.RemoveFavorite(favorites[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.ReorderFavorites(account.ID, []string{favorites[2].ID, favorites[1].ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	err = s.Export(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	imported := NewService()
+	err = imported.Import(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := s.AccountFavorites(account.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := imported.AccountFavorites(account.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("AccountFavorites(): after Import got %v, want %v", got, want)
+	}
+}
+
+func TestFileFavoriteRepository_reopenAfterDelete(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "favorites.dump")
+	repo, err := NewFileFavoriteRepository(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"a", "b"} {
+		err = repo.Save(&types.Favorite{ID: id, AccountID: 1, Name: id, Amount: 10, Category: "food"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = repo.Delete("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = repo.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := NewFileFavoriteRepository(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = reopened.Close() }()
+
+	all, err := reopened.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 1 || all[0].ID != "b" {
+		t.Errorf("All(): after reopen got %v", all)
+	}
+}
@@ -0,0 +1,204 @@
+package wallet
+
+import (
+	"bufio"
+	"github.com/aminjonshermatov/wallet/pkg/types"
+	"io"
+	"os"
+	"strings"
+)
+
+// deletedPrefix marks a line that removes the record with the id following it.
+const deletedPrefix = "-"
+
+// recordFile is an append-only file of dump lines, the last line for an id wins.
+type recordFile struct {
+	file	*os.File
+}
+
+func openRecordFile(path string, load func(line string) error) (*recordFile, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0660)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadString('\n')
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			_ = file.Close()
+			return nil, err
+		}
+
+		err = load(strings.TrimSuffix(line, "\n"))
+		if err != nil {
+			_ = file.Close()
+			return nil, err
+		}
+	}
+
+	return &recordFile{file: file}, nil
+}
+
+func (f *recordFile) append(line string) error {
+	_, err := f.file.WriteString(line + "\n")
+	return err
+}
+
+func (f *recordFile) Close() error {
+	return f.file.Close()
+}
+
+// FileAccountRepository keeps accounts in memory and appends every change to a file.
+type FileAccountRepository struct {
+	*recordFile
+	memory	*MemoryAccountRepository
+}
+
+func NewFileAccountRepository(path string) (*FileAccountRepository, error) {
+	memory := NewMemoryAccountRepository()
+	file, err := openRecordFile(path, func(line string) error {
+		account, err := decodeAccount(line)
+		if err != nil {
+			return err
+		}
+		return memory.Save(account)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileAccountRepository{recordFile: file, memory: memory}, nil
+}
+
+func (r *FileAccountRepository) NextID() (int64, error) {
+	return r.memory.NextID()
+}
+
+func (r *FileAccountRepository) Save(account *types.Account) error {
+	err := r.append(encodeAccount(account))
+	if err != nil {
+		return err
+	}
+
+	return r.memory.Save(account)
+}
+
+func (r *FileAccountRepository) FindByID(id int64) (*types.Account, error) {
+	return r.memory.FindByID(id)
+}
+
+func (r *FileAccountRepository) FindByPhone(phone types.Phone) (*types.Account, error) {
+	return r.memory.FindByPhone(phone)
+}
+
+func (r *FileAccountRepository) All() ([]*types.Account, error) {
+	return r.memory.All()
+}
+
+// FilePaymentRepository keeps payments in memory and appends every change to a file.
+type FilePaymentRepository struct {
+	*recordFile
+	memory	*MemoryPaymentRepository
+}
+
+func NewFilePaymentRepository(path string) (*FilePaymentRepository, error) {
+	memory := NewMemoryPaymentRepository()
+	file, err := openRecordFile(path, func(line string) error {
+		payment, err := decodePayment(line)
+		if err != nil {
+			return err
+		}
+		return memory.Save(payment)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &FilePaymentRepository{recordFile: file, memory: memory}, nil
+}
+
+func (r *FilePaymentRepository) Save(payment *types.Payment) error {
+	err := r.append(encodePayment(payment))
+	if err != nil {
+		return err
+	}
+
+	return r.memory.Save(payment)
+}
+
+func (r *FilePaymentRepository) FindByID(id string) (*types.Payment, error) {
+	return r.memory.FindByID(id)
+}
+
+func (r *FilePaymentRepository) FindByAccountID(accountID int64) ([]*types.Payment, error) {
+	return r.memory.FindByAccountID(accountID)
+}
+
+func (r *FilePaymentRepository) All() ([]*types.Payment, error) {
+	return r.memory.All()
+}
+
+// FileFavoriteRepository keeps favorites in memory and appends every change to a file.
+type FileFavoriteRepository struct {
+	*recordFile
+	memory	*MemoryFavoriteRepository
+}
+
+func NewFileFavoriteRepository(path string) (*FileFavoriteRepository, error) {
+	memory := NewMemoryFavoriteRepository()
+	file, err := openRecordFile(path, func(line string) error {
+		if strings.HasPrefix(line, deletedPrefix) {
+			return memory.Delete(strings.TrimPrefix(line, deletedPrefix))
+		}
+
+		favorite, err := decodeFavorite(line)
+		if err != nil {
+			return err
+		}
+		return memory.Save(favorite)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileFavoriteRepository{recordFile: file, memory: memory}, nil
+}
+
+func (r *FileFavoriteRepository) Save(favorite *types.Favorite) error {
+	err := r.append(encodeFavorite(favorite))
+	if err != nil {
+		return err
+	}
+
+	return r.memory.Save(favorite)
+}
+
+func (r *FileFavoriteRepository) Delete(id string) error {
+	_, err := r.memory.FindByID(id)
+	if err != nil {
+		return err
+	}
+
+	err = r.append(deletedPrefix + id)
+	if err != nil {
+		return err
+	}
+
+	return r.memory.Delete(id)
+}
+
+func (r *FileFavoriteRepository) FindByID(id string) (*types.Favorite, error) {
+	return r.memory.FindByID(id)
+}
+
+func (r *FileFavoriteRepository) FindByAccountID(accountID int64) ([]*types.Favorite, error) {
+	return r.memory.FindByAccountID(accountID)
+}
+
+func (r *FileFavoriteRepository) All() ([]*types.Favorite, error) {
+	return r.memory.All()
+}
@@ -0,0 +1,624 @@
+package wallet
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aminjonshermatov/wallet/pkg/types"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var ErrUnknownFormat = errors.New("unknown dump format")
+
+// Format selects how Export writes and Import reads accounts, payments and favorites.
+// Idempotency keys and spending limits are kept in their own dump files in every format.
+type Format string
+
+const (
+	// FormatDump is the ';'-separated text format, one record per line.
+	FormatDump		Format = "dump"
+	// FormatJSON writes the whole state as one JSON document in wallet.json.
+	FormatJSON		Format = "json"
+	// FormatJSONLines writes one JSON object per line to accounts.jsonl, payments.jsonl and favorites.jsonl.
+	FormatJSONLines	Format = "jsonl"
+	// FormatCSV writes RFC 4180 accounts.csv, payments.csv and favorites.csv with a header row.
+	FormatCSV		Format = "csv"
+)
+
+type dumpOptions struct {
+	format		Format
+	delimiter	rune
+	lenient		bool
+	report		*ImportReport
+}
+
+// DumpOption configures Export and Import.
+type DumpOption func(o *dumpOptions)
+
+// WithFormat selects the dump format, FormatDump is used by default.
+func WithFormat(format Format) DumpOption {
+	return func(o *dumpOptions) {
+		o.format = format
+	}
+}
+
+// WithDelimiter sets the field delimiter of FormatCSV, ',' is used by default.
+func WithDelimiter(delimiter rune) DumpOption {
+	return func(o *dumpOptions) {
+		o.delimiter = delimiter
+	}
+}
+
+func newDumpOptions(options []DumpOption) (*dumpOptions, error) {
+	o := &dumpOptions{format: FormatDump, delimiter: ','}
+	for _, option := range options {
+		option(o)
+	}
+
+	if !validDelimiter(o.delimiter) {
+		return nil, ErrInvalidDelimiter
+	}
+
+	switch o.format {
+	case FormatDump, FormatJSON, FormatJSONLines, FormatCSV:
+		return o, nil
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+// extension is the file name extension of payment history files in the format.
+func (f Format) extension() string {
+	if f == FormatJSONLines {
+		return ".jsonl"
+	}
+	return "." + string(f)
+}
+
+type jsonAccount struct {
+	ID			int64				`json:"id"`
+	Phone		types.Phone			`json:"phone"`
+	Balance		types.Money			`json:"balance"`
+	Status		types.AccountStatus	`json:"status"`
+	CreditLimit	types.Money			`json:"creditLimit"`
+}
+
+type jsonPayment struct {
+	ID			string					`json:"id"`
+	AccountID	int64					`json:"accountId"`
+	Amount		types.Money				`json:"amount"`
+	Refunded	types.Money				`json:"refunded"`
+	Category	types.PaymentCategory	`json:"category"`
+	Status		types.PaymentStatus		`json:"status"`
+	Kind		types.PaymentKind		`json:"kind,omitempty"`
+	LinkedID	string					`json:"linkedId,omitempty"`
+	CreatedAt	*time.Time				`json:"createdAt,omitempty"`
+	UpdatedAt	*time.Time				`json:"updatedAt,omitempty"`
+}
+
+type jsonFavorite struct {
+	ID			string					`json:"id"`
+	AccountID	int64					`json:"accountId"`
+	Name		string					`json:"name"`
+	Amount		types.Money				`json:"amount"`
+	Category	types.PaymentCategory	`json:"category"`
+	Position	int						`json:"position"`
+}
+
+type jsonState struct {
+	Accounts	[]jsonAccount	`json:"accounts"`
+	Payments	[]jsonPayment	`json:"payments"`
+	Favorites	[]jsonFavorite	`json:"favorites"`
+}
+
+func newJSONAccount(account *types.Account) jsonAccount {
+	return jsonAccount{
+		ID:				account.ID,
+		Phone:			account.Phone,
+		Balance:		account.Balance,
+		Status:			account.Status,
+		CreditLimit:	account.CreditLimit,
+	}
+}
+
+func (a jsonAccount) account() *types.Account {
+	account := &types.Account{
+		ID:				a.ID,
+		Phone:			a.Phone,
+		Balance:		a.Balance,
+		Status:			a.Status,
+		CreditLimit:	a.CreditLimit,
+	}
+	if account.Status == "" {
+		account.Status = types.AccountStatusActive
+	}
+	return account
+}
+
+func newJSONPayment(payment *types.Payment) jsonPayment {
+	return jsonPayment{
+		ID:			payment.ID,
+		AccountID:	payment.AccountID,
+		Amount:		payment.Amount,
+		Refunded:	payment.Refunded,
+		Category:	payment.Category,
+		Status:		payment.Status,
+		Kind:		payment.Kind,
+		LinkedID:	payment.LinkedID,
+		CreatedAt:	jsonTime(payment.CreatedAt),
+		UpdatedAt:	jsonTime(payment.UpdatedAt),
+	}
+}
+
+func (p jsonPayment) payment() *types.Payment {
+	payment := &types.Payment{
+		ID:			p.ID,
+		AccountID:	p.AccountID,
+		Amount:		p.Amount,
+		Refunded:	p.Refunded,
+		Category:	p.Category,
+		Status:		p.Status,
+		Kind:		p.Kind,
+		LinkedID:	p.LinkedID,
+	}
+	if p.CreatedAt != nil {
+		payment.CreatedAt = *p.CreatedAt
+	}
+	if p.UpdatedAt != nil {
+		payment.UpdatedAt = *p.UpdatedAt
+	}
+	return payment
+}
+
+// jsonTime leaves the zero time out of the document.
+func jsonTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func newJSONFavorite(favorite *types.Favorite) jsonFavorite {
+	return jsonFavorite{
+		ID:			favorite.ID,
+		AccountID:	favorite.AccountID,
+		Name:		favorite.Name,
+		Amount:		favorite.Amount,
+		Category:	favorite.Category,
+		Position:	favorite.Position,
+	}
+}
+
+func (f jsonFavorite) favorite() *types.Favorite {
+	return &types.Favorite{
+		ID:			f.ID,
+		AccountID:	f.AccountID,
+		Name:		f.Name,
+		Amount:		f.Amount,
+		Category:	f.Category,
+		Position:	f.Position,
+	}
+}
+
+// restoreAccount adds an imported account unless an account with the same id exists.
+func (s *Service) restoreAccount(account *types.Account) error {
+	err := checkAccount(account)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.accounts.FindByID(account.ID)
+	if err == ErrAccountNotFound {
+		return s.importAccount(account)
+	}
+	return err
+}
+
+// restorePayment adds an imported payment unless a payment with the same id exists,
+// the account of the payment must be in the wallet.
+func (s *Service) restorePayment(payment *types.Payment) error {
+	err := checkPayment(payment)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.payments.FindByID(payment.ID)
+	if err != ErrPaymentNotFound {
+		return err
+	}
+
+	err = s.checkOwner(payment.AccountID)
+	if err != nil {
+		return err
+	}
+	return s.payments.Save(payment)
+}
+
+// restoreFavorite adds an imported favorite unless a favorite with the same id exists,
+// the account of the favorite must be in the wallet.
+func (s *Service) restoreFavorite(favorite *types.Favorite) error {
+	if favorite.Amount <= 0 {
+		return &columnError{column: "amount", err: ErrAmountMustBePositive}
+	}
+	if checkCategory(favorite.Category) != nil {
+		return &columnError{column: "category", err: ErrInvalidCategory}
+	}
+	if strings.TrimSpace(favorite.Name) == "" || strings.ContainsAny(favorite.Name, ";\r\n") {
+		return &columnError{column: "name", err: ErrInvalidFavoriteName}
+	}
+
+	_, err := s.favorites.FindByID(favorite.ID)
+	if err != ErrFavoriteNotFound {
+		return err
+	}
+
+	err = s.checkOwner(favorite.AccountID)
+	if err != nil {
+		return err
+	}
+	return s.favorites.Save(favorite)
+}
+
+// checkOwner reports an imported record whose account isn't in the wallet.
+func (s *Service) checkOwner(accountID int64) error {
+	_, err := s.accounts.FindByID(accountID)
+	if err == ErrAccountNotFound {
+		return &columnError{column: "accountId", err: err}
+	}
+	return err
+}
+
+func checkAccount(account *types.Account) error {
+	switch account.Status {
+	case types.AccountStatusActive, types.AccountStatusFrozen, types.AccountStatusClosed:
+	default:
+		return &columnError{column: "status", err: fmt.Errorf("unknown account status %q", account.Status)}
+	}
+
+	if account.CreditLimit < 0 {
+		return &columnError{column: "creditLimit", err: ErrInvalidCreditLimit}
+	}
+	if account.Balance < -account.CreditLimit {
+		return &columnError{column: "balance", err: ErrNotEnoughBalance}
+	}
+	return nil
+}
+
+func knownPaymentStatus(status types.PaymentStatus) bool {
+	switch status {
+	case types.PaymentStatusOk, types.PaymentStatusFail, types.PaymentStatusInProgress, types.PaymentStatusRefunded:
+		return true
+	}
+	return false
+}
+
+func checkPayment(payment *types.Payment) error {
+	if checkCategory(payment.Category) != nil {
+		return &columnError{column: "category", err: ErrInvalidCategory}
+	}
+	if !knownPaymentStatus(payment.Status) {
+		return &columnError{column: "status", err: fmt.Errorf("unknown payment status %q", payment.Status)}
+	}
+
+	switch payment.Kind {
+	case types.PaymentKindRegular, types.PaymentKindTransferOut, types.PaymentKindTransferIn:
+	default:
+		return &columnError{column: "kind", err: fmt.Errorf("unknown payment kind %q", payment.Kind)}
+	}
+
+	if payment.Amount <= 0 {
+		return &columnError{column: "amount", err: ErrAmountMustBePositive}
+	}
+	if payment.Refunded < 0 || payment.Refunded > payment.Amount {
+		return &columnError{column: "refunded", err: ErrRefundExceedsAmount}
+	}
+	return nil
+}
+
+func exportJSON(s *Service, dir string) error {
+	accounts, err := s.accounts.All()
+	if err != nil {
+		return err
+	}
+	payments, err := s.payments.All()
+	if err != nil {
+		return err
+	}
+	favorites, err := s.favorites.All()
+	if err != nil {
+		return err
+	}
+
+	state := jsonState{
+		Accounts:	make([]jsonAccount, 0, len(accounts)),
+		Payments:	make([]jsonPayment, 0, len(payments)),
+		Favorites:	make([]jsonFavorite, 0, len(favorites)),
+	}
+	for _, account := range accounts {
+		state.Accounts = append(state.Accounts, newJSONAccount(account))
+	}
+	for _, payment := range payments {
+		state.Payments = append(state.Payments, newJSONPayment(payment))
+	}
+	for _, favorite := range favorites {
+		state.Favorites = append(state.Favorites, newJSONFavorite(favorite))
+	}
+
+	return writeJSON(dir + "/" + "wallet.json", state)
+}
+
+// writeJSON writes value as an indented JSON document.
+func writeJSON(path string, value interface{}) (err error) {
+	file, err := create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := file.Close(); cerr != nil {
+			if err == nil {
+				err = cerr
+			}
+		}
+	}()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// importJSON restores the records of wallet.json one by one, so an invalid record is reported
+// with its line and the others are still restored. A document that isn't valid JSON or isn't an object
+// of record arrays fails as a whole, an unknown element is reported and skipped.
+func importJSON(s *Service, dir string, report *ImportReport) error {
+	data, err := os.ReadFile(dir + "/" + "wallet.json")
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	restore := map[string]func(record []byte) error{
+		"accounts": func(record []byte) error {
+			var account jsonAccount
+			err := json.Unmarshal(record, &account)
+			if err != nil {
+				return err
+			}
+			return s.restoreAccount(account.account())
+		},
+		"payments": func(record []byte) error {
+			var payment jsonPayment
+			err := json.Unmarshal(record, &payment)
+			if err != nil {
+				return err
+			}
+			return s.restorePayment(payment.payment())
+		},
+		"favorites": func(record []byte) error {
+			var favorite jsonFavorite
+			err := json.Unmarshal(record, &favorite)
+			if err != nil {
+				return err
+			}
+			return s.restoreFavorite(favorite.favorite())
+		},
+	}
+
+	// accounts are restored before the payments and favorites that refer to them
+	lists := make(map[string][]json.RawMessage)
+	lines := make(map[string][]int)
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	open, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if open != json.Delim('{') {
+		return fmt.Errorf("%w: wallet.json is not an object", ErrMalformedRecord)
+	}
+	for decoder.More() {
+		line := jsonLine(data, decoder.InputOffset())
+		key, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		name, _ := key.(string)
+		if restore[name] == nil {
+			var skipped json.RawMessage
+			err = decoder.Decode(&skipped)
+			if err != nil {
+				return err
+			}
+			err = report.reject("wallet.json", line, fmt.Errorf("%w: unknown element %q", ErrMalformedRecord, name))
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		open, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		if open == nil {
+			continue
+		}
+		if open != json.Delim('[') {
+			return fmt.Errorf("%w: %s is not an array", ErrMalformedRecord, name)
+		}
+		for decoder.More() {
+			line := jsonLine(data, decoder.InputOffset())
+			var record json.RawMessage
+			err = decoder.Decode(&record)
+			if err != nil {
+				return err
+			}
+			lists[name] = append(lists[name], record)
+			lines[name] = append(lines[name], line)
+		}
+		_, err = decoder.Token()
+		if err != nil {
+			return err
+		}
+	}
+	_, err = decoder.Token()
+	if err != nil {
+		return err
+	}
+	_, err = decoder.Token()
+	if err != io.EOF {
+		return fmt.Errorf("%w: wallet.json has data after the object", ErrMalformedRecord)
+	}
+
+	for _, name := range []string{"accounts", "payments", "favorites"} {
+		for i, record := range lists[name] {
+			err = report.reject("wallet.json", lines[name][i], restore[name](record))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// jsonLine returns the 1-based line of the value that starts at offset, after separators.
+func jsonLine(data []byte, offset int64) int {
+	i := int(offset)
+	for i < len(data) && strings.ContainsRune(" \t\r\n,", rune(data[i])) {
+		i++
+	}
+	return 1 + bytes.Count(data[:i], []byte("\n"))
+}
+
+func exportJSONLines(s *Service, dir string) error {
+	accounts, err := s.accounts.All()
+	if err != nil {
+		return err
+	}
+	records := make([]interface{}, 0, len(accounts))
+	for _, account := range accounts {
+		records = append(records, newJSONAccount(account))
+	}
+	err = writeJSONLines(dir + "/" + "accounts.jsonl", records)
+	if err != nil {
+		return err
+	}
+
+	payments, err := s.payments.All()
+	if err != nil {
+		return err
+	}
+	records = make([]interface{}, 0, len(payments))
+	for _, payment := range payments {
+		records = append(records, newJSONPayment(payment))
+	}
+	err = writeJSONLines(dir + "/" + "payments.jsonl", records)
+	if err != nil {
+		return err
+	}
+
+	favorites, err := s.favorites.All()
+	if err != nil {
+		return err
+	}
+	records = make([]interface{}, 0, len(favorites))
+	for _, favorite := range favorites {
+		records = append(records, newJSONFavorite(favorite))
+	}
+	return writeJSONLines(dir + "/" + "favorites.jsonl", records)
+}
+
+// writeJSONLines writes one record per line.
+func writeJSONLines(path string, records []interface{}) error {
+	return writeRecords(path, len(records), func(w io.Writer) error {
+		writer := bufio.NewWriter(w)
+		encoder := json.NewEncoder(writer)
+		for _, record := range records {
+			err := encoder.Encode(record)
+			if err != nil {
+				return err
+			}
+		}
+		return writer.Flush()
+	})
+}
+
+func importJSONLines(s *Service, dir string, report *ImportReport) error {
+	err := readJSONLines(dir + "/" + "accounts.jsonl", report, func(line []byte) error {
+		var account jsonAccount
+		err := json.Unmarshal(line, &account)
+		if err != nil {
+			return err
+		}
+		return s.restoreAccount(account.account())
+	})
+	if err != nil {
+		return err
+	}
+
+	err = readJSONLines(dir + "/" + "payments.jsonl", report, func(line []byte) error {
+		var payment jsonPayment
+		err := json.Unmarshal(line, &payment)
+		if err != nil {
+			return err
+		}
+		return s.restorePayment(payment.payment())
+	})
+	if err != nil {
+		return err
+	}
+
+	return readJSONLines(dir + "/" + "favorites.jsonl", report, func(line []byte) error {
+		var favorite jsonFavorite
+		err := json.Unmarshal(line, &favorite)
+		if err != nil {
+			return err
+		}
+		return s.restoreFavorite(favorite.favorite())
+	})
+}
+
+// readJSONLines calls read for every non-empty line of the file, a missing file has no lines.
+// Lines read finds invalid are reported.
+func readJSONLines(path string, report *ImportReport, read func(line []byte) error) (err error) {
+	src, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := src.Close(); cerr != nil {
+			if err == nil {
+				err = cerr
+			}
+		}
+	}()
+
+	name := filepath.Base(path)
+	reader := bufio.NewReader(src)
+	for number := 1; ; number++ {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		if trimmed := strings.TrimSpace(line); trimmed != "" {
+			rerr := report.reject(name, number, read([]byte(trimmed)))
+			if rerr != nil {
+				return rerr
+			}
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package wallet
+
+import (
+	"errors"
+	"github.com/aminjonshermatov/wallet/pkg/types"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// dumpTestAccount has payment categories holding the separators and quotes of the formats
+// that may hold them, categories can't hold the ';' and line breaks of the dump files.
+var dumpTestAccount = testAccount{
+	phone:		"+992000000001",
+	balance:	10_000,
+	payments:	[]struct{
+		amount		types.Money
+		category	types.PaymentCategory
+	}{
+		{amount: 1_000, category: "food, \"cafe\" {lunch}"},
+		{amount: 500, category: "auto|fuel"},
+	},
+}
+
+// newDumpTestService adds dumpTestAccount with a rejected payment and a favorite
+// whose name holds separators too, and an empty account.
+func newDumpTestService(t *testing.T) *testService {
+	t.Helper()
+	s := newTestService()
+	_, payments, err := s.addAccount(dumpTestAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.RegisterAccount("+992000000002")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.Reject(payments[1].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.FavoritePayment(payments[0].ID, "osh, \"big\" plov")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+// assertSameState compares accounts, payments, favorites and payment histories of two services,
+// timestamps are compared as instants.
+func assertSameState(t *testing.T, got *Service, want *Service) {
+	t.Helper()
+
+	gotAccounts, _ := got.accounts.All()
+	wantAccounts, _ := want.accounts.All()
+	if !reflect.DeepEqual(gotAccounts, wantAccounts) {
+		t.Errorf("accounts: got %v, want %v", gotAccounts, wantAccounts)
+	}
+
+	gotPayments, _ := got.payments.All()
+	wantPayments, _ := want.payments.All()
+	if len(gotPayments) != len(wantPayments) {
+		t.Fatalf("payments: got %v, want %v", gotPayments, wantPayments)
+	}
+	for i := range wantPayments {
+		g, w := *gotPayments[i], *wantPayments[i]
+		if !g.CreatedAt.Equal(w.CreatedAt) || !g.UpdatedAt.Equal(w.UpdatedAt) {
+			t.Errorf("payment %s: got timestamps %v %v, want %v %v", w.ID, g.CreatedAt, g.UpdatedAt, w.CreatedAt, w.UpdatedAt)
+		}
+		g.CreatedAt, g.UpdatedAt = w.CreatedAt, w.UpdatedAt
+		if !reflect.DeepEqual(g, w) {
+			t.Errorf("payment: got %v, want %v", g, w)
+		}
+	}
+
+	gotFavorites, _ := got.favorites.All()
+	wantFavorites, _ := want.favorites.All()
+	if !reflect.DeepEqual(gotFavorites, wantFavorites) {
+		t.Errorf("favorites: got %v, want %v", gotFavorites, wantFavorites)
+	}
+
+	if len(got.transitions) != len(want.transitions) {
+		t.Fatalf("transitions: got %v, want %v", got.transitions, want.transitions)
+	}
+	for paymentID, wantTransitions := range want.transitions {
+		gotTransitions := got.transitions[paymentID]
+		if len(gotTransitions) != len(wantTransitions) {
+			t.Errorf("transitions of %s: got %v, want %v", paymentID, gotTransitions, wantTransitions)
+			continue
+		}
+		for i, w := range wantTransitions {
+			g := gotTransitions[i]
+			if g.From != w.From || g.To != w.To || !g.At.Equal(w.At) {
+				t.Errorf("transitions of %s: got %v, want %v", paymentID, gotTransitions, wantTransitions)
+				break
+			}
+		}
+	}
+}
+
+func TestService_Export_formats(t *testing.T) {
+	tests := []struct{
+		format	Format
+		files	[]string
+	}{
+		{FormatDump, []string{"accounts.dump", "payments.dump", "favorites.dump", "transitions.dump"}},
+		{FormatJSON, []string{"wallet.json"}},
+		{FormatJSONLines, []string{"accounts.jsonl", "payments.jsonl", "favorites.jsonl"}},
+	}
+
+	for _, tt := range tests {
+		s := newDumpTestService(t)
+		dir := t.TempDir()
+
+		err := s.Export(dir, WithFormat(tt.format))
+		if err != nil {
+			t.Fatalf("Export(%s): %v", tt.format, err)
+		}
+		for _, file := range tt.files {
+			_, err = os.Stat(filepath.Join(currentGeneration(t, dir), file))
+			if err != nil {
+				t.Errorf("Export(%s): %v", tt.format, err)
+			}
+		}
+
+		imported := NewService()
+		err = imported.Import(dir, WithFormat(tt.format))
+		if err != nil {
+			t.Fatalf("Import(%s): %v", tt.format, err)
+		}
+		assertSameState(t, imported, s.Service)
+
+		_, err = imported.Pay(1, 1, "food")
+		if err != nil {
+			t.Errorf("Import(%s): imported wallet must accept payments, returned = %v", tt.format, err)
+		}
+	}
+}
+
+func TestService_dumpSeparators(t *testing.T) {
+	s := newTestService()
+	account, _, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, category := range []types.PaymentCategory{"food;drinks", "food\ndrinks", "food\r"} {
+		_, err = s.Pay(account.ID, 10, category)
+		if err != ErrInvalidCategory {
+			t.Errorf("Pay(%q): must return ErrInvalidCategory, returned = %v", category, err)
+		}
+		err = s.SetSpendingLimit(account.ID, LimitPeriodDaily, category, 10)
+		if err != ErrInvalidCategory {
+			t.Errorf("SetSpendingLimit(%q): must return ErrInvalidCategory, returned = %v", category, err)
+		}
+	}
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"accounts.jsonl":	`{"id": 1, "phone": "+992000000001", "balance": 100}`,
+		"payments.jsonl":	`{"id": "p1", "accountId": 1, "amount": 10, "category": "food;drinks", "status": "OK"}`,
+		"favorites.jsonl":	`{"id": "f1", "accountId": 1, "name": "osh\nplov", "amount": 10, "category": "food"}`,
+	}
+	for name, content := range files {
+		err = os.WriteFile(filepath.Join(dir, name), []byte(content + "\n"), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report := &ImportReport{}
+	err = NewService().Import(dir, WithFormat(FormatJSONLines), WithReport(report))
+	if !errors.Is(err, ErrInvalidRecords) || !errors.Is(err, ErrInvalidCategory) || !errors.Is(err, ErrInvalidFavoriteName) {
+		t.Errorf("Import(): must report the separators, returned = %v", err)
+	}
+}
+
+func TestService_Import_jsonKeepsExisting(t *testing.T) {
+	s := newDumpTestService(t)
+	dir := t.TempDir()
+	err := s.Export(dir, WithFormat(FormatJSONLines))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.Import(dir, WithFormat(FormatJSONLines))
+	if err != nil {
+		t.Fatal(err)
+	}
+	payments, _ := s.payments.All()
+	if len(payments) != 2 {
+		t.Errorf("Import(): existing records must be kept, got %d payments", len(payments))
+	}
+}
+
+func TestService_Import_jsonMissingFiles(t *testing.T) {
+	for _, format := range []Format{FormatJSON, FormatJSONLines} {
+		s := NewService()
+		err := s.Import(t.TempDir(), WithFormat(format))
+		if err != nil {
+			t.Errorf("Import(%s): missing files must be an empty wallet, returned = %v", format, err)
+		}
+	}
+}
+
+func TestService_Import_jsonMalformed(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "payments.jsonl"), []byte("{\"id\":\"1\"}\n{\"id\":"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = NewService().Import(dir, WithFormat(FormatJSONLines))
+	if err == nil {
+		t.Error("Import(): must fail on a malformed line")
+	}
+}
+
+func TestService_Export_unknownFormat(t *testing.T) {
+	s := NewService()
+	err := s.Export(t.TempDir(), WithFormat("xml"))
+	if err != ErrUnknownFormat {
+		t.Errorf("Export(): must return ErrUnknownFormat, returned = %v", err)
+	}
+	err = s.Import(t.TempDir(), WithFormat("xml"))
+	if err != ErrUnknownFormat {
+		t.Errorf("Import(): must return ErrUnknownFormat, returned = %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package wallet
+
+import (
+	"github.com/aminjonshermatov/wallet/pkg/types"
+	"sort"
+	"time"
+)
+
+// PaymentsBetween returns a filter for FilterPaymentsByFn that keeps payments created in [from, to).
+// A zero from or to leaves that side of the range open.
+func PaymentsBetween(from time.Time, to time.Time) func(payment types.Payment) bool {
+	return func(payment types.Payment) bool {
+		if !from.IsZero() && payment.CreatedAt.Before(from) {
+			return false
+		}
+		if !to.IsZero() && !payment.CreatedAt.Before(to) {
+			return false
+		}
+		return true
+	}
+}
+
+// SortPaymentsByCreatedAt orders payments from the oldest to the newest.
+func SortPaymentsByCreatedAt(payments []types.Payment) {
+	sort.SliceStable(payments, func(i, j int) bool {
+		return payments[i].CreatedAt.Before(payments[j].CreatedAt)
+	})
+}
+
+// ExportAccountHistoryBetween returns the account payments created in [from, to), oldest first.
+func (s *Service) ExportAccountHistoryBetween(accountID int64, from time.Time, to time.Time) ([]types.Payment, error) {
+	s.rlock()
+	defer s.mu.RUnlock()
+
+	_, err := s.findAccountByID(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	payments, err := s.payments.FindByAccountID(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	between := PaymentsBetween(from, to)
+	res := make([]types.Payment, 0)
+	for _, payment := range payments {
+		if between(*payment) {
+			res = append(res, *payment)
+		}
+	}
+
+	SortPaymentsByCreatedAt(res)
+	return res, nil
+}
+
+// FilterPaymentsBetween is FilterPayments limited to payments created in [from, to), oldest first.
+func (s *Service) FilterPaymentsBetween(accountID int64, from time.Time, to time.Time, goroutines int) ([]types.Payment, error) {
+	payments, err := s.FilterPayments(accountID, goroutines)
+	if err != nil {
+		return nil, err
+	}
+
+	between := PaymentsBetween(from, to)
+	res := make([]types.Payment, 0)
+	for _, payment := range payments {
+		if between(payment) {
+			res = append(res, payment)
+		}
+	}
+
+	SortPaymentsByCreatedAt(res)
+	return res, nil
+}
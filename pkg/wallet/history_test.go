@@ -0,0 +1,200 @@
+package wallet
+
+import (
+	"github.com/aminjonshermatov/wallet/pkg/types"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testClock struct {
+	mu	sync.Mutex
+	now	time.Time
+}
+
+func newTestClock() *testClock {
+	return &testClock{now: time.Date(2021, time.March, 1, 10, 0, 0, 0, time.UTC)}
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func (c *testClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+func TestService_Pay_timestamps(t *testing.T) {
+	clock := newTestClock()
+	s := &testService{Service: NewService(WithClock(clock.Now))}
+	_, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payment := payments[0]
+	if !payment.CreatedAt.Equal(clock.Now()) || !payment.UpdatedAt.Equal(clock.Now()) {
+		t.Errorf("Pay(): invalid timestamps, payment = %v", payment)
+	}
+
+	clock.Advance(time.Hour)
+	err = s.Confirm(payment.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.FindPaymentByID(payment.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.CreatedAt.Equal(payment.CreatedAt) || !got.UpdatedAt.Equal(clock.Now()) {
+		t.Errorf("Confirm(): invalid timestamps, payment = %v", got)
+	}
+}
+
+func TestService_timestamps_exportImport(t *testing.T) {
+	clock := newTestClock()
+	s := &testService{Service: NewService(WithClock(clock.Now))}
+	account, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(time.Minute)
+	err = s.Reject(payments[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	err = s.Export(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	imported := NewService()
+	err = imported.Import(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := s.ExportAccountHistory(account.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := imported.ExportAccountHistory(account.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Import(): got %v, want %v", got, want)
+	}
+	for i := range got {
+		if !got[i].CreatedAt.Equal(want[i].CreatedAt) || !got[i].UpdatedAt.Equal(want[i].UpdatedAt) {
+			t.Errorf("Import(): timestamps don't match, got %v, want %v", got[i], want[i])
+		}
+	}
+}
+
+func TestService_ExportAccountHistoryBetween(t *testing.T) {
+	clock := newTestClock()
+	s := &testService{Service: NewService(WithClock(clock.Now))}
+
+	account, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Deposit(account.ID, 1_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := s.RegisterAccount("+992000000002")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Deposit(other.ID, 1_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := clock.Now()
+	ids := make([]string, 0)
+	for i := 0; i < 5; i++ {
+		payment, err := s.Pay(account.ID, types.Money(i + 1), "foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = s.Pay(other.ID, 1, "foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, payment.ID)
+		clock.Advance(24 * time.Hour)
+	}
+
+	tests := []struct{
+		name	string
+		from	time.Time
+		to		time.Time
+		want	[]string
+	}{
+		{"all", time.Time{}, time.Time{}, ids},
+		{"from", start.Add(48 * time.Hour), time.Time{}, ids[2:]},
+		{"to", time.Time{}, start.Add(48 * time.Hour), ids[:2]},
+		{"range", start.Add(24 * time.Hour), start.Add(72 * time.Hour), ids[1:3]},
+		{"empty", start.Add(-time.Hour), start, []string{}},
+	}
+
+	for _, tt := range tests {
+		history, err := s.ExportAccountHistoryBetween(account.ID, tt.from, tt.to)
+		if err != nil {
+			t.Fatal(err)
+		}
+		filtered, err := s.FilterPaymentsBetween(account.ID, tt.from, tt.to, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, got := range [][]types.Payment{history, filtered} {
+			gotIDs := make([]string, 0)
+			for _, payment := range got {
+				gotIDs = append(gotIDs, payment.ID)
+			}
+			if !reflect.DeepEqual(gotIDs, tt.want) {
+				t.Errorf("%s: got %v, want %v", tt.name, gotIDs, tt.want)
+			}
+		}
+	}
+
+	_, err = s.ExportAccountHistoryBetween(100, time.Time{}, time.Time{})
+	if err != ErrAccountNotFound {
+		t.Errorf("ExportAccountHistoryBetween(): must return ErrAccountNotFound, returned = %v", err)
+	}
+}
+
+func TestSortPaymentsByCreatedAt(t *testing.T) {
+	now := time.Now()
+	payments := []types.Payment{
+		{ID: "c", CreatedAt: now.Add(2 * time.Second)},
+		{ID: "a", CreatedAt: now},
+		{ID: "b", CreatedAt: now.Add(time.Second)},
+	}
+
+	SortPaymentsByCreatedAt(payments)
+
+	for i, id := range []string{"a", "b", "c"} {
+		if payments[i].ID != id {
+			t.Errorf("SortPaymentsByCreatedAt(): got %v", payments)
+		}
+	}
+}
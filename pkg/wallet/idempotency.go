@@ -0,0 +1,208 @@
+package wallet
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/aminjonshermatov/wallet/pkg/types"
+	"sort"
+	"strings"
+	"time"
+)
+
+const DefaultIdempotencyWindow = 24 * time.Hour
+
+// minIdempotencySweep is the number of remembered keys below which expired ones are not swept.
+const minIdempotencySweep = 64
+
+var ErrInvalidIdempotencyKey = errors.New("idempotency key must be non empty and must not contain ';' or new lines")
+var ErrIdempotencyKeyReused = errors.New("idempotency key already used with different parameters")
+
+// idempotencyRecord remembers the result of an operation made with a key.
+type idempotencyRecord struct {
+	key			string
+	request		string
+	paymentID	string
+	createdAt	time.Time
+}
+
+// WithIdempotencyWindow sets how long idempotency keys are remembered.
+func WithIdempotencyWindow(window time.Duration) Option {
+	return func(s *Service) {
+		s.idempotencyWindow = window
+	}
+}
+
+// PayWithKey is Pay that returns the original payment when called again with the same key.
+func (s *Service) PayWithKey(key string, accountID int64, amount types.Money, category types.PaymentCategory) (*types.Payment, error) {
+	request := fmt.Sprintf("PAY;%d;%d;%s", accountID, amount, category)
+	return s.payWithKey(walPayWithKey, walArgs{Key: key, AccountID: accountID, Amount: amount, Category: category}, request, func() (*types.Payment, error) {
+		return s.pay(accountID, amount, category)
+	})
+}
+
+// RepeatWithKey is Repeat that returns the original payment when called again with the same key.
+func (s *Service) RepeatWithKey(key string, paymentID string) (*types.Payment, error) {
+	request := fmt.Sprintf("REPEAT;%s", paymentID)
+	return s.payWithKey(walRepeatWithKey, walArgs{Key: key, PaymentID: paymentID}, request, func() (*types.Payment, error) {
+		return s.repeat(paymentID)
+	})
+}
+
+// PayFromFavoriteWithKey is PayFromFavorite that returns the original payment when called again with the same key.
+func (s *Service) PayFromFavoriteWithKey(key string, favoriteID string) (*types.Payment, error) {
+	request := fmt.Sprintf("FAVORITE;%s", favoriteID)
+	return s.payWithKey(walPayFromFavoriteWithKey, walArgs{Key: key, FavoriteID: favoriteID}, request, func() (*types.Payment, error) {
+		return s.payFromFavorite(favoriteID)
+	})
+}
+
+// DepositWithKey is Deposit that does nothing when called again with the same key.
+func (s *Service) DepositWithKey(key string, accountID int64, amount types.Money) error {
+	request := fmt.Sprintf("DEPOSIT;%d;%d", accountID, amount)
+	_, err := s.payWithKey(walDepositWithKey, walArgs{Key: key, AccountID: accountID, Amount: amount}, request, func() (*types.Payment, error) {
+		return nil, s.deposit(accountID, amount)
+	})
+	return err
+}
+
+// payWithKey runs operation logged as op unless the key of args was used already.
+func (s *Service) payWithKey(op walOperation, args walArgs, request string, operation func() (*types.Payment, error)) (*types.Payment, error) {
+	key := args.Key
+	if key == "" || strings.ContainsAny(key, ";\n") {
+		return nil, ErrInvalidIdempotencyKey
+	}
+
+	s.lock()
+	defer s.mu.Unlock()
+
+	if err := s.begin(op, args); err != nil {
+		return nil, err
+	}
+	defer s.end()
+
+	request = fingerprint(request)
+	record, ok := s.idempotency[key]
+	if ok && s.expired(record) {
+		delete(s.idempotency, key)
+		ok = false
+	}
+
+	if ok {
+		if record.request != request {
+			return nil, ErrIdempotencyKeyReused
+		}
+		if record.paymentID == "" {
+			return nil, nil
+		}
+
+		payment, err := s.findPaymentByID(record.paymentID)
+		if err != nil {
+			return nil, err
+		}
+		return copyPayment(payment), nil
+	}
+
+	payment, err := operation()
+	if err != nil {
+		return nil, err
+	}
+
+	record = &idempotencyRecord{
+		key:		key,
+		request:	request,
+		createdAt:	s.timestamp(),
+	}
+	if payment != nil {
+		record.paymentID = payment.ID
+		payment = copyPayment(payment)
+	}
+	s.pruneIdempotency()
+	s.idempotency[key] = record
+
+	return payment, nil
+}
+
+// pruneIdempotency drops the expired records once their number has doubled since the last sweep,
+// so a sweep costs amortised constant time per remembered key.
+func (s *Service) pruneIdempotency() {
+	if len(s.idempotency) < s.idempotencySweep {
+		return
+	}
+
+	for key, record := range s.idempotency {
+		if s.expired(record) {
+			delete(s.idempotency, key)
+		}
+	}
+	s.idempotencySweep = 2 * len(s.idempotency)
+	if s.idempotencySweep < minIdempotencySweep {
+		s.idempotencySweep = minIdempotencySweep
+	}
+}
+
+func (s *Service) expired(record *idempotencyRecord) bool {
+	return !s.timestamp().Before(record.createdAt.Add(s.idempotencyWindow))
+}
+
+func fingerprint(request string) string {
+	sum := sha256.Sum256([]byte(request))
+	return hex.EncodeToString(sum[:])
+}
+
+func encodeIdempotencyRecord(record *idempotencyRecord) string {
+	return record.key + ";" +
+		record.request + ";" +
+		record.paymentID + ";" +
+		encodeTime(record.createdAt)
+}
+
+func decodeIdempotencyRecord(line string) (*idempotencyRecord, error) {
+	col := strings.Split(line, ";")
+	if len(col) < 4 {
+		return nil, ErrMalformedRecord
+	}
+
+	createdAt, err := decodeTime(col[3])
+	if err != nil {
+		return nil, &columnError{column: "createdAt", err: err}
+	}
+
+	return &idempotencyRecord{
+		key:		col[0],
+		request:	col[1],
+		paymentID:	col[2],
+		createdAt:	createdAt,
+	}, nil
+}
+
+func exportIdempotency(s *Service, dir string) error {
+	keys := make([]string, 0, len(s.idempotency))
+	for key, record := range s.idempotency {
+		if !s.expired(record) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	lines := make([]string, 0, len(keys))
+	for _, key := range keys {
+		lines = append(lines, encodeIdempotencyRecord(s.idempotency[key]))
+	}
+	return writeDumpFile(dir, "idempotency.dump", lines)
+}
+
+func importIdempotency(s *Service, dir string, report *ImportReport) error {
+	return readDump(dir, "idempotency.dump", report, func(line string) error {
+		record, err := decodeIdempotencyRecord(line)
+		if err != nil {
+			return err
+		}
+
+		if _, ok := s.idempotency[record.key]; !ok && !s.expired(record) {
+			s.idempotency[record.key] = record
+		}
+		return nil
+	})
+}
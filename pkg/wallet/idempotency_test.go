@@ -0,0 +1,256 @@
+package wallet
+
+import (
+	"fmt"
+	"github.com/aminjonshermatov/wallet/pkg/types"
+	"testing"
+	"time"
+)
+
+func TestService_PayWithKey_retry(t *testing.T) {
+	s := newTestService()
+	account, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Deposit(account.ID, 1_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := s.PayWithKey("key-1", account.ID, 100, "food")
+	if err != nil {
+		t.Fatalf("PayWithKey(): error = %v", err)
+	}
+
+	second, err := s.PayWithKey("key-1", account.ID, 100, "food")
+	if err != nil {
+		t.Fatalf("PayWithKey(): retry error = %v", err)
+	}
+	if *first != *second {
+		t.Errorf("PayWithKey(): retry must return the original payment, got %v, want %v", second, first)
+	}
+
+	s.checkBalance(t, account.ID, 900)
+
+	_, err = s.PayWithKey("key-1", account.ID, 200, "food")
+	if err != ErrIdempotencyKeyReused {
+		t.Errorf("PayWithKey(): must return ErrIdempotencyKeyReused, returned = %v", err)
+	}
+
+	_, err = s.PayWithKey("key-2", account.ID, 100, "food")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.checkBalance(t, account.ID, 800)
+}
+
+func TestService_PayWithKey_failedIsNotRemembered(t *testing.T) {
+	s := newTestService()
+	account, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.PayWithKey("key-1", account.ID, 100, "food")
+	if err != ErrNotEnoughBalance {
+		t.Fatalf("PayWithKey(): must return ErrNotEnoughBalance, returned = %v", err)
+	}
+
+	err = s.Deposit(account.ID, 1_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.PayWithKey("key-1", account.ID, 100, "food")
+	if err != nil {
+		t.Fatalf("PayWithKey(): error = %v", err)
+	}
+	s.checkBalance(t, account.ID, 900)
+}
+
+func TestService_PayWithKey_invalidKey(t *testing.T) {
+	s := newTestService()
+
+	for _, key := range []string{"", "a;b", "a\nb"} {
+		_, err := s.PayWithKey(key, 1, 100, "food")
+		if err != ErrInvalidIdempotencyKey {
+			t.Errorf("PayWithKey(%q): must return ErrInvalidIdempotencyKey, returned = %v", key, err)
+		}
+	}
+}
+
+func TestService_DepositWithKey(t *testing.T) {
+	s := newTestService()
+	account, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		err = s.DepositWithKey("deposit-1", account.ID, 500)
+		if err != nil {
+			t.Fatalf("DepositWithKey(): error = %v", err)
+		}
+	}
+	s.checkBalance(t, account.ID, 500)
+
+	err = s.DepositWithKey("deposit-1", account.ID, 600)
+	if err != ErrIdempotencyKeyReused {
+		t.Errorf("DepositWithKey(): must return ErrIdempotencyKeyReused, returned = %v", err)
+	}
+}
+
+func TestService_RepeatAndPayFromFavoriteWithKey(t *testing.T) {
+	s := newTestService()
+	account, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	favorite, err := s.FavoritePayment(payments[0].ID, "osh")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repeated, err := s.RepeatWithKey("repeat-1", payments[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := s.RepeatWithKey("repeat-1", payments[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repeated.ID != again.ID {
+		t.Errorf("RepeatWithKey(): retry must return the original payment")
+	}
+
+	fromFavorite, err := s.PayFromFavoriteWithKey("favorite-1", favorite.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err = s.PayFromFavoriteWithKey("favorite-1", favorite.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fromFavorite.ID != again.ID {
+		t.Errorf("PayFromFavoriteWithKey(): retry must return the original payment")
+	}
+
+	_, err = s.PayFromFavoriteWithKey("repeat-1", favorite.ID)
+	if err != ErrIdempotencyKeyReused {
+		t.Errorf("PayFromFavoriteWithKey(): must return ErrIdempotencyKeyReused, returned = %v", err)
+	}
+
+	s.checkBalance(t, account.ID, defaultTestAccount.balance - 3 * payments[0].Amount)
+}
+
+func TestService_PayWithKey_expires(t *testing.T) {
+	clock := newTestClock()
+	s := &testService{Service: NewService(WithClock(clock.Now), WithIdempotencyWindow(time.Hour))}
+	account, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Deposit(account.ID, 1_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := s.PayWithKey("key-1", account.ID, 100, "food")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clock.Advance(59 * time.Minute)
+	second, err := s.PayWithKey("key-1", account.ID, 100, "food")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.ID != second.ID {
+		t.Errorf("PayWithKey(): key must not expire inside the window")
+	}
+
+	clock.Advance(time.Minute)
+	third, err := s.PayWithKey("key-1", account.ID, 100, "food")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.ID == third.ID {
+		t.Errorf("PayWithKey(): key must expire after the window")
+	}
+	s.checkBalance(t, account.ID, 800)
+}
+
+func TestService_DepositWithKey_prunesExpired(t *testing.T) {
+	clock := newTestClock()
+	s := NewService(WithClock(clock.Now), WithIdempotencyWindow(time.Hour))
+	account, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 100; i++ {
+		err = s.DepositWithKey(fmt.Sprintf("old-%d", i), account.ID, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	clock.Advance(time.Hour)
+	for i := 0; i < 50; i++ {
+		err = s.DepositWithKey(fmt.Sprintf("new-%d", i), account.ID, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(s.idempotency) > 50 {
+		t.Errorf("DepositWithKey(): expired keys must be pruned, got %d keys", len(s.idempotency))
+	}
+	_, ok := s.idempotency["new-49"]
+	if !ok {
+		t.Errorf("DepositWithKey(): keys inside the window must be kept")
+	}
+}
+
+func TestService_PayWithKey_exportImport(t *testing.T) {
+	clock := newTestClock()
+	s := &testService{Service: NewService(WithClock(clock.Now))}
+	account, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Deposit(account.ID, 1_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := s.PayWithKey("key-1", account.ID, 100, "food")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	err = s.Export(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	imported := &testService{Service: NewService(WithClock(clock.Now))}
+	err = imported.Import(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := imported.PayWithKey("key-1", account.ID, 100, "food")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.ID != second.ID {
+		t.Errorf("PayWithKey(): key must survive Export/Import, got %v, want %v", second.ID, first.ID)
+	}
+	imported.checkBalance(t, account.ID, 900)
+
+	_, err = imported.PayWithKey("key-1", account.ID, 100, types.PaymentCategory("auto"))
+	if err != ErrIdempotencyKeyReused {
+		t.Errorf("PayWithKey(): must return ErrIdempotencyKeyReused, returned = %v", err)
+	}
+}
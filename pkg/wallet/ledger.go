@@ -0,0 +1,197 @@
+package wallet
+
+import (
+	"errors"
+	"github.com/aminjonshermatov/wallet/pkg/types"
+	"strconv"
+)
+
+var ErrUnbalancedEntry = errors.New("journal entry is not balanced")
+
+// LedgerAccount names a side of a journal entry: a wallet account or an external one.
+type LedgerAccount string
+
+const (
+	LedgerDeposits	LedgerAccount = "external:deposits"
+	LedgerPayments	LedgerAccount = "external:payments"
+	LedgerOpening	LedgerAccount = "external:opening"
+	LedgerPayouts	LedgerAccount = "external:payouts"
+)
+
+func WalletLedgerAccount(accountID int64) LedgerAccount {
+	return LedgerAccount("wallet:" + strconv.FormatInt(accountID, 10))
+}
+
+type LedgerOperation string
+
+const (
+	LedgerOperationDeposit	LedgerOperation = "DEPOSIT"
+	LedgerOperationPay		LedgerOperation = "PAY"
+	LedgerOperationReject	LedgerOperation = "REJECT"
+	LedgerOperationRefund	LedgerOperation = "REFUND"
+	LedgerOperationTransfer	LedgerOperation = "TRANSFER"
+	LedgerOperationOpening	LedgerOperation = "OPENING"
+	LedgerOperationPayout	LedgerOperation = "PAYOUT"
+)
+
+// JournalEntry moves Amount from the Debit account to the Credit account,
+// so every entry is balanced by construction.
+type JournalEntry struct {
+	ID			int64
+	Operation	LedgerOperation
+	Debit		LedgerAccount
+	Credit		LedgerAccount
+	Amount		types.Money
+	PaymentID	string
+}
+
+// JournalRepository is an append-only store of journal entries.
+type JournalRepository interface {
+	Append(entry JournalEntry) error
+	All() ([]JournalEntry, error)
+}
+
+type MemoryJournalRepository struct {
+	entries	[]JournalEntry
+}
+
+func NewMemoryJournalRepository() *MemoryJournalRepository {
+	return &MemoryJournalRepository{}
+}
+
+func (r *MemoryJournalRepository) Append(entry JournalEntry) error {
+	r.entries = append(r.entries, entry)
+	return nil
+}
+
+func (r *MemoryJournalRepository) All() ([]JournalEntry, error) {
+	return r.entries[:len(r.entries):len(r.entries)], nil
+}
+
+func WithJournalRepository(journal JournalRepository) Option {
+	return func(s *Service) {
+		s.journal = journal
+	}
+}
+
+// Discrepancy is an account whose stored balance differs from its journal.
+type Discrepancy struct {
+	AccountID		int64
+	Balance			types.Money
+	JournalBalance	types.Money
+}
+
+func (s *Service) post(operation LedgerOperation, debit LedgerAccount, credit LedgerAccount, amount types.Money, paymentID string) error {
+	if amount <= 0 || debit == credit {
+		return ErrUnbalancedEntry
+	}
+
+	entries, err := s.journal.All()
+	if err != nil {
+		return err
+	}
+
+	return s.journal.Append(JournalEntry{
+		ID:			int64(len(entries) + 1),
+		Operation:	operation,
+		Debit:		debit,
+		Credit:		credit,
+		Amount:		amount,
+		PaymentID:	paymentID,
+	})
+}
+
+// openAccount stores an account that comes with a balance, e.g. from a dump,
+// and writes the opening entry for it.
+func (s *Service) openAccount(account *types.Account) error {
+	var err error
+	switch {
+	case account.Balance > 0:
+		err = s.post(LedgerOperationOpening, LedgerOpening, WalletLedgerAccount(account.ID), account.Balance, "")
+	case account.Balance < 0:
+		err = s.post(LedgerOperationOpening, WalletLedgerAccount(account.ID), LedgerOpening, -account.Balance, "")
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.accounts.Save(account)
+}
+
+// Journal returns all journal entries in the order they were written.
+func (s *Service) Journal() ([]JournalEntry, error) {
+	s.rlock()
+	defer s.mu.RUnlock()
+
+	entries, err := s.journal.All()
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]JournalEntry, len(entries))
+	copy(res, entries)
+	return res, nil
+}
+
+// JournalBalance derives the balance of the account from the journal.
+func (s *Service) JournalBalance(accountID int64) (types.Money, error) {
+	s.rlock()
+	defer s.mu.RUnlock()
+
+	_, err := s.findAccountByID(accountID)
+	if err != nil {
+		return 0, err
+	}
+
+	balances, err := s.journalBalances()
+	if err != nil {
+		return 0, err
+	}
+
+	return balances[WalletLedgerAccount(accountID)], nil
+}
+
+// Reconcile returns every account whose stored balance disagrees with its journal.
+func (s *Service) Reconcile() ([]Discrepancy, error) {
+	s.rlock()
+	defer s.mu.RUnlock()
+
+	balances, err := s.journalBalances()
+	if err != nil {
+		return nil, err
+	}
+
+	accounts, err := s.accounts.All()
+	if err != nil {
+		return nil, err
+	}
+
+	discrepancies := make([]Discrepancy, 0)
+	for _, account := range accounts {
+		journalBalance := balances[WalletLedgerAccount(account.ID)]
+		if account.Balance != journalBalance {
+			discrepancies = append(discrepancies, Discrepancy{
+				AccountID:		account.ID,
+				Balance:		account.Balance,
+				JournalBalance:	journalBalance,
+			})
+		}
+	}
+
+	return discrepancies, nil
+}
+
+func (s *Service) journalBalances() (map[LedgerAccount]types.Money, error) {
+	entries, err := s.journal.All()
+	if err != nil {
+		return nil, err
+	}
+
+	balances := make(map[LedgerAccount]types.Money)
+	for _, entry := range entries {
+		balances[entry.Debit] -= entry.Amount
+		balances[entry.Credit] += entry.Amount
+	}
+
+	return balances, nil
+}
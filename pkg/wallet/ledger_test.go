@@ -0,0 +1,151 @@
+package wallet
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestService_Journal_balancedEntries(t *testing.T) {
+	s := newTestService()
+	from, to := s.addTransferAccounts(t)
+
+	payment, err := s.Pay(from.ID, 100, "food")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.Repeat(payment.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Reject(payment.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transfer, err := s.Transfer(from.ID, to.ID, 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Reject(transfer.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.Transfer(from.ID, to.ID, 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := s.Journal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	operations := make([]LedgerOperation, 0)
+	for _, entry := range entries {
+		operations = append(operations, entry.Operation)
+		if entry.Amount <= 0 || entry.Debit == entry.Credit {
+			t.Errorf("unbalanced entry %v", entry)
+		}
+	}
+	want := []LedgerOperation{
+		LedgerOperationDeposit,
+		LedgerOperationPay,
+		LedgerOperationPay,
+		LedgerOperationReject,
+		LedgerOperationTransfer,
+		LedgerOperationReject,
+		LedgerOperationTransfer,
+	}
+	if !reflect.DeepEqual(operations, want) {
+		t.Errorf("Journal(): got operations %v, want %v", operations, want)
+	}
+
+	for _, accountID := range []int64{from.ID, to.ID} {
+		account, err := s.FindAccountByID(accountID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		balance, err := s.JournalBalance(accountID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if balance != account.Balance {
+			t.Errorf("JournalBalance(): got %v, want %v", balance, account.Balance)
+		}
+	}
+
+	discrepancies, err := s.Reconcile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(discrepancies) != 0 {
+		t.Errorf("Reconcile(): must be empty, got %v", discrepancies)
+	}
+}
+
+func TestService_Reconcile_tamperedBalance(t *testing.T) {
+	s := newTestService()
+	account, _, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := s.accounts.FindByID(account.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored.Balance += 1
+	err = s.accounts.Save(stored)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	discrepancies, err := s.Reconcile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Discrepancy{{
+		AccountID:		account.ID,
+		Balance:		stored.Balance,
+		JournalBalance:	stored.Balance - 1,
+	}}
+	if !reflect.DeepEqual(discrepancies, want) {
+		t.Errorf("Reconcile(): got %v, want %v", discrepancies, want)
+	}
+}
+
+func TestService_Reconcile_afterImport(t *testing.T) {
+	s := newTestService()
+	_, _, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	err = s.Export(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	imported := NewService()
+	err = imported.Import(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := imported.Journal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Operation != LedgerOperationOpening {
+		t.Errorf("Import(): must write an opening entry, got %v", entries)
+	}
+
+	discrepancies, err := imported.Reconcile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(discrepancies) != 0 {
+		t.Errorf("Reconcile(): must be empty, got %v", discrepancies)
+	}
+}
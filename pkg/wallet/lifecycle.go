@@ -0,0 +1,189 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aminjonshermatov/wallet/pkg/types"
+	"sort"
+	"strings"
+)
+
+var ErrInvalidTransition = errors.New("invalid payment status transition")
+
+// TransitionError is returned when a payment can't be moved to the requested status.
+// It matches ErrInvalidTransition with errors.Is.
+type TransitionError struct {
+	PaymentID	string
+	From		types.PaymentStatus
+	To			types.PaymentStatus
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("payment %s: can't change status from %s to %s", e.PaymentID, e.From, e.To)
+}
+
+func (e *TransitionError) Unwrap() error {
+	return ErrInvalidTransition
+}
+
+// Confirm completes an in progress payment.
+func (s *Service) Confirm(paymentID string) error {
+	s.lock()
+	defer s.mu.Unlock()
+
+	if err := s.begin(walConfirm, walArgs{PaymentID: paymentID}); err != nil {
+		return err
+	}
+	defer s.end()
+
+	payment, err := s.findPaymentByID(paymentID)
+	if err != nil {
+		return err
+	}
+
+	if payment.Kind != types.PaymentKindRegular {
+		return s.confirmTransfer(payment)
+	}
+
+	err = s.transition(payment, types.PaymentStatusOk)
+	if err != nil {
+		return err
+	}
+
+	return s.payments.Save(payment)
+}
+
+// PaymentHistory returns the status transitions the payment went through,
+// Export and Import keep them with the payments.
+func (s *Service) PaymentHistory(paymentID string) ([]types.PaymentTransition, error) {
+	s.rlock()
+	defer s.mu.RUnlock()
+
+	_, err := s.findPaymentByID(paymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	history := make([]types.PaymentTransition, len(s.transitions[paymentID]))
+	copy(history, s.transitions[paymentID])
+	return history, nil
+}
+
+func (s *Service) transition(payment *types.Payment, to types.PaymentStatus) error {
+	err := checkTransition(payment, to)
+	if err != nil {
+		return err
+	}
+
+	s.recordTransition(payment.ID, payment.Status, to)
+	payment.Status = to
+	payment.UpdatedAt = s.timestamp()
+	return nil
+}
+
+func checkTransition(payment *types.Payment, to types.PaymentStatus) error {
+	if !payment.Status.CanTransitionTo(to) {
+		return &TransitionError{
+			PaymentID:	payment.ID,
+			From:		payment.Status,
+			To:			to,
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) recordTransition(paymentID string, from types.PaymentStatus, to types.PaymentStatus) {
+	s.transitions[paymentID] = append(s.transitions[paymentID], types.PaymentTransition{
+		From:	from,
+		To:		to,
+		At:		s.timestamp(),
+	})
+}
+
+// transitionRecord is a line of transitions.dump.
+type transitionRecord struct {
+	paymentID	string
+	transition	types.PaymentTransition
+}
+
+func encodeTransition(record transitionRecord) string {
+	return record.paymentID + ";" +
+		string(record.transition.From) + ";" +
+		string(record.transition.To) + ";" +
+		encodeTime(record.transition.At)
+}
+
+func decodeTransition(line string) (transitionRecord, error) {
+	col := strings.Split(line, ";")
+	if len(col) < 4 {
+		return transitionRecord{}, ErrMalformedRecord
+	}
+
+	from := types.PaymentStatus(col[1])
+	if from != "" && !knownPaymentStatus(from) {
+		return transitionRecord{}, &columnError{column: "from", err: fmt.Errorf("unknown payment status %q", from)}
+	}
+	to := types.PaymentStatus(col[2])
+	if !knownPaymentStatus(to) {
+		return transitionRecord{}, &columnError{column: "to", err: fmt.Errorf("unknown payment status %q", to)}
+	}
+	at, err := decodeTime(col[3])
+	if err != nil {
+		return transitionRecord{}, &columnError{column: "at", err: err}
+	}
+
+	return transitionRecord{
+		paymentID:	col[0],
+		transition:	types.PaymentTransition{From: from, To: to, At: at},
+	}, nil
+}
+
+func exportTransitions(s *Service, dir string) error {
+	paymentIDs := make([]string, 0, len(s.transitions))
+	for paymentID := range s.transitions {
+		paymentIDs = append(paymentIDs, paymentID)
+	}
+	sort.Strings(paymentIDs)
+
+	lines := make([]string, 0, len(paymentIDs))
+	for _, paymentID := range paymentIDs {
+		for _, transition := range s.transitions[paymentID] {
+			lines = append(lines, encodeTransition(transitionRecord{paymentID: paymentID, transition: transition}))
+		}
+	}
+	return writeDumpFile(dir, "transitions.dump", lines)
+}
+
+// importTransitions adds the transitions of the imported payments, transitions
+// the Service already has are kept as they are.
+func importTransitions(s *Service, dir string, report *ImportReport) error {
+	return readDump(dir, "transitions.dump", report, func(line string) error {
+		record, err := decodeTransition(line)
+		if err != nil {
+			return err
+		}
+
+		_, err = s.payments.FindByID(record.paymentID)
+		if err == ErrPaymentNotFound {
+			return &columnError{column: "paymentId", err: err}
+		}
+		if err != nil {
+			return err
+		}
+
+		if !s.hasTransition(record) {
+			s.transitions[record.paymentID] = append(s.transitions[record.paymentID], record.transition)
+		}
+		return nil
+	})
+}
+
+func (s *Service) hasTransition(record transitionRecord) bool {
+	for _, existing := range s.transitions[record.paymentID] {
+		if existing.From == record.transition.From && existing.To == record.transition.To && existing.At.Equal(record.transition.At) {
+			return true
+		}
+	}
+	return false
+}
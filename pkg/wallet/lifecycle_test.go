@@ -0,0 +1,229 @@
+package wallet
+
+import (
+	"errors"
+	"github.com/aminjonshermatov/wallet/pkg/types"
+	"github.com/google/uuid"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestService_Confirm_success(t *testing.T) {
+	s := newTestService()
+	_, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payment := payments[0]
+	err = s.Confirm(payment.ID)
+	if err != nil {
+		t.Fatalf("Confirm(): error = %v", err)
+	}
+
+	got, err := s.FindPaymentByID(payment.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != types.PaymentStatusOk {
+		t.Errorf("Confirm(): status didn't changed, payment = %v", got)
+	}
+}
+
+func TestService_Confirm_notFound(t *testing.T) {
+	s := newTestService()
+
+	err := s.Confirm(uuid.New().String())
+	if err != ErrPaymentNotFound {
+		t.Errorf("Confirm(): must return ErrPaymentNotFound, returned = %v", err)
+	}
+}
+
+func TestService_Confirm_twice(t *testing.T) {
+	s := newTestService()
+	_, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payment := payments[0]
+	err = s.Confirm(payment.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.Confirm(payment.ID)
+	var transitionErr *TransitionError
+	if !errors.As(err, &transitionErr) {
+		t.Fatalf("Confirm(): must return TransitionError, returned = %v", err)
+	}
+	if transitionErr.From != types.PaymentStatusOk || transitionErr.To != types.PaymentStatusOk {
+		t.Errorf("Confirm(): invalid error = %v", transitionErr)
+	}
+	if !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("Confirm(): error must match ErrInvalidTransition")
+	}
+}
+
+func TestService_Reject_afterConfirm(t *testing.T) {
+	s := newTestService()
+	account, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payment := payments[0]
+	err = s.Confirm(payment.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.Reject(payment.ID)
+	if !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("Reject(): must return ErrInvalidTransition, returned = %v", err)
+	}
+
+	got, err := s.FindAccountByID(account.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Balance != defaultTestAccount.balance - payment.Amount {
+		t.Errorf("Reject(): balance must not change, account = %v", got)
+	}
+}
+
+func TestService_Reject_twice(t *testing.T) {
+	s := newTestService()
+	account, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payment := payments[0]
+	err = s.Reject(payment.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.Reject(payment.ID)
+	if !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("Reject(): must return ErrInvalidTransition, returned = %v", err)
+	}
+
+	got, err := s.FindAccountByID(account.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Balance != defaultTestAccount.balance {
+		t.Errorf("Reject(): balance credited twice, account = %v", got)
+	}
+}
+
+func TestService_PaymentHistory(t *testing.T) {
+	clock := newTestClock()
+	s := &testService{Service: NewService(WithClock(clock.Now))}
+	_, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := clock.Now()
+
+	clock.Advance(time.Minute)
+	payment := payments[0]
+	err = s.Reject(payment.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = s.Confirm(payment.ID)
+
+	history, err := s.PaymentHistory(payment.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []types.PaymentTransition{
+		{From: "", To: types.PaymentStatusInProgress, At: created},
+		{From: types.PaymentStatusInProgress, To: types.PaymentStatusFail, At: clock.Now()},
+	}
+	if !reflect.DeepEqual(history, want) {
+		t.Errorf("PaymentHistory(): got %v, want %v", history, want)
+	}
+
+	_, err = s.PaymentHistory(uuid.New().String())
+	if err != ErrPaymentNotFound {
+		t.Errorf("PaymentHistory(): must return ErrPaymentNotFound, returned = %v", err)
+	}
+}
+
+func TestService_PaymentHistory_exportImport(t *testing.T) {
+	clock := newTestClock()
+	s := &testService{Service: NewService(WithClock(clock.Now))}
+	_, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(time.Minute)
+	err = s.Confirm(payments[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := s.PaymentHistory(payments[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, format := range []Format{FormatDump, FormatJSON, FormatCSV} {
+		dir := t.TempDir()
+		err = s.Export(dir, WithFormat(format))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		imported := NewService()
+		err = imported.Import(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		history, err := imported.PaymentHistory(payments[0].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(history) != len(want) || history[1].To != types.PaymentStatusOk || !history[1].At.Equal(want[1].At) {
+			t.Errorf("Import(%s): got history %v, want %v", format, history, want)
+		}
+	}
+
+	dir := t.TempDir()
+	writeDump(t, dir, "transitions.dump", "missing;;INPROGRESS;0", payments[0].ID + ";OK;DONE;0")
+	report := &ImportReport{}
+	err = NewService().Import(dir, WithLenient(), WithReport(report))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertReported(t, report, "transitions.dump", []location{{1, "paymentId"}, {2, "to"}})
+}
+
+func TestPaymentStatus_CanTransitionTo(t *testing.T) {
+	tests := []struct{
+		from	types.PaymentStatus
+		to		types.PaymentStatus
+		want	bool
+	}{
+		{types.PaymentStatusInProgress, types.PaymentStatusOk, true},
+		{types.PaymentStatusInProgress, types.PaymentStatusFail, true},
+		{types.PaymentStatusOk, types.PaymentStatusFail, false},
+		{types.PaymentStatusFail, types.PaymentStatusOk, false},
+		{types.PaymentStatusFail, types.PaymentStatusFail, false},
+		{types.PaymentStatusOk, types.PaymentStatusInProgress, false},
+		{types.PaymentStatusInProgress, types.PaymentStatusRefunded, true},
+		{types.PaymentStatusOk, types.PaymentStatusRefunded, true},
+		{types.PaymentStatusFail, types.PaymentStatusRefunded, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.from.CanTransitionTo(tt.to); got != tt.want {
+			t.Errorf("%v.CanTransitionTo(%v) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,284 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aminjonshermatov/wallet/pkg/types"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrLimitExceeded = errors.New("spending limit exceeded")
+var ErrInvalidLimitPeriod = errors.New("limit period must be DAILY, WEEKLY or MONTHLY")
+var ErrLimitNotFound = errors.New("spending limit not found")
+
+// LimitPeriod is the calendar window a SpendingLimit is counted in.
+type LimitPeriod string
+
+const (
+	LimitPeriodDaily	LimitPeriod = "DAILY"
+	LimitPeriodWeekly	LimitPeriod = "WEEKLY"
+	LimitPeriodMonthly	LimitPeriod = "MONTHLY"
+)
+
+// SpendingLimit caps what the account pays in a period, outgoing transfers count as payments
+// of TransferCategory. An empty Category limits payments of all categories together.
+type SpendingLimit struct {
+	AccountID	int64
+	Period		LimitPeriod
+	Category	types.PaymentCategory
+	Amount		types.Money
+}
+
+// LimitError is returned when a payment doesn't fit into a limit, it matches ErrLimitExceeded.
+type LimitError struct {
+	Limit		SpendingLimit
+	Remaining	types.Money
+}
+
+func (e *LimitError) Error() string {
+	scope := "total"
+	if e.Limit.Category != "" {
+		scope = string(e.Limit.Category)
+	}
+	return fmt.Sprintf("%s %s limit exceeded, remaining %d", strings.ToLower(string(e.Limit.Period)), scope, e.Remaining)
+}
+
+func (e *LimitError) Unwrap() error {
+	return ErrLimitExceeded
+}
+
+// WithLimitLocation sets the time zone where days, weeks and months of spending limits start.
+// The default is UTC.
+func WithLimitLocation(location *time.Location) Option {
+	return func(s *Service) {
+		s.location = location
+	}
+}
+
+// SetSpendingLimit sets or replaces the account limit for the period and category.
+func (s *Service) SetSpendingLimit(accountID int64, period LimitPeriod, category types.PaymentCategory, amount types.Money) error {
+	if amount <= 0 {
+		return ErrAmountMustBePositive
+	}
+	if !period.valid() {
+		return ErrInvalidLimitPeriod
+	}
+	err := checkCategory(category)
+	if err != nil {
+		return err
+	}
+
+	s.lock()
+	defer s.mu.Unlock()
+
+	if err := s.begin(walSetSpendingLimit, walArgs{AccountID: accountID, Period: period, Category: category, Amount: amount}); err != nil {
+		return err
+	}
+	defer s.end()
+
+	_, err = s.findAccountByID(accountID)
+	if err != nil {
+		return err
+	}
+
+	s.setLimit(SpendingLimit{AccountID: accountID, Period: period, Category: category, Amount: amount})
+	return nil
+}
+
+// RemoveSpendingLimit removes the account limit for the period and category.
+func (s *Service) RemoveSpendingLimit(accountID int64, period LimitPeriod, category types.PaymentCategory) error {
+	s.lock()
+	defer s.mu.Unlock()
+
+	if err := s.begin(walRemoveSpendingLimit, walArgs{AccountID: accountID, Period: period, Category: category}); err != nil {
+		return err
+	}
+	defer s.end()
+
+	limits := s.limits[accountID]
+	for i, limit := range limits {
+		if limit.Period == period && limit.Category == category {
+			s.limits[accountID] = append(limits[:i:i], limits[i+1:]...)
+			return nil
+		}
+	}
+	return ErrLimitNotFound
+}
+
+// SpendingLimits returns limits of the account in the order they were set.
+func (s *Service) SpendingLimits(accountID int64) ([]SpendingLimit, error) {
+	s.rlock()
+	defer s.mu.RUnlock()
+
+	_, err := s.findAccountByID(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]SpendingLimit, len(s.limits[accountID]))
+	copy(res, s.limits[accountID])
+	return res, nil
+}
+
+func (s *Service) setLimit(limit SpendingLimit) {
+	limits := s.limits[limit.AccountID]
+	for i := range limits {
+		if limits[i].Period == limit.Period && limits[i].Category == limit.Category {
+			limits[i] = limit
+			return
+		}
+	}
+	s.limits[limit.AccountID] = append(limits, limit)
+}
+
+func (s *Service) hasLimit(limit SpendingLimit) bool {
+	for _, existing := range s.limits[limit.AccountID] {
+		if existing.Period == limit.Period && existing.Category == limit.Category {
+			return true
+		}
+	}
+	return false
+}
+
+// checkLimits returns a LimitError for the tightest limit the payment doesn't fit into.
+func (s *Service) checkLimits(accountID int64, amount types.Money, category types.PaymentCategory) error {
+	limits := s.limits[accountID]
+	if len(limits) == 0 {
+		return nil
+	}
+
+	payments, err := s.payments.FindByAccountID(accountID)
+	if err != nil {
+		return err
+	}
+
+	now := s.timestamp()
+	var exceeded *LimitError
+	for _, limit := range limits {
+		if limit.Category != "" && limit.Category != category {
+			continue
+		}
+
+		start := limit.Period.start(now.In(s.location))
+		spent := types.Money(0)
+		for _, payment := range payments {
+			if payment.Kind == types.PaymentKindTransferIn || payment.CreatedAt.Before(start) {
+				continue
+			}
+			if limit.Category == "" || payment.Category == limit.Category {
+				spent += payment.Charged()
+			}
+		}
+
+		remaining := limit.Amount - spent
+		if remaining < 0 {
+			remaining = 0
+		}
+		if amount > remaining && (exceeded == nil || remaining < exceeded.Remaining) {
+			exceeded = &LimitError{Limit: limit, Remaining: remaining}
+		}
+	}
+
+	if exceeded != nil {
+		return exceeded
+	}
+	return nil
+}
+
+func (p LimitPeriod) valid() bool {
+	return p == LimitPeriodDaily || p == LimitPeriodWeekly || p == LimitPeriodMonthly
+}
+
+// start returns the beginning of the period containing t, in the location of t.
+// Weeks start on Monday.
+func (p LimitPeriod) start(t time.Time) time.Time {
+	year, month, day := t.Date()
+	switch p {
+	case LimitPeriodWeekly:
+		day -= (int(t.Weekday()) + 6) % 7
+	case LimitPeriodMonthly:
+		day = 1
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+func encodeLimit(limit SpendingLimit) string {
+	return strconv.FormatInt(limit.AccountID, 10) + ";" +
+		string(limit.Period) + ";" +
+		string(limit.Category) + ";" +
+		strconv.FormatInt(int64(limit.Amount), 10)
+}
+
+func decodeLimit(line string) (SpendingLimit, error) {
+	col := strings.Split(line, ";")
+	if len(col) < 4 {
+		return SpendingLimit{}, ErrMalformedRecord
+	}
+
+	accountID, err := strconv.ParseInt(col[0], 10, 64)
+	if err != nil {
+		return SpendingLimit{}, &columnError{column: "accountId", err: err}
+	}
+
+	amount, err := strconv.ParseInt(col[3], 10, 64)
+	if err != nil {
+		return SpendingLimit{}, &columnError{column: "amount", err: err}
+	}
+
+	limit := SpendingLimit{
+		AccountID:	accountID,
+		Period:		LimitPeriod(col[1]),
+		Category:	types.PaymentCategory(col[2]),
+		Amount:		types.Money(amount),
+	}
+	if !limit.Period.valid() {
+		return SpendingLimit{}, &columnError{column: "period", err: ErrInvalidLimitPeriod}
+	}
+	if checkCategory(limit.Category) != nil {
+		return SpendingLimit{}, &columnError{column: "category", err: ErrInvalidCategory}
+	}
+	if limit.Amount <= 0 {
+		return SpendingLimit{}, &columnError{column: "amount", err: ErrAmountMustBePositive}
+	}
+	return limit, nil
+}
+
+func exportLimits(s *Service, dir string) error {
+	accountIDs := make([]int64, 0, len(s.limits))
+	for accountID := range s.limits {
+		accountIDs = append(accountIDs, accountID)
+	}
+	sort.Slice(accountIDs, func(i, j int) bool {
+		return accountIDs[i] < accountIDs[j]
+	})
+
+	lines := make([]string, 0, len(accountIDs))
+	for _, accountID := range accountIDs {
+		for _, limit := range s.limits[accountID] {
+			lines = append(lines, encodeLimit(limit))
+		}
+	}
+	return writeDumpFile(dir, "limits.dump", lines)
+}
+
+func importLimits(s *Service, dir string, report *ImportReport) error {
+	return readDump(dir, "limits.dump", report, func(record string) error {
+		limit, err := decodeLimit(record)
+		if err != nil {
+			return err
+		}
+
+		err = s.checkOwner(limit.AccountID)
+		if err != nil {
+			return err
+		}
+
+		if !s.hasLimit(limit) {
+			s.setLimit(limit)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,287 @@
+package wallet
+
+import (
+	"errors"
+	"github.com/aminjonshermatov/wallet/pkg/types"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestService_SetSpendingLimit_daily(t *testing.T) {
+	clock := newTestClock()
+	s := &testService{Service: NewService(WithClock(clock.Now))}
+	account, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spent := types.Money(0)
+	for _, payment := range payments {
+		spent += payment.Amount
+	}
+
+	err = s.SetSpendingLimit(account.ID, LimitPeriodDaily, "", spent + 100)
+	if err != nil {
+		t.Fatalf("SetSpendingLimit(): error = %v", err)
+	}
+
+	_, err = s.Pay(account.ID, 101, "food")
+	var limitErr *LimitError
+	if !errors.As(err, &limitErr) || !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("Pay(): must return LimitError, returned = %v", err)
+	}
+	if limitErr.Remaining != 100 || limitErr.Limit.Period != LimitPeriodDaily {
+		t.Errorf("Pay(): invalid error = %v", limitErr)
+	}
+
+	_, err = s.Pay(account.ID, 100, "food")
+	if err != nil {
+		t.Fatalf("Pay(): error = %v", err)
+	}
+	_, err = s.Repeat(payments[0].ID)
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Repeat(): must return ErrLimitExceeded, returned = %v", err)
+	}
+	favorite, err := s.FavoritePayment(payments[0].ID, "osh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.PayFromFavorite(favorite.ID)
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("PayFromFavorite(): must return ErrLimitExceeded, returned = %v", err)
+	}
+
+	clock.Set(time.Date(2021, 3, 2, 0, 0, 0, 0, time.UTC))
+	_, err = s.Repeat(payments[0].ID)
+	if err != nil {
+		t.Errorf("Repeat(): limit must reset the next day, returned = %v", err)
+	}
+}
+
+func TestService_SetSpendingLimit_category(t *testing.T) {
+	s := newTestService()
+	account, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Deposit(account.ID, 10_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.SetSpendingLimit(account.ID, LimitPeriodMonthly, "food", 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.SetSpendingLimit(account.ID, LimitPeriodWeekly, "", 1_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.Pay(account.ID, 800, "auto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	food, err := s.Pay(account.ID, 200, "food")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.Pay(account.ID, 1, "auto")
+	var limitErr *LimitError
+	if !errors.As(err, &limitErr) || limitErr.Limit.Category != "" || limitErr.Remaining != 0 {
+		t.Errorf("Pay(): must exceed the total weekly limit, returned = %v", err)
+	}
+
+	err = s.Reject(food.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.Pay(account.ID, 200, "food")
+	if err != nil {
+		t.Errorf("Pay(): rejected payments must not count, returned = %v", err)
+	}
+
+	err = s.RemoveSpendingLimit(account.ID, LimitPeriodWeekly, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.Pay(account.ID, 301, "food")
+	if !errors.As(err, &limitErr) || limitErr.Limit.Category != "food" || limitErr.Remaining != 300 {
+		t.Errorf("Pay(): must exceed the monthly food limit, returned = %v", err)
+	}
+	err = s.RemoveSpendingLimit(account.ID, LimitPeriodWeekly, "")
+	if err != ErrLimitNotFound {
+		t.Errorf("RemoveSpendingLimit(): must return ErrLimitNotFound, returned = %v", err)
+	}
+}
+
+func TestService_SetSpendingLimit_transfer(t *testing.T) {
+	s := newTestService()
+	from, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	to, err := s.RegisterAccount("+992000000002")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.SetSpendingLimit(from.ID, LimitPeriodDaily, TransferCategory, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.SetSpendingLimit(from.ID, LimitPeriodDaily, "", payments[0].Amount + 800)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	transfer, err := s.Transfer(from.ID, to.ID, 400)
+	if err != nil {
+		t.Fatalf("Transfer(): error = %v", err)
+	}
+	_, err = s.Repeat(transfer.ID)
+	var limitErr *LimitError
+	if !errors.As(err, &limitErr) || limitErr.Remaining != 100 || limitErr.Limit.Category != TransferCategory {
+		t.Fatalf("Repeat(): must return the transfer LimitError, returned = %v", err)
+	}
+
+	// outgoing transfers count in the total limit too
+	_, err = s.Pay(from.ID, 401, "food")
+	if !errors.As(err, &limitErr) || limitErr.Remaining != 400 {
+		t.Errorf("Pay(): must return the total LimitError, returned = %v", err)
+	}
+
+	// a rejected transfer frees its part of the limits, incoming transfers don't use them
+	err = s.Reject(transfer.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.Transfer(from.ID, to.ID, 500)
+	if err != nil {
+		t.Errorf("Transfer(): error = %v", err)
+	}
+	_, err = s.Transfer(to.ID, from.ID, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.Pay(from.ID, 300, "food")
+	if err != nil {
+		t.Errorf("Pay(): incoming transfers must not use the limits, error = %v", err)
+	}
+}
+
+func TestService_SetSpendingLimit_fail(t *testing.T) {
+	s := newTestService()
+	account, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.SetSpendingLimit(account.ID, "YEARLY", "", 100)
+	if err != ErrInvalidLimitPeriod {
+		t.Errorf("SetSpendingLimit(): must return ErrInvalidLimitPeriod, returned = %v", err)
+	}
+	err = s.SetSpendingLimit(account.ID, LimitPeriodDaily, "", 0)
+	if err != ErrAmountMustBePositive {
+		t.Errorf("SetSpendingLimit(): must return ErrAmountMustBePositive, returned = %v", err)
+	}
+	err = s.SetSpendingLimit(account.ID + 1, LimitPeriodDaily, "", 100)
+	if err != ErrAccountNotFound {
+		t.Errorf("SetSpendingLimit(): must return ErrAccountNotFound, returned = %v", err)
+	}
+}
+
+func TestLimitPeriod_start(t *testing.T) {
+	dushanbe := time.FixedZone("Asia/Dushanbe", 5 * 60 * 60)
+	// Wednesday 2021-03-03 22:30 UTC is Thursday 03:30 in Dushanbe.
+	now := time.Date(2021, 3, 3, 22, 30, 0, 0, time.UTC)
+
+	tests := []struct{
+		period		LimitPeriod
+		location	*time.Location
+		want		time.Time
+	}{
+		{LimitPeriodDaily, time.UTC, time.Date(2021, 3, 3, 0, 0, 0, 0, time.UTC)},
+		{LimitPeriodDaily, dushanbe, time.Date(2021, 3, 4, 0, 0, 0, 0, dushanbe)},
+		{LimitPeriodWeekly, time.UTC, time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{LimitPeriodMonthly, dushanbe, time.Date(2021, 3, 1, 0, 0, 0, 0, dushanbe)},
+	}
+
+	for _, tt := range tests {
+		got := tt.period.start(now.In(tt.location))
+		if !got.Equal(tt.want) {
+			t.Errorf("%v.start() in %v = %v, want %v", tt.period, tt.location, got, tt.want)
+		}
+	}
+}
+
+func TestService_SpendingLimits_location(t *testing.T) {
+	dushanbe := time.FixedZone("Asia/Dushanbe", 5 * 60 * 60)
+	clock := newTestClock()
+	clock.Set(time.Date(2021, 3, 1, 18, 0, 0, 0, time.UTC))
+	s := &testService{Service: NewService(WithClock(clock.Now), WithLimitLocation(dushanbe))}
+	account, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Deposit(account.ID, 1_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.SetSpendingLimit(account.ID, LimitPeriodDaily, "", 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.Pay(account.ID, 100, "food")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 19:00 UTC is already the next day in Dushanbe.
+	clock.Set(time.Date(2021, 3, 1, 19, 0, 0, 0, time.UTC))
+	_, err = s.Pay(account.ID, 100, "food")
+	if err != nil {
+		t.Errorf("Pay(): limit must reset at midnight in the limit location, returned = %v", err)
+	}
+}
+
+func TestService_SpendingLimits_exportImport(t *testing.T) {
+	s := newTestService()
+	account, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.SetSpendingLimit(account.ID, LimitPeriodDaily, "", 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.SetSpendingLimit(account.ID, LimitPeriodMonthly, "food", 1_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	err = s.Export(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	imported := NewService()
+	err = imported.Import(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := s.SpendingLimits(account.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := imported.SpendingLimits(account.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SpendingLimits(): after Import got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,223 @@
+package wallet
+
+import (
+	"github.com/aminjonshermatov/wallet/pkg/types"
+)
+
+// MemoryAccountRepository keeps accounts in memory, indexed by id and phone.
+// It is not safe for concurrent use on its own, the Service serializes access.
+type MemoryAccountRepository struct {
+	lastID		int64
+	accounts	[]*types.Account
+	byID		map[int64]*types.Account
+	byPhone		map[types.Phone]*types.Account
+}
+
+func NewMemoryAccountRepository() *MemoryAccountRepository {
+	return &MemoryAccountRepository{
+		byID:		make(map[int64]*types.Account),
+		byPhone:	make(map[types.Phone]*types.Account),
+	}
+}
+
+func (r *MemoryAccountRepository) NextID() (int64, error) {
+	return r.lastID + 1, nil
+}
+
+func (r *MemoryAccountRepository) Save(account *types.Account) error {
+	if r.byID == nil {
+		r.byID = make(map[int64]*types.Account)
+		r.byPhone = make(map[types.Phone]*types.Account)
+	}
+
+	if acc, ok := r.byID[account.ID]; ok {
+		if acc.Phone != account.Phone {
+			delete(r.byPhone, acc.Phone)
+			r.byPhone[account.Phone] = acc
+		}
+		*acc = *account
+		return nil
+	}
+
+	acc := copyAccount(account)
+	r.accounts = append(r.accounts, acc)
+	r.byID[acc.ID] = acc
+	r.byPhone[acc.Phone] = acc
+	if acc.ID > r.lastID {
+		r.lastID = acc.ID
+	}
+	return nil
+}
+
+func (r *MemoryAccountRepository) FindByID(id int64) (*types.Account, error) {
+	account, ok := r.byID[id]
+	if !ok {
+		return nil, ErrAccountNotFound
+	}
+
+	return copyAccount(account), nil
+}
+
+func (r *MemoryAccountRepository) FindByPhone(phone types.Phone) (*types.Account, error) {
+	account, ok := r.byPhone[phone]
+	if !ok {
+		return nil, ErrAccountNotFound
+	}
+
+	return copyAccount(account), nil
+}
+
+func (r *MemoryAccountRepository) All() ([]*types.Account, error) {
+	return r.accounts[:len(r.accounts):len(r.accounts)], nil
+}
+
+// MemoryPaymentRepository keeps payments in memory, indexed by id and account.
+// It is not safe for concurrent use on its own, the Service serializes access.
+type MemoryPaymentRepository struct {
+	payments	[]*types.Payment
+	byID		map[string]*types.Payment
+	byAccount	map[int64][]*types.Payment
+}
+
+func NewMemoryPaymentRepository() *MemoryPaymentRepository {
+	return &MemoryPaymentRepository{
+		byID:		make(map[string]*types.Payment),
+		byAccount:	make(map[int64][]*types.Payment),
+	}
+}
+
+func (r *MemoryPaymentRepository) Save(payment *types.Payment) error {
+	if r.byID == nil {
+		r.byID = make(map[string]*types.Payment)
+		r.byAccount = make(map[int64][]*types.Payment)
+	}
+
+	if p, ok := r.byID[payment.ID]; ok {
+		previousAccountID := p.AccountID
+		*p = *payment
+		if previousAccountID != p.AccountID {
+			r.byAccount[previousAccountID] = removePayment(r.byAccount[previousAccountID], p)
+			r.reindexAccount(p.AccountID)
+		}
+		return nil
+	}
+
+	p := copyPayment(payment)
+	r.payments = append(r.payments, p)
+	r.byID[p.ID] = p
+	r.byAccount[p.AccountID] = append(r.byAccount[p.AccountID], p)
+	return nil
+}
+
+func (r *MemoryPaymentRepository) FindByID(id string) (*types.Payment, error) {
+	payment, ok := r.byID[id]
+	if !ok {
+		return nil, ErrPaymentNotFound
+	}
+
+	return copyPayment(payment), nil
+}
+
+func (r *MemoryPaymentRepository) FindByAccountID(accountID int64) ([]*types.Payment, error) {
+	payments := r.byAccount[accountID]
+	res := make([]*types.Payment, len(payments))
+	for i, payment := range payments {
+		res[i] = copyPayment(payment)
+	}
+
+	return res, nil
+}
+
+func (r *MemoryPaymentRepository) All() ([]*types.Payment, error) {
+	return r.payments[:len(r.payments):len(r.payments)], nil
+}
+
+// reindexAccount rebuilds the account index in the order of all payments.
+func (r *MemoryPaymentRepository) reindexAccount(accountID int64) {
+	payments := make([]*types.Payment, 0)
+	for _, payment := range r.payments {
+		if payment.AccountID == accountID {
+			payments = append(payments, payment)
+		}
+	}
+
+	r.byAccount[accountID] = payments
+}
+
+func removePayment(payments []*types.Payment, payment *types.Payment) []*types.Payment {
+	for i, p := range payments {
+		if p == payment {
+			return append(payments[:i:i], payments[i+1:]...)
+		}
+	}
+
+	return payments
+}
+
+// MemoryFavoriteRepository keeps favorites in memory, indexed by id.
+// It is not safe for concurrent use on its own, the Service serializes access.
+type MemoryFavoriteRepository struct {
+	favorites	[]*types.Favorite
+	byID		map[string]*types.Favorite
+}
+
+func NewMemoryFavoriteRepository() *MemoryFavoriteRepository {
+	return &MemoryFavoriteRepository{
+		byID:	make(map[string]*types.Favorite),
+	}
+}
+
+func (r *MemoryFavoriteRepository) Save(favorite *types.Favorite) error {
+	if r.byID == nil {
+		r.byID = make(map[string]*types.Favorite)
+	}
+
+	if f, ok := r.byID[favorite.ID]; ok {
+		*f = *favorite
+		return nil
+	}
+
+	f := copyFavorite(favorite)
+	r.favorites = append(r.favorites, f)
+	r.byID[f.ID] = f
+	return nil
+}
+
+func (r *MemoryFavoriteRepository) Delete(id string) error {
+	if _, ok := r.byID[id]; !ok {
+		return ErrFavoriteNotFound
+	}
+
+	delete(r.byID, id)
+	for i, favorite := range r.favorites {
+		if favorite.ID == id {
+			r.favorites = append(r.favorites[:i:i], r.favorites[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (r *MemoryFavoriteRepository) FindByID(id string) (*types.Favorite, error) {
+	favorite, ok := r.byID[id]
+	if !ok {
+		return nil, ErrFavoriteNotFound
+	}
+
+	return copyFavorite(favorite), nil
+}
+
+func (r *MemoryFavoriteRepository) FindByAccountID(accountID int64) ([]*types.Favorite, error) {
+	res := make([]*types.Favorite, 0)
+	for _, favorite := range r.favorites {
+		if favorite.AccountID == accountID {
+			res = append(res, copyFavorite(favorite))
+		}
+	}
+
+	return res, nil
+}
+
+func (r *MemoryFavoriteRepository) All() ([]*types.Favorite, error) {
+	return r.favorites[:len(r.favorites):len(r.favorites)], nil
+}
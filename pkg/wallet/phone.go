@@ -0,0 +1,116 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aminjonshermatov/wallet/pkg/types"
+	"strings"
+)
+
+var ErrInvalidPhone = errors.New("invalid phone number")
+
+// DefaultCountryCode is used for numbers written without a country code.
+const DefaultCountryCode = "992"
+
+// PhoneError describes why a phone number was rejected, it matches ErrInvalidPhone.
+type PhoneError struct {
+	Phone	types.Phone
+	Reason	string
+}
+
+func (e *PhoneError) Error() string {
+	return fmt.Sprintf("invalid phone number %q: %s", string(e.Phone), e.Reason)
+}
+
+func (e *PhoneError) Unwrap() error {
+	return ErrInvalidPhone
+}
+
+// phoneCountry is the numbering plan of a country known to NormalizePhone.
+type phoneCountry struct {
+	code			string
+	nationalLength	int
+}
+
+var phoneCountries = []phoneCountry{
+	{code: "992", nationalLength: 9},
+}
+
+// E.164 limits the number to 15 digits including the country code.
+const (
+	minPhoneDigits	= 8
+	maxPhoneDigits	= 15
+)
+
+// NormalizePhone returns the phone in E.164 form: "+" followed by the country code and the national number.
+// Spaces, dashes, dots and parentheses are ignored, "00" may be used instead of "+",
+// and a national number without a country code belongs to DefaultCountryCode.
+func NormalizePhone(phone types.Phone) (types.Phone, error) {
+	raw := strings.TrimSpace(string(phone))
+	international := strings.HasPrefix(raw, "+")
+	if international {
+		raw = raw[1:]
+	}
+
+	digits := make([]byte, 0, len(raw))
+	for i := 0; i < len(raw); i++ {
+		c := raw[i]
+		switch {
+		case c >= '0' && c <= '9':
+			digits = append(digits, c)
+		case c == ' ' || c == '-' || c == '.' || c == '(' || c == ')':
+		default:
+			return "", &PhoneError{Phone: phone, Reason: fmt.Sprintf("unexpected character %q", c)}
+		}
+	}
+	number := string(digits)
+
+	// "00" is the international prefix, unless the whole number is a national one.
+	if !international && strings.HasPrefix(number, "00") && len(number) != defaultNationalLength() {
+		number = number[2:]
+		international = true
+	}
+
+	if number == "" {
+		return "", &PhoneError{Phone: phone, Reason: "no digits"}
+	}
+
+	for _, country := range phoneCountries {
+		if strings.HasPrefix(number, country.code) && len(number) == len(country.code) + country.nationalLength {
+			return types.Phone("+" + number), nil
+		}
+		if !international && country.code == DefaultCountryCode && len(number) == country.nationalLength {
+			return types.Phone("+" + country.code + number), nil
+		}
+		if strings.HasPrefix(number, country.code) && (international || len(number) > country.nationalLength) {
+			return "", &PhoneError{
+				Phone:	phone,
+				Reason:	fmt.Sprintf("+%s numbers must have %d digits after the country code", country.code, country.nationalLength),
+			}
+		}
+	}
+
+	if !international {
+		return "", &PhoneError{Phone: phone, Reason: "country code is required"}
+	}
+	if number[0] == '0' {
+		return "", &PhoneError{Phone: phone, Reason: "country code must not start with 0"}
+	}
+	if len(number) < minPhoneDigits || len(number) > maxPhoneDigits {
+		return "", &PhoneError{
+			Phone:	phone,
+			Reason:	fmt.Sprintf("number must have from %d to %d digits", minPhoneDigits, maxPhoneDigits),
+		}
+	}
+
+	return types.Phone("+" + number), nil
+}
+
+func defaultNationalLength() int {
+	for _, country := range phoneCountries {
+		if country.code == DefaultCountryCode {
+			return country.nationalLength
+		}
+	}
+	return 0
+}
@@ -0,0 +1,119 @@
+package wallet
+
+import (
+	"errors"
+	"github.com/aminjonshermatov/wallet/pkg/types"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct{
+		phone	types.Phone
+		want	types.Phone
+	}{
+		{"+992000000001", "+992000000001"},
+		{"992000000001", "+992000000001"},
+		{"+992 00 000 0001", "+992000000001"},
+		{"00992-00-000-00-01", "+992000000001"},
+		{"(00) 000-00-01", "+992000000001"},
+		{" 000000001 ", "+992000000001"},
+		{"+7 (912) 345-67-89", "+79123456789"},
+	}
+
+	for _, tt := range tests {
+		got, err := NormalizePhone(tt.phone)
+		if err != nil {
+			t.Errorf("NormalizePhone(%q): error = %v", tt.phone, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("NormalizePhone(%q) = %q, want %q", tt.phone, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizePhone_invalid(t *testing.T) {
+	for _, phone := range []types.Phone{
+		"",
+		"+",
+		"phone",
+		"+992 00 000 00",
+		"+992 00 000 00011",
+		"99200000000122",
+		"12345",
+		"+0123456789",
+		"+1234567",
+		"+1234567890123456",
+		"+992;000000001",
+	} {
+		_, err := NormalizePhone(phone)
+		var phoneErr *PhoneError
+		if !errors.As(err, &phoneErr) {
+			t.Errorf("NormalizePhone(%q): must return PhoneError, returned = %v", phone, err)
+			continue
+		}
+		if phoneErr.Phone != phone || !errors.Is(err, ErrInvalidPhone) {
+			t.Errorf("NormalizePhone(%q): invalid error = %v", phone, err)
+		}
+	}
+}
+
+func TestService_RegisterAccount_normalizesPhone(t *testing.T) {
+	s := newTestService()
+	account, err := s.RegisterAccount("+992 00 000 0001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.Phone != "+992000000001" {
+		t.Errorf("RegisterAccount(): phone must be normalized, got %q", account.Phone)
+	}
+
+	for _, phone := range []types.Phone{"+992000000001", "992000000001", "000000001"} {
+		_, err = s.RegisterAccount(phone)
+		if err != ErrPhoneRegistered {
+			t.Errorf("RegisterAccount(%q): must return ErrPhoneRegistered, returned = %v", phone, err)
+		}
+	}
+
+	_, err = s.RegisterAccount("+992 00")
+	if !errors.Is(err, ErrInvalidPhone) {
+		t.Errorf("RegisterAccount(): must return ErrInvalidPhone, returned = %v", err)
+	}
+}
+
+func TestService_Import_normalizesPhone(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "accounts.dump"), []byte("1;992 000 000 001;100\n"), 0660)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := newTestService()
+	err = s.Import(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	account, err := s.FindAccountByID(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.Phone != "+992000000001" {
+		t.Errorf("Import(): phone must be normalized, got %q", account.Phone)
+	}
+
+	_, err = s.RegisterAccount("+992000000001")
+	if err != ErrPhoneRegistered {
+		t.Errorf("RegisterAccount(): must return ErrPhoneRegistered, returned = %v", err)
+	}
+
+	err = os.WriteFile(filepath.Join(dir, "accounts.dump"), []byte("2;+992000000001;0\n"), 0660)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Import(dir)
+	if !errors.Is(err, ErrPhoneRegistered) {
+		t.Errorf("Import(): must return ErrPhoneRegistered for a duplicate phone, returned = %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package wallet
+
+import (
+	"errors"
+	"github.com/aminjonshermatov/wallet/pkg/types"
+)
+
+var ErrRefundExceedsAmount = errors.New("refund exceeds the not refunded part of the payment")
+var ErrTransferNotRefundable = errors.New("transfers can't be refunded, reject them instead")
+
+// Refund gives amount of the payment back to the account.
+// It may be called several times until the whole payment amount is refunded,
+// then the payment moves to PaymentStatusRefunded. The original amount is kept.
+func (s *Service) Refund(paymentID string, amount types.Money) (*types.Payment, error) {
+	s.lock()
+	defer s.mu.Unlock()
+
+	if err := s.begin(walRefund, walArgs{PaymentID: paymentID, Amount: amount}); err != nil {
+		return nil, err
+	}
+	defer s.end()
+
+	payment, err := s.refund(paymentID, amount)
+	if err != nil {
+		return nil, err
+	}
+
+	return copyPayment(payment), nil
+}
+
+func (s *Service) refund(paymentID string, amount types.Money) (*types.Payment, error) {
+	if amount <= 0 {
+		return nil, ErrAmountMustBePositive
+	}
+
+	payment, err := s.findPaymentByID(paymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if payment.Kind != types.PaymentKindRegular {
+		return nil, ErrTransferNotRefundable
+	}
+
+	err = checkTransition(payment, types.PaymentStatusRefunded)
+	if err != nil {
+		return nil, err
+	}
+
+	if amount > payment.Charged() {
+		return nil, ErrRefundExceedsAmount
+	}
+
+	account, err := s.findAccountByID(payment.AccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	if account.Status == types.AccountStatusClosed {
+		return nil, ErrAccountClosed
+	}
+
+	err = s.post(LedgerOperationRefund, LedgerPayments, WalletLedgerAccount(account.ID), amount, payment.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	account.Balance += amount
+	payment.Refunded += amount
+	payment.UpdatedAt = s.timestamp()
+	if payment.Charged() == 0 {
+		err = s.transition(payment, types.PaymentStatusRefunded)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = s.payments.Save(payment)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.accounts.Save(account)
+	if err != nil {
+		return nil, err
+	}
+
+	return payment, nil
+}
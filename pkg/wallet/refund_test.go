@@ -0,0 +1,161 @@
+package wallet
+
+import (
+	"errors"
+	"github.com/aminjonshermatov/wallet/pkg/types"
+	"testing"
+)
+
+func TestService_Refund_partial(t *testing.T) {
+	s := newTestService()
+	account, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Deposit(account.ID, 1_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payment, err := s.Pay(account.ID, 300, "food")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.Refund(payment.ID, 100)
+	if err != nil {
+		t.Fatalf("Refund(): error = %v", err)
+	}
+	if got.Amount != 300 || got.Refunded != 100 || got.Status != types.PaymentStatusInProgress {
+		t.Errorf("Refund(): invalid payment = %v", got)
+	}
+	s.checkBalance(t, account.ID, 800)
+
+	_, err = s.Refund(payment.ID, 201)
+	if err != ErrRefundExceedsAmount {
+		t.Errorf("Refund(): must return ErrRefundExceedsAmount, returned = %v", err)
+	}
+
+	got, err = s.Refund(payment.ID, 200)
+	if err != nil {
+		t.Fatalf("Refund(): error = %v", err)
+	}
+	if got.Amount != 300 || got.Refunded != 300 || got.Status != types.PaymentStatusRefunded {
+		t.Errorf("Refund(): invalid payment = %v", got)
+	}
+	s.checkBalance(t, account.ID, 1_000)
+
+	_, err = s.Refund(payment.ID, 1)
+	if !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("Refund(): must return ErrInvalidTransition, returned = %v", err)
+	}
+
+	discrepancies, err := s.Reconcile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(discrepancies) != 0 {
+		t.Errorf("Reconcile(): must be empty, got %v", discrepancies)
+	}
+}
+
+func TestService_Refund_confirmed(t *testing.T) {
+	s := newTestService()
+	account, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payment := payments[0]
+	err = s.Confirm(payment.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.Refund(payment.ID, payment.Amount)
+	if err != nil {
+		t.Fatalf("Refund(): error = %v", err)
+	}
+	s.checkBalance(t, account.ID, defaultTestAccount.balance)
+}
+
+func TestService_Refund_fail(t *testing.T) {
+	s := newTestService()
+	from, to := s.addTransferAccounts(t)
+
+	_, err := s.Refund("unknown", 10)
+	if err != ErrPaymentNotFound {
+		t.Errorf("Refund(): must return ErrPaymentNotFound, returned = %v", err)
+	}
+
+	payment, err := s.Pay(from.ID, 100, "food")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.Refund(payment.ID, 0)
+	if err != ErrAmountMustBePositive {
+		t.Errorf("Refund(): must return ErrAmountMustBePositive, returned = %v", err)
+	}
+
+	transfer, err := s.Transfer(from.ID, to.ID, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.Refund(transfer.ID, 10)
+	if err != ErrTransferNotRefundable {
+		t.Errorf("Refund(): must return ErrTransferNotRefundable, returned = %v", err)
+	}
+}
+
+func TestService_Refund_sumAndExport(t *testing.T) {
+	s := newTestService()
+	account, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Deposit(account.ID, 1_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	refunded, err := s.Pay(account.ID, 300, "food")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rejected, err := s.Pay(account.ID, 200, "food")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.Refund(refunded.ID, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Reject(rejected.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sum := s.SumPayments(2); sum != 200 {
+		t.Errorf("SumPayments(): got %v, want %v", sum, 200)
+	}
+
+	dir := t.TempDir()
+	err = s.Export(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	imported := NewService()
+	err = imported.Import(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := imported.FindPaymentByID(rejected.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Amount != 200 || got.Refunded != 200 {
+		t.Errorf("Import(): invalid rejected payment = %v", got)
+	}
+	if sum := imported.SumPayments(2); sum != 200 {
+		t.Errorf("SumPayments(): after Import got %v, want %v", sum, 200)
+	}
+}
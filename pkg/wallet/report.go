@@ -0,0 +1,274 @@
+package wallet
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aminjonshermatov/wallet/pkg/types"
+	"strconv"
+)
+
+var ErrInvalidRecords = errors.New("invalid records")
+
+// RecordError is a record Import couldn't read or restore.
+type RecordError struct {
+	File	string
+	// Line is 1-based, in csv files it is the line the record starts on, in files written
+	// by ExportToFile it is the row.
+	// 0 means the whole file, like a file that doesn't match the manifest.
+	Line	int
+	// Column is empty when the record as a whole is invalid.
+	Column	string
+	Reason	string
+
+	err		error
+}
+
+func (e RecordError) String() string {
+	location := e.File
+	if e.Line != 0 {
+		location += ":" + strconv.Itoa(e.Line)
+	}
+	if e.Column != "" {
+		location += ": " + e.Column
+	}
+	return location + ": " + e.Reason
+}
+
+// ImportReport lists the invalid records of an Import. A strict Import returns it as the error
+// after it left the Service untouched, a lenient one fills it through WithReport.
+type ImportReport struct {
+	Records	[]RecordError
+}
+
+func (r *ImportReport) Error() string {
+	switch len(r.Records) {
+	case 0:
+		return ErrInvalidRecords.Error()
+	case 1:
+		return fmt.Sprintf("%v: %s", ErrInvalidRecords, r.Records[0])
+	default:
+		return fmt.Sprintf("%d %v, first %s", len(r.Records), ErrInvalidRecords, r.Records[0])
+	}
+}
+
+func (r *ImportReport) Unwrap() error {
+	return ErrInvalidRecords
+}
+
+// Is matches the errors of the invalid records too, so the report of a record with
+// a wrong checksum is ErrChecksumMismatch.
+func (r *ImportReport) Is(target error) bool {
+	for _, record := range r.Records {
+		if errors.Is(record.err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// add reports the record at the line of the file as invalid because of err.
+func (r *ImportReport) add(file string, line int, err error) {
+	record := RecordError{File: file, Line: line, Reason: err.Error(), err: err}
+
+	var column *columnError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &column):
+		record.Column = column.column
+		record.Reason = column.err.Error()
+	case errors.As(err, &typeErr):
+		record.Column = typeErr.Field
+	}
+	r.Records = append(r.Records, record)
+}
+
+// reject reports the record at the line of the file when err is about the record
+// and returns other errors, like those of the storage, nil is no error.
+func (r *ImportReport) reject(file string, line int, err error) error {
+	if err == nil || !invalidRecord(err) {
+		return err
+	}
+	r.add(file, line, err)
+	return nil
+}
+
+func invalidRecord(err error) bool {
+	var column *columnError
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var parseErr *csv.ParseError
+	return errors.As(err, &column) || errors.As(err, &syntaxErr) || errors.As(err, &typeErr) || errors.As(err, &parseErr) ||
+		errors.Is(err, ErrMalformedRecord) || errors.Is(err, ErrChecksumMismatch)
+}
+
+// columnError is a record error caused by the value of one column.
+type columnError struct {
+	column	string
+	err		error
+}
+
+func (e *columnError) Error() string {
+	return "column " + e.column + ": " + e.err.Error()
+}
+
+func (e *columnError) Unwrap() error {
+	return e.err
+}
+
+// WithLenient makes Import skip invalid records and import the rest. By default Import is strict,
+// an invalid record fails it and leaves the Service untouched.
+func WithLenient() DumpOption {
+	return func(o *dumpOptions) {
+		o.lenient = true
+	}
+}
+
+// WithReport makes Import list the invalid records in report.
+func WithReport(report *ImportReport) DumpOption {
+	return func(o *dumpOptions) {
+		o.report = report
+	}
+}
+
+// importRecords lets read restore records into the Service, invalid records are reported and skipped.
+// A strict import reads into a staging copy of the Service and adds the staged records
+// to the Service only if every record is valid. The caller holds the lock.
+func (s *Service) importRecords(opts *dumpOptions, read func(s *Service, report *ImportReport) error) error {
+	report := opts.report
+	if report == nil {
+		report = &ImportReport{}
+	}
+	report.Records = nil
+
+	if opts.lenient {
+		return read(s, report)
+	}
+
+	staging, err := s.staging()
+	if err != nil {
+		return err
+	}
+	err = read(staging, report)
+	if err != nil {
+		return err
+	}
+	if len(report.Records) != 0 {
+		return report
+	}
+	return s.commit(staging)
+}
+
+// staging returns an in-memory Service with copies of the records of s.
+func (s *Service) staging() (*Service, error) {
+	staging := NewService(WithClock(s.now), WithIdempotencyWindow(s.idempotencyWindow), WithLimitLocation(s.location))
+
+	accounts, err := s.accounts.All()
+	if err != nil {
+		return nil, err
+	}
+	for _, account := range accounts {
+		err = staging.accounts.Save(copyAccount(account))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	payments, err := s.payments.All()
+	if err != nil {
+		return nil, err
+	}
+	for _, payment := range payments {
+		err = staging.payments.Save(copyPayment(payment))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	favorites, err := s.favorites.All()
+	if err != nil {
+		return nil, err
+	}
+	for _, favorite := range favorites {
+		err = staging.favorites.Save(copyFavorite(favorite))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for key, record := range s.idempotency {
+		staging.idempotency[key] = record
+	}
+	for accountID, limits := range s.limits {
+		staging.limits[accountID] = append([]SpendingLimit(nil), limits...)
+	}
+	for paymentID, transitions := range s.transitions {
+		staging.transitions[paymentID] = append([]types.PaymentTransition(nil), transitions...)
+	}
+	return staging, nil
+}
+
+// commit adds the records read into staging to s. Imports only add records,
+// so the records of staging s doesn't have are the imported ones.
+func (s *Service) commit(staging *Service) error {
+	accounts, err := staging.accounts.All()
+	if err != nil {
+		return err
+	}
+	for _, account := range accounts {
+		_, err = s.accounts.FindByID(account.ID)
+		if err == ErrAccountNotFound {
+			err = s.openAccount(copyAccount(account))
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	payments, err := staging.payments.All()
+	if err != nil {
+		return err
+	}
+	for _, payment := range payments {
+		_, err = s.payments.FindByID(payment.ID)
+		if err == ErrPaymentNotFound {
+			err = s.payments.Save(copyPayment(payment))
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	favorites, err := staging.favorites.All()
+	if err != nil {
+		return err
+	}
+	for _, favorite := range favorites {
+		_, err = s.favorites.FindByID(favorite.ID)
+		if err == ErrFavoriteNotFound {
+			err = s.favorites.Save(copyFavorite(favorite))
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for key, record := range staging.idempotency {
+		if _, ok := s.idempotency[key]; !ok {
+			s.idempotency[key] = record
+		}
+	}
+	for _, limits := range staging.limits {
+		for _, limit := range limits {
+			if !s.hasLimit(limit) {
+				s.setLimit(limit)
+			}
+		}
+	}
+	// staging started with the transitions of s, so its histories extend those of s
+	for paymentID, transitions := range staging.transitions {
+		s.transitions[paymentID] = transitions
+	}
+	return nil
+}
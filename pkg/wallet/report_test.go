@@ -0,0 +1,394 @@
+package wallet
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// location is where a record error is reported.
+type location struct {
+	line	int
+	column	string
+}
+
+func assertReported(t *testing.T, report *ImportReport, file string, want []location) {
+	t.Helper()
+	got := make([]location, 0, len(report.Records))
+	for _, record := range report.Records {
+		if record.File != file || record.Reason == "" {
+			t.Errorf("Import(): invalid report %v", record)
+		}
+		got = append(got, location{line: record.Line, column: record.Column})
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Import(): got reported %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Import(): got reported %v, want %v", got, want)
+		}
+	}
+}
+
+func writeInvalidAccounts(t *testing.T, dir string) {
+	t.Helper()
+	writeDump(t, dir, "accounts.dump",
+		"11;+992000000007;100",
+		"12;+992000000002",
+		"13;+992000000003;ten",
+		"14;abc;0",
+		"15;+992000000001;0",
+		";;;;;;;;",
+		"16;+992000000006;0;ACTIVE;x",
+	)
+}
+
+var invalidAccounts = []location{{2, ""}, {3, "balance"}, {4, "phone"}, {5, "phone"}, {6, "id"}, {7, "creditLimit"}}
+
+func TestService_Import_strictReport(t *testing.T) {
+	dir := t.TempDir()
+	writeInvalidAccounts(t, dir)
+	s := newTestService()
+	_, _, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, _ := s.Journal()
+
+	err = s.Import(dir)
+	var report *ImportReport
+	if !errors.As(err, &report) || !errors.Is(err, ErrInvalidRecords) || !errors.Is(err, ErrPhoneRegistered) {
+		t.Fatalf("Import(): must return an *ImportReport, returned = %v", err)
+	}
+	assertReported(t, report, "accounts.dump", invalidAccounts)
+
+	accounts, _ := s.accounts.All()
+	entries, _ := s.Journal()
+	if len(accounts) != 1 || len(entries) != len(before) {
+		t.Errorf("Import(): strict import must leave the wallet untouched, got %d accounts, %d entries", len(accounts), len(entries))
+	}
+}
+
+func TestService_Import_lenient(t *testing.T) {
+	dir := t.TempDir()
+	writeInvalidAccounts(t, dir)
+	s := newTestService()
+	_, _, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := &ImportReport{}
+	err = s.Import(dir, WithLenient(), WithReport(report))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertReported(t, report, "accounts.dump", invalidAccounts)
+
+	account, err := s.FindAccountByID(11)
+	if err != nil || account.Balance != 100 {
+		t.Errorf("Import(): valid records must be imported, got %v, %v", account, err)
+	}
+	accounts, _ := s.accounts.All()
+	if len(accounts) != 2 {
+		t.Errorf("Import(): got %d accounts, want 2", len(accounts))
+	}
+}
+
+func TestService_Import_lenientCorruptedLine(t *testing.T) {
+	dir := t.TempDir()
+	s := newTestService()
+	_, _, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.RegisterAccount("+992000000002")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Export(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flipByte(t, filepath.Join(currentGeneration(t, dir), "accounts.dump"), 2)
+
+	imported := NewService()
+	report := &ImportReport{}
+	err = imported.Import(dir, WithLenient(), WithReport(report))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the payment and its history are left without the corrupted account
+	want := []string{"accounts.dump", "accounts.dump", "payments.dump accountId", "transitions.dump paymentId"}
+	if len(report.Records) != len(want) {
+		t.Fatalf("Import(): got report %v, want %v", report.Records, want)
+	}
+	for i, record := range report.Records {
+		if got := strings.TrimSpace(record.File + " " + record.Column); got != want[i] {
+			t.Errorf("Import(): got reported %s, want %s", record, want[i])
+		}
+	}
+	if !report.Is(ErrCorruptedSnapshot) || !report.Is(ErrChecksumMismatch) {
+		t.Errorf("Import(): got report %v, want the file and its line", report.Records)
+	}
+	accounts, _ := imported.accounts.All()
+	if len(accounts) != 1 {
+		t.Errorf("Import(): got %d accounts, want the one with a valid checksum", len(accounts))
+	}
+
+	report = &ImportReport{}
+	err = NewService().Import(dir, WithReport(report))
+	if !errors.Is(err, ErrInvalidRecords) || len(report.Records) != len(want) {
+		t.Errorf("Import(): strict import must return the report, returned = %v, report = %v", err, report.Records)
+	}
+}
+
+func TestService_Import_invalidValues(t *testing.T) {
+	dir := t.TempDir()
+	writeDump(t, dir, "accounts.dump",
+		"1;+992000000001;100",
+		"2;+992000000002;0;OPEN",
+		"3;+992000000003;-10;ACTIVE;5",
+	)
+	writeDump(t, dir, "payments.dump",
+		"p1;1;10;food;OK",
+		"p2;1;10;food;DONE",
+		"p3;1;10;food;OK;GIFT;",
+		"p4;1;-10;food;OK",
+		"p5;1;10;food;REFUNDED;;;;;20",
+		"p6;7;10;food;OK",
+	)
+	writeDump(t, dir, "favorites.dump", "f1;1;osh;10;food", "f2;7;tea;10;food", "f3;1;taxi;0;food")
+	writeDump(t, dir, "limits.dump", "1;DAILY;;500", "1;DAILY;;-5", "77;DAILY;;5")
+
+	s := NewService()
+	report := &ImportReport{}
+	err := s.Import(dir, WithLenient(), WithReport(report))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"accounts.dump:2: status", "accounts.dump:3: balance",
+		"payments.dump:2: status", "payments.dump:3: kind", "payments.dump:4: amount", "payments.dump:5: refunded", "payments.dump:6: accountId",
+		"favorites.dump:2: accountId", "favorites.dump:3: amount",
+		"limits.dump:2: amount", "limits.dump:3: accountId",
+	}
+	if len(report.Records) != len(want) {
+		t.Fatalf("Import(): got report %v, want %v", report.Records, want)
+	}
+	for i, record := range report.Records {
+		if !strings.HasPrefix(record.String(), want[i] + ": ") {
+			t.Errorf("Import(): got reported %s, want %s", record, want[i])
+		}
+	}
+	limits, err := s.SpendingLimits(1)
+	if err != nil || len(limits) != 1 || limits[0].Amount != 500 || len(s.limits) != 1 {
+		t.Errorf("Import(): got limits %v, %v, want only the valid one", s.limits, err)
+	}
+
+	csvDir := t.TempDir()
+	err = os.WriteFile(filepath.Join(csvDir, "accounts.csv"), []byte("id,phone,balance\n1,+992000000001,100\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(csvDir, "payments.csv"), []byte(`id,accountId,amount,category,status,kind,refunded
+p1,1,10,food,OK,,0
+p2,1,10,food,done,,0
+p3,1,10,food,OK,,11
+p4,2,10,food,OK,,0
+`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report = &ImportReport{}
+	err = NewService().Import(csvDir, WithFormat(FormatCSV), WithReport(report))
+	if !errors.Is(err, ErrInvalidRecords) {
+		t.Fatalf("Import(): must return ErrInvalidRecords, returned = %v", err)
+	}
+	assertReported(t, report, "payments.csv", []location{{3, "status"}, {4, "refunded"}, {5, "accountId"}})
+}
+
+func TestService_Import_strictCommitsStaging(t *testing.T) {
+	dir := t.TempDir()
+	s := newTestService()
+	account, _, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := s.RegisterAccount("+992000000002")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.SetSpendingLimit(account.ID, LimitPeriodDaily, "", 5_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.DepositWithKey("deposit-1", other.ID, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Export(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	strict, lenient := NewService(), NewService()
+	err = strict.Import(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = lenient.Import(dir, WithLenient())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSameState(t, strict, lenient)
+
+	strictJournal, _ := strict.Journal()
+	lenientJournal, _ := lenient.Journal()
+	if len(strictJournal) != len(lenientJournal) || len(strictJournal) != 2 {
+		t.Errorf("Import(): got journal %v, want %v", strictJournal, lenientJournal)
+	}
+	if !reflect.DeepEqual(strict.limits, s.limits) || len(strict.idempotency) != 1 {
+		t.Errorf("Import(): got limits %v and %d idempotency records", strict.limits, len(strict.idempotency))
+	}
+}
+
+func TestService_Import_reportFormats(t *testing.T) {
+	tests := []struct{
+		name	string
+		format	Format
+		file	string
+		content	string
+		want	[]location
+	}{
+		{"json", FormatJSON, "wallet.json", `{
+  "accounts": [
+    {"id": 1, "phone": "+992000000001", "balance": 100},
+    {"id": 2, "phone": "+992000000002", "balance": "ten"}
+  ],
+  "payments": [{"id": "p1", "accountId": 1, "amount": 10, "category": "food", "status": "OK"}]
+}
+`, []location{{4, "balance"}}},
+		{"json unknown element", FormatJSON, "wallet.json", `{
+  "accounts": [{"id": 1, "phone": "+992000000001", "balance": 100}],
+  "limits": [{"accountId": 1, "period": "DAILY", "amount": 5}]
+}
+`, []location{{3, ""}}},
+		{"jsonl", FormatJSONLines, "accounts.jsonl", `{"id": 1, "phone": "+992000000001", "balance": 100}
+{"id": 2, "phone": "+992000000002", "balance":
+{"id": 3, "phone": "+992000000003", "balance": true}
+`, []location{{2, ""}, {3, "balance"}}},
+		{"csv", FormatCSV, "accounts.csv", `id,phone,balance
+1,+992000000001,100
+,+992000000002,0
+3,+992000000003,ten
+4,"+99200"0000004,0
+`, []location{{3, "id"}, {4, "balance"}, {5, ""}}},
+		{"csv multiline cell", FormatCSV, "accounts.csv", `id,phone,balance,note
+1,+992000000001,100,"first
+second"
+2,+992000000002,ten,
+
+3,abc,0,"a ""quoted""
+note"
+4,"+99200"0000004,0,
+`, []location{{4, "balance"}, {6, "phone"}, {8, ""}}},
+	}
+
+	for _, tt := range tests {
+		dir := t.TempDir()
+		err := os.WriteFile(filepath.Join(dir, tt.file), []byte(tt.content), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		s := NewService()
+		err = s.Import(dir, WithFormat(tt.format))
+		if !errors.Is(err, ErrInvalidRecords) {
+			t.Errorf("Import(%s): must return ErrInvalidRecords, returned = %v", tt.name, err)
+		}
+		accounts, _ := s.accounts.All()
+		if len(accounts) != 0 {
+			t.Errorf("Import(%s): strict import must leave the wallet untouched", tt.name)
+		}
+
+		report := &ImportReport{}
+		err = s.Import(dir, WithFormat(tt.format), WithLenient(), WithReport(report))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertReported(t, report, tt.file, tt.want)
+		if _, err = s.FindAccountByID(1); err != nil {
+			t.Errorf("Import(%s): valid records must be imported, returned = %v", tt.name, err)
+		}
+	}
+}
+
+func TestService_Import_jsonShape(t *testing.T) {
+	tests := []string{
+		`[1, {"a": 2}]`,
+		`{"accounts": 5, "payments": [{"id": "p1", "accountId": 1, "amount": 10}]}`,
+		`{"accounts": {"id": 1}}`,
+		`{"accounts": []} []`,
+		`"wallet"`,
+	}
+
+	for _, content := range tests {
+		dir := t.TempDir()
+		err := os.WriteFile(filepath.Join(dir, "wallet.json"), []byte(content), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		s := NewService()
+		err = s.Import(dir, WithFormat(FormatJSON), WithLenient())
+		if !errors.Is(err, ErrMalformedRecord) {
+			t.Errorf("Import(%s): must return ErrMalformedRecord, returned = %v", content, err)
+		}
+	}
+
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "wallet.json"), []byte(`{"accounts": null, "payments": [], "favorites": null}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = NewService().Import(dir, WithFormat(FormatJSON))
+	if err != nil {
+		t.Errorf("Import(): empty lists must be imported, returned = %v", err)
+	}
+}
+
+func TestService_ImportFromFile_report(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "export.txt")
+	err := os.WriteFile(path, []byte("1;+992000000001;0|2;+992000000002|3;abc;0|"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewService()
+	err = s.ImportFromFile(path)
+	var report *ImportReport
+	if !errors.As(err, &report) {
+		t.Fatalf("ImportFromFile(): must return an *ImportReport, returned = %v", err)
+	}
+	assertReported(t, report, "export.txt", []location{{2, ""}, {3, "phone"}})
+	accounts, _ := s.accounts.All()
+	if len(accounts) != 0 {
+		t.Errorf("ImportFromFile(): strict import must leave the wallet untouched, got %d accounts", len(accounts))
+	}
+
+	err = s.ImportFromFile(path, WithLenient())
+	if err != nil {
+		t.Fatal(err)
+	}
+	accounts, _ = s.accounts.All()
+	if len(accounts) != 1 {
+		t.Errorf("ImportFromFile(): got %d accounts, want 1", len(accounts))
+	}
+}
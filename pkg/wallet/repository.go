@@ -0,0 +1,34 @@
+package wallet
+
+import (
+	"github.com/aminjonshermatov/wallet/pkg/types"
+)
+
+// AccountRepository stores accounts for the Service.
+//
+// Find* return copies, changes are stored only by Save.
+// All may share the repository storage, its result must be treated as read-only.
+type AccountRepository interface {
+	NextID() (int64, error)
+	Save(account *types.Account) error
+	FindByID(id int64) (*types.Account, error)
+	FindByPhone(phone types.Phone) (*types.Account, error)
+	All() ([]*types.Account, error)
+}
+
+// PaymentRepository stores payments for the Service in insertion order.
+type PaymentRepository interface {
+	Save(payment *types.Payment) error
+	FindByID(id string) (*types.Payment, error)
+	FindByAccountID(accountID int64) ([]*types.Payment, error)
+	All() ([]*types.Payment, error)
+}
+
+// FavoriteRepository stores favorites for the Service in insertion order.
+type FavoriteRepository interface {
+	Save(favorite *types.Favorite) error
+	Delete(id string) error
+	FindByID(id string) (*types.Favorite, error)
+	FindByAccountID(accountID int64) ([]*types.Favorite, error)
+	All() ([]*types.Favorite, error)
+}
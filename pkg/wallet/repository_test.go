@@ -0,0 +1,438 @@
+package wallet
+
+import (
+	"github.com/aminjonshermatov/wallet/pkg/types"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type testBackend struct {
+	name		string
+	accounts	func(t *testing.T) AccountRepository
+	payments	func(t *testing.T) PaymentRepository
+	favorites	func(t *testing.T) FavoriteRepository
+}
+
+var testBackends = []testBackend{
+	{
+		name: "memory",
+		accounts: func(t *testing.T) AccountRepository {
+			return NewMemoryAccountRepository()
+		},
+		payments: func(t *testing.T) PaymentRepository {
+			return NewMemoryPaymentRepository()
+		},
+		favorites: func(t *testing.T) FavoriteRepository {
+			return NewMemoryFavoriteRepository()
+		},
+	},
+	{
+		name: "file",
+		accounts: func(t *testing.T) AccountRepository {
+			repo, err := NewFileAccountRepository(filepath.Join(t.TempDir(), "accounts.dump"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { _ = repo.Close() })
+			return repo
+		},
+		payments: func(t *testing.T) PaymentRepository {
+			repo, err := NewFilePaymentRepository(filepath.Join(t.TempDir(), "payments.dump"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { _ = repo.Close() })
+			return repo
+		},
+		favorites: func(t *testing.T) FavoriteRepository {
+			repo, err := NewFileFavoriteRepository(filepath.Join(t.TempDir(), "favorites.dump"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { _ = repo.Close() })
+			return repo
+		},
+	},
+}
+
+func TestRepositories_conformance(t *testing.T) {
+	for _, backend := range testBackends {
+		backend := backend
+		t.Run(backend.name + "/accounts", func(t *testing.T) {
+			testAccountRepository(t, backend.accounts(t))
+		})
+		t.Run(backend.name + "/payments", func(t *testing.T) {
+			testPaymentRepository(t, backend.payments(t))
+		})
+		t.Run(backend.name + "/favorites", func(t *testing.T) {
+			testFavoriteRepository(t, backend.favorites(t))
+		})
+		t.Run(backend.name + "/service", func(t *testing.T) {
+			testServiceOnRepositories(t, NewService(
+				WithAccountRepository(backend.accounts(t)),
+				WithPaymentRepository(backend.payments(t)),
+				WithFavoriteRepository(backend.favorites(t)),
+			))
+		})
+	}
+}
+
+func testAccountRepository(t *testing.T, repo AccountRepository) {
+	_, err := repo.FindByID(1)
+	if err != ErrAccountNotFound {
+		t.Fatalf("FindByID(): must return ErrAccountNotFound, returned = %v", err)
+	}
+	_, err = repo.FindByPhone("+992000000001")
+	if err != ErrAccountNotFound {
+		t.Fatalf("FindByPhone(): must return ErrAccountNotFound, returned = %v", err)
+	}
+
+	for i, phone := range []types.Phone{"+992000000001", "+992000000002"} {
+		id, err := repo.NextID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id != int64(i + 1) {
+			t.Fatalf("NextID(): got %v, want %v", id, i + 1)
+		}
+
+		err = repo.Save(&types.Account{ID: id, Phone: phone, Balance: types.Money(i * 100)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	account, err := repo.FindByID(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := types.Account{ID: 2, Phone: "+992000000002", Balance: 100}
+	if *account != want {
+		t.Fatalf("FindByID(): got %v, want %v", *account, want)
+	}
+
+	account.Balance = 500
+	unsaved, err := repo.FindByID(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unsaved.Balance != 100 {
+		t.Fatalf("FindByID(): must return a copy, got %v", *unsaved)
+	}
+	err = repo.Save(account)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	account, err = repo.FindByPhone("+992000000002")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.Balance != 500 {
+		t.Fatalf("Save(): update is not visible, got %v", *account)
+	}
+
+	account.Phone = "+992000000003"
+	err = repo.Save(account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = repo.FindByPhone("+992000000002")
+	if err != ErrAccountNotFound {
+		t.Fatalf("FindByPhone(): old phone must not be found, returned = %v", err)
+	}
+	account, err = repo.FindByPhone("+992000000003")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.ID != 2 {
+		t.Fatalf("FindByPhone(): got %v", *account)
+	}
+
+	accounts, err := repo.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accounts) != 2 || accounts[0].ID != 1 || accounts[1].ID != 2 {
+		t.Fatalf("All(): got %v", accounts)
+	}
+}
+
+func testPaymentRepository(t *testing.T, repo PaymentRepository) {
+	_, err := repo.FindByID("missing")
+	if err != ErrPaymentNotFound {
+		t.Fatalf("FindByID(): must return ErrPaymentNotFound, returned = %v", err)
+	}
+
+	payments := []types.Payment{
+		{ID: "a", AccountID: 1, Amount: 10, Category: "auto", Status: types.PaymentStatusInProgress},
+		{ID: "b", AccountID: 2, Amount: 20, Category: "food", Status: types.PaymentStatusInProgress},
+		{ID: "c", AccountID: 1, Amount: 30, Category: "food", Status: types.PaymentStatusInProgress},
+	}
+	for i := range payments {
+		err = repo.Save(&payments[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	payment, err := repo.FindByID("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *payment != payments[1] {
+		t.Fatalf("FindByID(): got %v, want %v", *payment, payments[1])
+	}
+
+	payment.Status = types.PaymentStatusFail
+	err = repo.Save(payment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payment, err = repo.FindByID("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payment.Status != types.PaymentStatusFail {
+		t.Fatalf("Save(): update is not visible, got %v", *payment)
+	}
+
+	byAccount, err := repo.FindByAccountID(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(byAccount) != 2 || byAccount[0].ID != "a" || byAccount[1].ID != "c" {
+		t.Fatalf("FindByAccountID(): got %v", byAccount)
+	}
+
+	payment, err = repo.FindByID("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	payment.AccountID = 1
+	err = repo.Save(payment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byAccount, err = repo.FindByAccountID(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(byAccount) != 3 || byAccount[0].ID != "a" || byAccount[1].ID != "b" || byAccount[2].ID != "c" {
+		t.Fatalf("FindByAccountID(): got %v", byAccount)
+	}
+	byAccount, err = repo.FindByAccountID(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(byAccount) != 0 {
+		t.Fatalf("FindByAccountID(): must be empty after move, got %v", byAccount)
+	}
+
+	byAccount, err = repo.FindByAccountID(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(byAccount) != 0 {
+		t.Fatalf("FindByAccountID(): must be empty, got %v", byAccount)
+	}
+
+	all, err := repo.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]string, 0)
+	for _, payment := range all {
+		ids = append(ids, payment.ID)
+	}
+	if !reflect.DeepEqual(ids, []string{"a", "b", "c"}) {
+		t.Fatalf("All(): got %v", ids)
+	}
+}
+
+func testFavoriteRepository(t *testing.T, repo FavoriteRepository) {
+	_, err := repo.FindByID("missing")
+	if err != ErrFavoriteNotFound {
+		t.Fatalf("FindByID(): must return ErrFavoriteNotFound, returned = %v", err)
+	}
+
+	favorites := []types.Favorite{
+		{ID: "a", AccountID: 1, Name: "osh", Amount: 10, Category: "food"},
+		{ID: "b", AccountID: 2, Name: "taxi", Amount: 20, Category: "auto"},
+	}
+	for i := range favorites {
+		err = repo.Save(&favorites[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	favorite, err := repo.FindByID("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *favorite != favorites[0] {
+		t.Fatalf("FindByID(): got %v, want %v", *favorite, favorites[0])
+	}
+
+	favorite.Name = "plov"
+	err = repo.Save(favorite)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	byAccount, err := repo.FindByAccountID(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(byAccount) != 1 || byAccount[0].Name != "plov" {
+		t.Fatalf("FindByAccountID(): got %v", byAccount)
+	}
+
+	all, err := repo.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 || all[0].ID != "a" || all[1].ID != "b" {
+		t.Fatalf("All(): got %v", all)
+	}
+	err = repo.Delete("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = repo.Delete("a")
+	if err != ErrFavoriteNotFound {
+		t.Fatalf("Delete(): must return ErrFavoriteNotFound, returned = %v", err)
+	}
+	_, err = repo.FindByID("a")
+	if err != ErrFavoriteNotFound {
+		t.Fatalf("FindByID(): must return ErrFavoriteNotFound after Delete, returned = %v", err)
+	}
+	all, err = repo.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 1 || all[0].ID != "b" {
+		t.Fatalf("All(): after Delete got %v", all)
+	}
+}
+
+func testServiceOnRepositories(t *testing.T, svc *Service) {
+	s := &testService{Service: svc}
+
+	account, payments, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.RegisterAccount(defaultTestAccount.phone)
+	if err != ErrPhoneRegistered {
+		t.Fatalf("RegisterAccount(): must return ErrPhoneRegistered, returned = %v", err)
+	}
+
+	favorite, err := s.FavoritePayment(payments[0].ID, "osh")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.PayFromFavorite(favorite.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.Reject(payments[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.FindAccountByID(account.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := defaultTestAccount.balance - favorite.Amount
+	if got.Balance != want {
+		t.Fatalf("invalid balance, got %v, want %v", got.Balance, want)
+	}
+
+	history, err := s.ExportAccountHistory(account.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[0].Status != types.PaymentStatusFail {
+		t.Fatalf("invalid history, got %v", history)
+	}
+}
+
+func TestFileRepositories_reopen(t *testing.T) {
+	dir := t.TempDir()
+
+	open := func() (*FileAccountRepository, *FilePaymentRepository, *FileFavoriteRepository) {
+		accounts, err := NewFileAccountRepository(filepath.Join(dir, "accounts.dump"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		payments, err := NewFilePaymentRepository(filepath.Join(dir, "payments.dump"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		favorites, err := NewFileFavoriteRepository(filepath.Join(dir, "favorites.dump"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return accounts, payments, favorites
+	}
+
+	accounts, payments, favorites := open()
+	s := &testService{Service: NewService(
+		WithAccountRepository(accounts),
+		WithPaymentRepository(payments),
+		WithFavoriteRepository(favorites),
+	)}
+	account, paid, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	favorite, err := s.FavoritePayment(paid[0].ID, "osh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, closer := range []interface{ Close() error }{accounts, payments, favorites} {
+		if err := closer.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	accounts, payments, favorites = open()
+	defer func() {
+		_ = accounts.Close()
+		_ = payments.Close()
+		_ = favorites.Close()
+	}()
+	reopened := NewService(
+		WithAccountRepository(accounts),
+		WithPaymentRepository(payments),
+		WithFavoriteRepository(favorites),
+	)
+
+	got, err := reopened.FindAccountByID(account.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Balance != defaultTestAccount.balance - paid[0].Amount {
+		t.Errorf("invalid balance after reopen, got %v", got.Balance)
+	}
+
+	_, err = reopened.PayFromFavorite(favorite.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	next, err := reopened.RegisterAccount("+992000000002")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.ID != account.ID + 1 {
+		t.Errorf("invalid next account id, got %v, want %v", next.ID, account.ID + 1)
+	}
+}
@@ -0,0 +1,302 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var ErrScheduleNotFound = errors.New("schedule not found")
+var ErrInvalidScheduleDay = errors.New("day of month must be from 1 to 31")
+
+// ScheduleFrequency tells how often a Schedule pays its favorite.
+type ScheduleFrequency string
+
+const (
+	ScheduleOnce	ScheduleFrequency = "ONCE"
+	ScheduleDaily	ScheduleFrequency = "DAILY"
+	ScheduleWeekly	ScheduleFrequency = "WEEKLY"
+	ScheduleMonthly	ScheduleFrequency = "MONTHLY"
+)
+
+// Schedule pays a favorite at Due and, unless it is ScheduleOnce, at the following occurrences.
+// Next is when the scheduler tries to pay: Due, or a later time while retrying.
+type Schedule struct {
+	ID			string
+	FavoriteID	string
+	Frequency	ScheduleFrequency
+	Day			int
+	Due			time.Time
+	Next		time.Time
+	Attempt		int
+	Active		bool
+}
+
+// ScheduleRun is one attempt to pay a scheduled occurrence.
+// Error is empty and PaymentID is set when the attempt succeeded.
+type ScheduleRun struct {
+	ScheduleID	string
+	Due			time.Time
+	At			time.Time
+	Attempt		int
+	PaymentID	string
+	Error		string
+}
+
+// RetryPolicy makes the scheduler try again after Delay when the account has not enough balance,
+// up to MaxAttempts attempts per occurrence.
+type RetryPolicy struct {
+	MaxAttempts	int
+	Delay		time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 3, Delay: time.Hour}
+
+// Scheduler pays favorites of the Service on schedule.
+// It keeps schedules in memory, RunDue or Run must be called to make the payments.
+type Scheduler struct {
+	mu			sync.Mutex
+	service		*Service
+	now			func() time.Time
+	retry		RetryPolicy
+	schedules	[]*Schedule
+	byID		map[string]*Schedule
+	runs		map[string][]ScheduleRun
+}
+
+type SchedulerOption func(s *Scheduler)
+
+// WithSchedulerClock makes the Scheduler decide what is due by now instead of the Service clock.
+func WithSchedulerClock(now func() time.Time) SchedulerOption {
+	return func(s *Scheduler) {
+		s.now = now
+	}
+}
+
+func WithRetryPolicy(retry RetryPolicy) SchedulerOption {
+	return func(s *Scheduler) {
+		s.retry = retry
+	}
+}
+
+func NewScheduler(service *Service, options ...SchedulerOption) *Scheduler {
+	service.init()
+	s := &Scheduler{
+		service:	service,
+		now:		service.now,
+		retry:		DefaultRetryPolicy,
+		byID:		make(map[string]*Schedule),
+		runs:		make(map[string][]ScheduleRun),
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// ScheduleOnce pays the favorite once at the given time.
+func (s *Scheduler) ScheduleOnce(favoriteID string, at time.Time) (*Schedule, error) {
+	return s.add(favoriteID, ScheduleOnce, 0, at)
+}
+
+// ScheduleDaily pays the favorite every day starting at first.
+func (s *Scheduler) ScheduleDaily(favoriteID string, first time.Time) (*Schedule, error) {
+	return s.add(favoriteID, ScheduleDaily, 0, first)
+}
+
+// ScheduleWeekly pays the favorite every week starting at first.
+func (s *Scheduler) ScheduleWeekly(favoriteID string, first time.Time) (*Schedule, error) {
+	return s.add(favoriteID, ScheduleWeekly, 0, first)
+}
+
+// ScheduleMonthly pays the favorite every month on the given day at the time of day of from,
+// starting with the first such day not before from. In shorter months the last day is used.
+func (s *Scheduler) ScheduleMonthly(favoriteID string, day int, from time.Time) (*Schedule, error) {
+	if day < 1 || day > 31 {
+		return nil, ErrInvalidScheduleDay
+	}
+
+	first := monthlyOccurrence(from, day, 0)
+	if first.Before(from) {
+		first = monthlyOccurrence(from, day, 1)
+	}
+	return s.add(favoriteID, ScheduleMonthly, day, first)
+}
+
+func (s *Scheduler) add(favoriteID string, frequency ScheduleFrequency, day int, first time.Time) (*Schedule, error) {
+	_, err := s.service.FindFavoriteByID(favoriteID)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule := &Schedule{
+		ID:			uuid.New().String(),
+		FavoriteID:	favoriteID,
+		Frequency:	frequency,
+		Day:		day,
+		Due:		first,
+		Next:		first,
+		Active:		true,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.schedules = append(s.schedules, schedule)
+	s.byID[schedule.ID] = schedule
+
+	clone := *schedule
+	return &clone, nil
+}
+
+// Cancel stops the schedule, its run history is kept.
+func (s *Scheduler) Cancel(scheduleID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	schedule, ok := s.byID[scheduleID]
+	if !ok {
+		return ErrScheduleNotFound
+	}
+
+	schedule.Active = false
+	return nil
+}
+
+func (s *Scheduler) FindScheduleByID(scheduleID string) (*Schedule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	schedule, ok := s.byID[scheduleID]
+	if !ok {
+		return nil, ErrScheduleNotFound
+	}
+
+	clone := *schedule
+	return &clone, nil
+}
+
+// Schedules returns all schedules in the order they were created.
+func (s *Scheduler) Schedules() []Schedule {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res := make([]Schedule, 0, len(s.schedules))
+	for _, schedule := range s.schedules {
+		res = append(res, *schedule)
+	}
+	return res
+}
+
+// Runs returns attempts made for the schedule, oldest first.
+func (s *Scheduler) Runs(scheduleID string) ([]ScheduleRun, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.byID[scheduleID]; !ok {
+		return nil, ErrScheduleNotFound
+	}
+
+	res := make([]ScheduleRun, len(s.runs[scheduleID]))
+	copy(res, s.runs[scheduleID])
+	return res, nil
+}
+
+// RunDue pays every active schedule whose Next time has come and returns the attempts made.
+// Occurrences missed while the scheduler was not running are paid once.
+func (s *Scheduler) RunDue() []ScheduleRun {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	res := make([]ScheduleRun, 0)
+	for _, schedule := range s.schedules {
+		if !schedule.Active || schedule.Next.After(now) {
+			continue
+		}
+
+		run := s.pay(schedule, now)
+		s.runs[schedule.ID] = append(s.runs[schedule.ID], run)
+		res = append(res, run)
+	}
+	return res
+}
+
+// Run calls RunDue every interval until the context is done.
+func (s *Scheduler) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.RunDue()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) pay(schedule *Schedule, now time.Time) ScheduleRun {
+	schedule.Attempt++
+	run := ScheduleRun{
+		ScheduleID:	schedule.ID,
+		Due:		schedule.Due,
+		At:			now,
+		Attempt:	schedule.Attempt,
+	}
+
+	// the key makes a repeated attempt for a paid occurrence return the same payment
+	key := "schedule-" + schedule.ID + "-" + strconv.FormatInt(schedule.Due.UnixNano(), 10)
+	payment, err := s.service.PayFromFavoriteWithKey(key, schedule.FavoriteID)
+	if err == nil {
+		run.PaymentID = payment.ID
+		s.advance(schedule, now)
+		return run
+	}
+
+	run.Error = err.Error()
+	if err == ErrNotEnoughBalance && schedule.Attempt < s.retry.MaxAttempts {
+		schedule.Next = now.Add(s.retry.Delay)
+		return run
+	}
+
+	s.advance(schedule, now)
+	return run
+}
+
+// advance moves the schedule to its first occurrence after now.
+func (s *Scheduler) advance(schedule *Schedule, now time.Time) {
+	schedule.Attempt = 0
+	if schedule.Frequency == ScheduleOnce {
+		schedule.Active = false
+		return
+	}
+
+	for !schedule.Due.After(now) {
+		switch schedule.Frequency {
+		case ScheduleDaily:
+			schedule.Due = schedule.Due.AddDate(0, 0, 1)
+		case ScheduleWeekly:
+			schedule.Due = schedule.Due.AddDate(0, 0, 7)
+		case ScheduleMonthly:
+			schedule.Due = monthlyOccurrence(schedule.Due, schedule.Day, 1)
+		}
+	}
+	schedule.Next = schedule.Due
+}
+
+// monthlyOccurrence returns the day of the month months after the month of t,
+// keeping the time of day of t. The day is limited by the length of that month.
+func monthlyOccurrence(t time.Time, day int, months int) time.Time {
+	year, month, _ := t.Date()
+	first := time.Date(year, month + time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	last := first.AddDate(0, 1, -1).Day()
+	if day > last {
+		day = last
+	}
+	return first.AddDate(0, 0, day - 1)
+}
@@ -0,0 +1,288 @@
+package wallet
+
+import (
+	"context"
+	"github.com/aminjonshermatov/wallet/pkg/types"
+	"testing"
+	"time"
+)
+
+// addScheduledFavorite registers an account with a favorite paying amount,
+// the account balance is enough for exactly one payment.
+func (s *testService) addScheduledFavorite(t *testing.T, amount types.Money) (int64, string) {
+	account, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Deposit(account.ID, amount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payment, err := s.Pay(account.ID, amount, "phone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Reject(payment.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	favorite, err := s.FavoritePayment(payment.ID, "phone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return account.ID, favorite.ID
+}
+
+func TestScheduler_ScheduleOnce(t *testing.T) {
+	clock := newTestClock()
+	s := &testService{Service: NewService(WithClock(clock.Now))}
+	accountID, favoriteID := s.addScheduledFavorite(t, 100)
+	scheduler := NewScheduler(s.Service)
+
+	schedule, err := scheduler.ScheduleOnce(favoriteID, clock.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("ScheduleOnce(): error = %v", err)
+	}
+
+	if runs := scheduler.RunDue(); len(runs) != 0 {
+		t.Fatalf("RunDue(): nothing is due yet, got %v", runs)
+	}
+
+	clock.Advance(time.Hour)
+	runs := scheduler.RunDue()
+	if len(runs) != 1 || runs[0].PaymentID == "" || runs[0].Error != "" {
+		t.Fatalf("RunDue(): got %v", runs)
+	}
+	s.checkBalance(t, accountID, 0)
+
+	clock.Advance(24 * time.Hour)
+	if runs := scheduler.RunDue(); len(runs) != 0 {
+		t.Errorf("RunDue(): one-off schedule must run once, got %v", runs)
+	}
+
+	got, err := scheduler.FindScheduleByID(schedule.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Active {
+		t.Errorf("FindScheduleByID(): one-off schedule must be inactive after run, got %v", got)
+	}
+
+	_, err = scheduler.ScheduleOnce("missing", clock.Now())
+	if err != ErrFavoriteNotFound {
+		t.Errorf("ScheduleOnce(): must return ErrFavoriteNotFound, returned = %v", err)
+	}
+}
+
+func TestScheduler_ScheduleDaily(t *testing.T) {
+	clock := newTestClock()
+	s := &testService{Service: NewService(WithClock(clock.Now))}
+	accountID, favoriteID := s.addScheduledFavorite(t, 100)
+	err := s.Deposit(accountID, 1_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scheduler := NewScheduler(s.Service)
+
+	schedule, err := scheduler.ScheduleDaily(favoriteID, clock.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for day := 0; day < 3; day++ {
+		if runs := scheduler.RunDue(); len(runs) != 1 || runs[0].Error != "" {
+			t.Fatalf("RunDue(): day %d got %v", day, runs)
+		}
+		if runs := scheduler.RunDue(); len(runs) != 0 {
+			t.Fatalf("RunDue(): day %d must run once, got %v", day, runs)
+		}
+		clock.Advance(24 * time.Hour)
+	}
+	s.checkBalance(t, accountID, 800)
+
+	// missed days are paid once
+	clock.Advance(3 * 24 * time.Hour)
+	if runs := scheduler.RunDue(); len(runs) != 1 {
+		t.Fatalf("RunDue(): got %v", runs)
+	}
+	got, err := scheduler.FindScheduleByID(schedule.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Due.Equal(clock.Now().Add(24 * time.Hour)) {
+		t.Errorf("FindScheduleByID(): next occurrence must be tomorrow, got %v", got.Due)
+	}
+
+	err = scheduler.Cancel(schedule.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(24 * time.Hour)
+	if runs := scheduler.RunDue(); len(runs) != 0 {
+		t.Errorf("RunDue(): canceled schedule must not run, got %v", runs)
+	}
+}
+
+func TestScheduler_ScheduleMonthly(t *testing.T) {
+	start := time.Date(2021, 1, 15, 9, 0, 0, 0, time.UTC)
+	clock := newTestClock()
+	clock.Set(start)
+	s := &testService{Service: NewService(WithClock(clock.Now))}
+	accountID, favoriteID := s.addScheduledFavorite(t, 100)
+	err := s.Deposit(accountID, 1_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scheduler := NewScheduler(s.Service)
+
+	_, err = scheduler.ScheduleMonthly(favoriteID, 0, start)
+	if err != ErrInvalidScheduleDay {
+		t.Errorf("ScheduleMonthly(): must return ErrInvalidScheduleDay, returned = %v", err)
+	}
+
+	schedule, err := scheduler.ScheduleMonthly(favoriteID, 31, start)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []time.Time{
+		time.Date(2021, 1, 31, 9, 0, 0, 0, time.UTC),
+		time.Date(2021, 2, 28, 9, 0, 0, 0, time.UTC),
+		time.Date(2021, 3, 31, 9, 0, 0, 0, time.UTC),
+		time.Date(2021, 4, 30, 9, 0, 0, 0, time.UTC),
+	}
+	for _, due := range want {
+		got, err := scheduler.FindScheduleByID(schedule.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Due.Equal(due) {
+			t.Fatalf("FindScheduleByID(): got due %v, want %v", got.Due, due)
+		}
+		clock.Set(due)
+		if runs := scheduler.RunDue(); len(runs) != 1 || runs[0].Error != "" {
+			t.Fatalf("RunDue(): got %v", runs)
+		}
+	}
+
+	schedule, err = scheduler.ScheduleMonthly(favoriteID, 1, time.Date(2021, 5, 1, 9, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !schedule.Due.Equal(time.Date(2021, 5, 1, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("ScheduleMonthly(): first occurrence may be the start itself, got %v", schedule.Due)
+	}
+}
+
+func TestScheduler_retry(t *testing.T) {
+	clock := newTestClock()
+	s := &testService{Service: NewService(WithClock(clock.Now))}
+	accountID, favoriteID := s.addScheduledFavorite(t, 100)
+	scheduler := NewScheduler(s.Service, WithRetryPolicy(RetryPolicy{MaxAttempts: 3, Delay: time.Hour}))
+
+	schedule, err := scheduler.ScheduleDaily(favoriteID, clock.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := clock.Now()
+
+	// the first day is paid, the second one fails three times and is skipped
+	if runs := scheduler.RunDue(); len(runs) != 1 || runs[0].Error != "" {
+		t.Fatalf("RunDue(): got %v", runs)
+	}
+	clock.Advance(24 * time.Hour)
+	for attempt := 1; attempt <= 3; attempt++ {
+		runs := scheduler.RunDue()
+		if len(runs) != 1 || runs[0].Error != ErrNotEnoughBalance.Error() || runs[0].Attempt != attempt {
+			t.Fatalf("RunDue(): attempt %d got %v", attempt, runs)
+		}
+		if runs := scheduler.RunDue(); len(runs) != 0 {
+			t.Fatalf("RunDue(): retry must wait for the delay, got %v", runs)
+		}
+		clock.Advance(time.Hour)
+	}
+
+	got, err := scheduler.FindScheduleByID(schedule.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Due.Equal(first.Add(48 * time.Hour)) || got.Attempt != 0 {
+		t.Fatalf("FindScheduleByID(): must move to the next day after the last attempt, got %v", got)
+	}
+
+	// the third day is paid on retry after a deposit
+	clock.Set(got.Due)
+	if runs := scheduler.RunDue(); len(runs) != 1 || runs[0].Error == "" {
+		t.Fatalf("RunDue(): got %v", runs)
+	}
+	err = s.Deposit(accountID, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(time.Hour)
+	runs := scheduler.RunDue()
+	if len(runs) != 1 || runs[0].PaymentID == "" || runs[0].Attempt != 2 {
+		t.Fatalf("RunDue(): retry must pay, got %v", runs)
+	}
+	s.checkBalance(t, accountID, 0)
+
+	history, err := scheduler.Runs(schedule.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 6 {
+		t.Errorf("Runs(): got %d runs, want 6: %v", len(history), history)
+	}
+	if !history[5].Due.Equal(first.Add(48 * time.Hour)) || history[5].PaymentID != runs[0].PaymentID {
+		t.Errorf("Runs(): invalid last run %v", history[5])
+	}
+
+	_, err = scheduler.Runs("missing")
+	if err != ErrScheduleNotFound {
+		t.Errorf("Runs(): must return ErrScheduleNotFound, returned = %v", err)
+	}
+}
+
+func TestScheduler_otherErrorsAreNotRetried(t *testing.T) {
+	clock := newTestClock()
+	s := &testService{Service: NewService(WithClock(clock.Now))}
+	accountID, favoriteID := s.addScheduledFavorite(t, 100)
+	scheduler := NewScheduler(s.Service)
+
+	_, err := scheduler.ScheduleOnce(favoriteID, clock.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Freeze(accountID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runs := scheduler.RunDue()
+	if len(runs) != 1 || runs[0].Error != ErrAccountFrozen.Error() {
+		t.Fatalf("RunDue(): got %v", runs)
+	}
+	clock.Advance(24 * time.Hour)
+	if runs := scheduler.RunDue(); len(runs) != 0 {
+		t.Errorf("RunDue(): must not retry, got %v", runs)
+	}
+}
+
+func TestScheduler_Run(t *testing.T) {
+	s := newTestService()
+	accountID, favoriteID := s.addScheduledFavorite(t, 100)
+	scheduler := NewScheduler(s.Service)
+
+	_, err := scheduler.ScheduleOnce(favoriteID, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = scheduler.Run(ctx, time.Millisecond)
+	if err != context.Canceled {
+		t.Errorf("Run(): must return context.Canceled, returned = %v", err)
+	}
+	s.checkBalance(t, accountID, 0)
+}
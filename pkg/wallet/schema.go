@@ -0,0 +1,361 @@
+package wallet
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var ErrUnsupportedDumpVersion = errors.New("unsupported dump version")
+
+// DumpVersion is the version of the dump files Export writes.
+// Version 1 files have no header, their columns are positional and later columns may be missing.
+// Since version 2 every file starts with a header line naming its version and columns,
+// records are read by column name so columns added later are empty in older files.
+const DumpVersion = 2
+
+const dumpHeaderPrefix = "#v"
+
+// dumpSchema is the current layout of a dump file.
+type dumpSchema struct {
+	columns		[]string
+	// required is the number of leading columns every version has.
+	required	int
+	// upgrade decodes a record in the current column order and encodes it again.
+	upgrade		func(record string) (string, error)
+}
+
+var dumpFiles = []string{"accounts.dump", "payments.dump", "favorites.dump", "idempotency.dump", "limits.dump", "transitions.dump"}
+
+var dumpSchemas = map[string]*dumpSchema{
+	"accounts.dump": {columns: accountColumns, required: 3, upgrade: func(record string) (string, error) {
+		account, err := decodeAccount(record)
+		if err != nil {
+			return "", err
+		}
+		return encodeAccount(account), nil
+	}},
+	"payments.dump": {columns: paymentColumns, required: 5, upgrade: func(record string) (string, error) {
+		payment, err := decodePayment(record)
+		if err != nil {
+			return "", err
+		}
+		return encodePayment(payment), nil
+	}},
+	"favorites.dump": {columns: favoriteColumns, required: 5, upgrade: func(record string) (string, error) {
+		favorite, err := decodeFavorite(record)
+		if err != nil {
+			return "", err
+		}
+		return encodeFavorite(favorite), nil
+	}},
+	"idempotency.dump": {columns: []string{"key", "request", "paymentId", "createdAt"}, required: 4, upgrade: func(record string) (string, error) {
+		idempotency, err := decodeIdempotencyRecord(record)
+		if err != nil {
+			return "", err
+		}
+		return encodeIdempotencyRecord(idempotency), nil
+	}},
+	"limits.dump": {columns: []string{"accountId", "period", "category", "amount"}, required: 4, upgrade: func(record string) (string, error) {
+		limit, err := decodeLimit(record)
+		if err != nil {
+			return "", err
+		}
+		return encodeLimit(limit), nil
+	}},
+	"transitions.dump": {columns: []string{"paymentId", "from", "to", "at"}, required: 4, upgrade: func(record string) (string, error) {
+		transition, err := decodeTransition(record)
+		if err != nil {
+			return "", err
+		}
+		return encodeTransition(transition), nil
+	}},
+}
+
+// dumpHeader returns the sealed header line of the dump file, without the line break.
+func dumpHeader(name string) string {
+	return sealRecord(dumpHeaderPrefix + strconv.Itoa(DumpVersion) + ";" + strings.Join(dumpSchemas[name].columns, ";"))
+}
+
+// dumpReader checks the lines of one dump file and returns its records in the current column order.
+type dumpReader struct {
+	checker	recordChecker
+	schema	*dumpSchema
+	version	int
+	// positions are the columns of the file record in the current column order, -1 for a missing column.
+	positions	[]int
+}
+
+func newDumpReader(name string) *dumpReader {
+	return &dumpReader{schema: dumpSchemas[name], version: 1}
+}
+
+// read returns the record of the line, header is true for the header line which has no record.
+func (r *dumpReader) read(line string) (record string, header bool, err error) {
+	line, err = r.checker.open(line)
+	if err != nil {
+		return "", false, err
+	}
+
+	if r.checker.line == 1 && strings.HasPrefix(line, dumpHeaderPrefix) {
+		return "", true, r.readHeader(line)
+	}
+
+	switch r.version {
+	case 1:
+		return line, false, nil
+	default:
+		col := strings.Split(line, ";")
+		current := make([]string, len(r.positions))
+		for i, position := range r.positions {
+			if position >= 0 && position < len(col) {
+				current[i] = col[position]
+			}
+		}
+		return strings.Join(current, ";"), false, nil
+	}
+}
+
+func (r *dumpReader) readHeader(line string) error {
+	col := strings.Split(strings.TrimPrefix(line, dumpHeaderPrefix), ";")
+	version, err := strconv.Atoi(col[0])
+	if err != nil || version < 2 {
+		return fmt.Errorf("%w: header %q", ErrMalformedRecord, line)
+	}
+	if version > DumpVersion {
+		return fmt.Errorf("%w: %d, the newest supported is %d", ErrUnsupportedDumpVersion, version, DumpVersion)
+	}
+
+	r.version = version
+	r.positions = make([]int, len(r.schema.columns))
+	for i, column := range r.schema.columns {
+		r.positions[i] = -1
+		for position, name := range col[1:] {
+			if name == column {
+				r.positions[i] = position
+				break
+			}
+		}
+		if r.positions[i] < 0 && i < r.schema.required {
+			return fmt.Errorf("%w: header has no %s column", ErrMalformedRecord, column)
+		}
+	}
+	return nil
+}
+
+// readDump calls restore for every record of the dump file in the current column order,
+// a missing file has no records. Lines that fail their checksum and records restore
+// finds invalid are reported, a header that can't be read fails the whole file.
+func readDump(dir string, name string, report *ImportReport, restore func(record string) error) (err error) {
+	src, err := os.Open(filepath.Join(dir, name))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := src.Close(); cerr != nil {
+			if err == nil {
+				err = cerr
+			}
+		}
+	}()
+
+	dump := newDumpReader(name)
+	reader := bufio.NewReader(src)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if line == "" && err == io.EOF {
+			return nil
+		}
+
+		record, header, rerr := dump.read(strings.TrimSuffix(line, "\n"))
+		if header && rerr != nil {
+			return fmt.Errorf("%s: %w", name, rerr)
+		}
+		if !header && rerr == nil {
+			rerr = restore(record)
+		}
+		rerr = report.reject(name, dump.checker.line, rerr)
+		if rerr != nil {
+			return rerr
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// Migrate upgrades the dump files of dir to DumpVersion in place and returns the names of the upgraded files.
+// A directory written by Export gets a new generation with the upgraded files, so a crash leaves
+// the old one current, files of the legacy layout are replaced atomically one by one.
+func Migrate(dir string) ([]string, error) {
+	m, err := readManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	path := dir
+	names := dumpFiles
+	if m != nil {
+		err = m.verify(dir)
+		if err != nil {
+			return nil, err
+		}
+		path = generationPath(dir, m.Generation)
+		names = make([]string, 0, len(m.Files))
+		for _, file := range m.Files {
+			names = append(names, file.Name)
+		}
+	}
+
+	outdated, err := outdatedFiles(path, names)
+	if err != nil || len(outdated) == 0 {
+		return nil, err
+	}
+	if m == nil {
+		return outdated, migrateFiles(dir, dir, names, outdated)
+	}
+
+	next := &manifest{
+		Generation:	m.Generation + 1,
+		Format:		m.Format,
+		Delimiter:	m.Delimiter,
+		CreatedAt:	m.CreatedAt,
+		LSN:		m.LSN,
+	}
+	nextPath := generationPath(dir, next.Generation)
+	err = os.RemoveAll(nextPath)
+	if err != nil {
+		return nil, err
+	}
+	err = os.MkdirAll(nextPath, 0770)
+	if err != nil {
+		return nil, err
+	}
+
+	err = migrateFiles(path, nextPath, names, outdated)
+	if err != nil {
+		return nil, err
+	}
+	return outdated, commitGeneration(dir, next, m)
+}
+
+// migrateFiles writes the named files of src to dst, the outdated ones are upgraded
+// and the others are copied unless dst is src.
+func migrateFiles(src string, dst string, names []string, outdated []string) error {
+	upgrade := make(map[string]bool, len(outdated))
+	for _, name := range outdated {
+		upgrade[name] = true
+	}
+
+	for _, name := range names {
+		if src == dst && !upgrade[name] {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(src, name))
+		if err != nil {
+			return err
+		}
+		if upgrade[name] {
+			data, err = migrateDump(name, data)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = writeFileAtomic(filepath.Join(dst, name), data)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// outdatedFiles returns the dump files among names written in a version older than DumpVersion.
+func outdatedFiles(dir string, names []string) ([]string, error) {
+	outdated := make([]string, 0)
+	for _, name := range names {
+		if dumpSchemas[name] == nil {
+			continue
+		}
+
+		version, err := dumpVersion(filepath.Join(dir, name), name)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if version < DumpVersion {
+			outdated = append(outdated, name)
+		}
+	}
+	return outdated, nil
+}
+
+// dumpVersion reads the version of the dump file from its first line.
+func dumpVersion(path string, name string) (version int, err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if cerr := src.Close(); cerr != nil {
+			if err == nil {
+				err = cerr
+			}
+		}
+	}()
+
+	line, err := bufio.NewReader(src).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+
+	reader := newDumpReader(name)
+	_, _, err = reader.read(strings.TrimSuffix(line, "\n"))
+	if err != nil {
+		return 0, err
+	}
+	return reader.version, nil
+}
+
+// migrateDump rewrites the content of the dump file in the current version.
+func migrateDump(name string, data []byte) ([]byte, error) {
+	schema := dumpSchemas[name]
+	reader := newDumpReader(name)
+	migrated := []byte(dumpHeader(name) + "\n")
+
+	for _, line := range strings.SplitAfter(string(data), "\n") {
+		if line == "" {
+			continue
+		}
+
+		record, header, err := reader.read(strings.TrimSuffix(line, "\n"))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if header {
+			continue
+		}
+
+		record, err = schema.upgrade(record)
+		if err != nil {
+			return nil, fmt.Errorf("%s: line %d: %w", name, reader.checker.line, err)
+		}
+		migrated = append(migrated, []byte(sealRecord(record) + "\n")...)
+	}
+	return migrated, nil
+}
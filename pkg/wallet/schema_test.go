@@ -0,0 +1,183 @@
+package wallet
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeDump writes the lines of a dump file to dir.
+func writeDump(t *testing.T, dir string, name string, lines ...string) {
+	t.Helper()
+	err := os.WriteFile(filepath.Join(dir, name), []byte(strings.Join(lines, "\n") + "\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// firstLine returns the first line of the file.
+func firstLine(t *testing.T, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.SplitN(string(content), "\n", 2)[0]
+}
+
+// writeLegacyDumps writes version 1 dumps as they were written before the later columns were added.
+func writeLegacyDumps(t *testing.T, dir string) {
+	t.Helper()
+	writeDump(t, dir, "accounts.dump", "1;+992000000001;9000", "2;+992000000002;0;FROZEN")
+	writeDump(t, dir, "payments.dump", "p1;1;1000;food;INPROGRESS")
+	writeDump(t, dir, "favorites.dump", "f1;1;osh;1000;food")
+}
+
+func TestService_Export_dumpHeader(t *testing.T) {
+	dir := t.TempDir()
+	s := newTestService()
+	_, _, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Export(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := firstLine(t, filepath.Join(currentGeneration(t, dir), "accounts.dump"))
+	if got != dumpHeader("accounts.dump") || !strings.HasPrefix(got, "#v2;id;phone;balance") {
+		t.Errorf("Export(): got header %q, want %q", got, dumpHeader("accounts.dump"))
+	}
+
+	imported := NewService()
+	err = imported.Import(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSameState(t, imported, s.Service)
+}
+
+func TestService_Import_dumpColumnsByName(t *testing.T) {
+	dir := t.TempDir()
+	// columns in another order, a column this version doesn't know and no creditLimit column
+	writeDump(t, dir, "accounts.dump",
+		sealRecord("#v2;phone;id;nickname;balance;status"),
+		sealRecord("+992000000001;1;tom;500;ACTIVE"),
+	)
+
+	s := NewService()
+	err := s.Import(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	account, err := s.FindAccountByID(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.Phone != "+992000000001" || account.Balance != 500 || account.CreditLimit != 0 {
+		t.Errorf("Import(): got %+v", account)
+	}
+}
+
+func TestService_Import_unsupportedDumpVersion(t *testing.T) {
+	dir := t.TempDir()
+	writeDump(t, dir, "accounts.dump", "#v3;id;phone;balance", "1;+992000000001;500")
+
+	err := NewService().Import(dir)
+	if !errors.Is(err, ErrUnsupportedDumpVersion) {
+		t.Errorf("Import(): must return ErrUnsupportedDumpVersion, returned = %v", err)
+	}
+
+	writeDump(t, dir, "accounts.dump", "#v2;phone;balance", "+992000000001;500")
+	err = NewService().Import(dir)
+	if !errors.Is(err, ErrMalformedRecord) {
+		t.Errorf("Import(): must return ErrMalformedRecord without the id column, returned = %v", err)
+	}
+}
+
+func TestMigrate_legacyLayout(t *testing.T) {
+	dir := t.TempDir()
+	writeLegacyDumps(t, dir)
+	want := NewService()
+	err := want.Import(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	migrated, err := Migrate(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(migrated, []string{"accounts.dump", "payments.dump", "favorites.dump"}) {
+		t.Errorf("Migrate(): got %v", migrated)
+	}
+	for _, name := range migrated {
+		if got := firstLine(t, filepath.Join(dir, name)); got != dumpHeader(name) {
+			t.Errorf("Migrate(): %s starts with %q, want the header", name, got)
+		}
+	}
+
+	got := NewService()
+	err = got.Import(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSameState(t, got, want)
+
+	migrated, err = Migrate(dir)
+	if err != nil || len(migrated) != 0 {
+		t.Errorf("Migrate(): got %v, %v, want nothing to migrate", migrated, err)
+	}
+	corruptions, err := Verify(dir)
+	if err != nil || corruptions != nil {
+		t.Errorf("Verify(): got %v, %v, want no corruptions", corruptions, err)
+	}
+}
+
+func TestMigrate_generation(t *testing.T) {
+	dir := t.TempDir()
+	err := os.MkdirAll(generationPath(dir, 1), 0770)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeLegacyDumps(t, generationPath(dir, 1))
+	err = commitGeneration(dir, &manifest{Generation: 1, Format: FormatDump, LSN: 7}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := NewService()
+	err = want.Import(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	migrated, err := Migrate(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrated) != 3 {
+		t.Errorf("Migrate(): got %v, want 3 files", migrated)
+	}
+
+	m, err := readManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Generation != 2 || m.LSN != 7 {
+		t.Errorf("Migrate(): got generation %d, lsn %d, want generation 2 with the lsn kept", m.Generation, m.LSN)
+	}
+	if got := firstLine(t, filepath.Join(currentGeneration(t, dir), "payments.dump")); got != dumpHeader("payments.dump") {
+		t.Errorf("Migrate(): payments.dump starts with %q, want the header", got)
+	}
+
+	got := NewService()
+	err = got.Import(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSameState(t, got, want)
+}
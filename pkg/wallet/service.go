@@ -1,10 +1,9 @@
 package wallet
 
 import (
-	"bufio"
 	"errors"
+	"fmt"
 	"github.com/aminjonshermatov/wallet/pkg/types"
-	"github.com/google/uuid"
 	"io"
 	"math"
 	"os"
@@ -12,6 +11,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
 var ErrPhoneRegistered = errors.New("phone already registered")
@@ -20,63 +20,256 @@ var ErrAccountNotFound = errors.New("account not found")
 var ErrPaymentNotFound = errors.New("payment not found")
 var ErrNotEnoughBalance = errors.New("account balance least then amount")
 var ErrFavoriteNotFound = errors.New("favorite payment not found")
+var ErrInvalidCategory = errors.New("category must not contain ';' or new lines")
 
 type Service struct {
-	nextAccountID	int64
-	accounts		[]*types.Account
-	payments		[]*types.Payment
-	favorites		[]*types.Favorite
+	mu			sync.RWMutex
+	// exportMu serialises exports, they hold only the read lock but each takes the next generation.
+	exportMu	sync.Mutex
+	once		sync.Once
+	accounts	AccountRepository
+	payments	PaymentRepository
+	favorites	FavoriteRepository
+	journal		JournalRepository
+	now			func() time.Time
+
+	transitions			map[string][]types.PaymentTransition
+	idempotency			map[string]*idempotencyRecord
+	idempotencyWindow	time.Duration
+	idempotencySweep	int
+	limits				map[int64][]SpendingLimit
+	location			*time.Location
+
+	wal					*WAL
+	// snapshotDir is where the log was last checkpointed, imports are checkpointed there too.
+	snapshotDir			string
+	lsn					uint64
+	op					*walRecord
+	replaying			*walRecord
 }
 
-func (s *Service) RegisterAccount(phone types.Phone) (*types.Account, error) {
-	for _, account := range s.accounts {
-		if account.Phone == phone {
-			return nil, ErrPhoneRegistered
+type Option func(s *Service)
+
+func WithAccountRepository(accounts AccountRepository) Option {
+	return func(s *Service) {
+		s.accounts = accounts
+	}
+}
+
+func WithPaymentRepository(payments PaymentRepository) Option {
+	return func(s *Service) {
+		s.payments = payments
+	}
+}
+
+func WithFavoriteRepository(favorites FavoriteRepository) Option {
+	return func(s *Service) {
+		s.favorites = favorites
+	}
+}
+
+// WithClock makes the Service take payment timestamps from now instead of time.Now.
+func WithClock(now func() time.Time) Option {
+	return func(s *Service) {
+		s.now = now
+	}
+}
+
+// NewService creates a Service, storages that are not given are kept in memory.
+// The zero Service is ready to use too.
+func NewService(options ...Option) *Service {
+	s := &Service{}
+	for _, option := range options {
+		option(s)
+	}
+	s.init()
+	return s
+}
+
+func (s *Service) init() {
+	s.once.Do(func() {
+		if s.accounts == nil {
+			s.accounts = NewMemoryAccountRepository()
+		}
+		if s.payments == nil {
+			s.payments = NewMemoryPaymentRepository()
+		}
+		if s.favorites == nil {
+			s.favorites = NewMemoryFavoriteRepository()
+		}
+		if s.journal == nil {
+			s.journal = NewMemoryJournalRepository()
+		}
+		if s.now == nil {
+			s.now = time.Now
+		}
+		if s.idempotencyWindow == 0 {
+			s.idempotencyWindow = DefaultIdempotencyWindow
 		}
+		if s.location == nil {
+			s.location = time.UTC
+		}
+		s.transitions = make(map[string][]types.PaymentTransition)
+		s.idempotency = make(map[string]*idempotencyRecord)
+		s.limits = make(map[int64][]SpendingLimit)
+	})
+}
+
+func (s *Service) lock() {
+	s.init()
+	s.mu.Lock()
+}
+
+func (s *Service) rlock() {
+	s.init()
+	s.mu.RLock()
+}
+
+// timestamp returns the current time without the monotonic clock reading,
+// so it survives a round trip through the dump files.
+func (s *Service) timestamp() time.Time {
+	if s.op != nil {
+		return s.op.At
+	}
+	return s.now().Round(0)
+}
+
+func (s *Service) RegisterAccount(phone types.Phone) (*types.Account, error) {
+	s.lock()
+	defer s.mu.Unlock()
+
+	if err := s.begin(walRegisterAccount, walArgs{Phone: phone}); err != nil {
+		return nil, err
+	}
+	defer s.end()
+
+	account, err := s.registerAccount(phone)
+	if err != nil {
+		return nil, err
+	}
+
+	return copyAccount(account), nil
+}
+
+func (s *Service) registerAccount(phone types.Phone) (*types.Account, error) {
+	phone, err := NormalizePhone(phone)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.accounts.FindByPhone(phone)
+	if err == nil {
+		return nil, ErrPhoneRegistered
+	}
+	if err != ErrAccountNotFound {
+		return nil, err
+	}
+
+	accountID, err := s.accounts.NextID()
+	if err != nil {
+		return nil, err
 	}
 
-	s.nextAccountID++
 	account := &types.Account{
-		ID: 		s.nextAccountID,
+		ID: 		accountID,
 		Phone: 		phone,
 		Balance: 	0,
+		Status:		types.AccountStatusActive,
 	}
 
-	s.accounts = append(s.accounts, account)
+	err = s.accounts.Save(account)
+	if err != nil {
+		return nil, err
+	}
 	return account, nil
 }
 
 func (s *Service) Deposit(accountID int64, amount types.Money) error {
+	s.lock()
+	defer s.mu.Unlock()
+
+	if err := s.begin(walDeposit, walArgs{AccountID: accountID, Amount: amount}); err != nil {
+		return err
+	}
+	defer s.end()
+
+	return s.deposit(accountID, amount)
+}
+
+func (s *Service) deposit(accountID int64, amount types.Money) error {
 	if amount <= 0 {
 		return ErrAmountMustBePositive
 	}
 
-	account, err := s.FindAccountByID(accountID)
+	account, err := s.findAccountByID(accountID)
+	if err != nil {
+		return err
+	}
+
+	err = checkActive(account)
+	if err != nil {
+		return err
+	}
+
+	err = s.post(LedgerOperationDeposit, LedgerDeposits, WalletLedgerAccount(accountID), amount, "")
 	if err != nil {
 		return err
 	}
 
 	account.Balance += amount
-	return nil
+	return s.accounts.Save(account)
 }
 
 func (s *Service) Pay(accountID int64, amount types.Money, category types.PaymentCategory) (*types.Payment, error) {
+	s.lock()
+	defer s.mu.Unlock()
+
+	if err := s.begin(walPay, walArgs{AccountID: accountID, Amount: amount, Category: category}); err != nil {
+		return nil, err
+	}
+	defer s.end()
+
+	payment, err := s.pay(accountID, amount, category)
+	if err != nil {
+		return nil, err
+	}
+
+	return copyPayment(payment), nil
+}
+
+func (s *Service) pay(accountID int64, amount types.Money, category types.PaymentCategory) (*types.Payment, error) {
 	if amount <= 0 {
 		return nil, ErrAmountMustBePositive
 	}
 
-	account, err := s.FindAccountByID(accountID)
+	err := checkCategory(category)
 	if err != nil {
 		return nil, err
 	}
 
-	if account.Balance < amount {
+	account, err := s.findAccountByID(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkActive(account)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.checkLimits(accountID, amount, category)
+	if err != nil {
+		return nil, err
+	}
+
+	if account.Available() < amount {
 		return nil, ErrNotEnoughBalance
 	}
 
 	account.Balance -= amount
 
-	paymentID := uuid.New().String()
+	paymentID := s.newID()
+	now := s.timestamp()
 
 	payment := &types.Payment{
 		ID:			paymentID,
@@ -84,109 +277,218 @@ func (s *Service) Pay(accountID int64, amount types.Money, category types.Paymen
 		Amount: 	amount,
 		Category: 	category,
 		Status: 	types.PaymentStatusInProgress,
+		CreatedAt:	now,
+		UpdatedAt:	now,
+	}
+
+	err = s.post(LedgerOperationPay, WalletLedgerAccount(accountID), LedgerPayments, amount, paymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.payments.Save(payment)
+	if err != nil {
+		return nil, err
 	}
 
-	s.payments = append(s.payments, payment)
+	err = s.accounts.Save(account)
+	if err != nil {
+		return nil, err
+	}
 
+	s.recordTransition(payment.ID, "", payment.Status)
 	return payment, nil
 }
 
+// checkCategory keeps the separators of the dump files out of categories.
+func checkCategory(category types.PaymentCategory) error {
+	if strings.ContainsAny(string(category), ";\r\n") {
+		return ErrInvalidCategory
+	}
+	return nil
+}
+
 func (s *Service) FindAccountByID(accountID int64) (*types.Account, error) {
-	var account *types.Account
+	s.rlock()
+	defer s.mu.RUnlock()
 
-	for _, acc := range s.accounts {
-		if acc.ID == accountID {
-			account = acc
-			break
-		}
+	account, err := s.findAccountByID(accountID)
+	if err != nil {
+		return nil, err
 	}
 
-	if account == nil {
-		return nil, ErrAccountNotFound
-	}
+	return copyAccount(account), nil
+}
 
-	return account, nil
+func (s *Service) findAccountByID(accountID int64) (*types.Account, error) {
+	return s.accounts.FindByID(accountID)
 }
 
 func (s *Service) FindPaymentByID(paymentID string) (*types.Payment, error) {
-	for _, payment := range s.payments {
-		if payment.ID == paymentID {
-			return payment, nil
-		}
+	s.rlock()
+	defer s.mu.RUnlock()
+
+	payment, err := s.findPaymentByID(paymentID)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil, ErrPaymentNotFound
+	return copyPayment(payment), nil
+}
+
+func (s *Service) findPaymentByID(paymentID string) (*types.Payment, error) {
+	return s.payments.FindByID(paymentID)
 }
 
 func (s *Service) Reject(paymentID string) error {
-	payment, err := s.FindPaymentByID(paymentID)
+	s.lock()
+	defer s.mu.Unlock()
+
+	if err := s.begin(walReject, walArgs{PaymentID: paymentID}); err != nil {
+		return err
+	}
+	defer s.end()
+
+	payment, err := s.findPaymentByID(paymentID)
 	if err != nil {
 		return err
 	}
 
-	account, err := s.FindAccountByID(payment.AccountID)
+	if payment.Kind != types.PaymentKindRegular {
+		return s.rejectTransfer(payment)
+	}
+
+	account, err := s.findAccountByID(payment.AccountID)
 	if err != nil {
 		return err
 	}
 
-	account.Balance += payment.Amount
-	payment.Amount = 0
-	payment.Status = types.PaymentStatusFail
-	return nil
+	if account.Status == types.AccountStatusClosed {
+		return ErrAccountClosed
+	}
+
+	err = checkTransition(payment, types.PaymentStatusFail)
+	if err != nil {
+		return err
+	}
+
+	charged := payment.Charged()
+	err = s.post(LedgerOperationReject, LedgerPayments, WalletLedgerAccount(account.ID), charged, payment.ID)
+	if err != nil {
+		return err
+	}
+
+	err = s.transition(payment, types.PaymentStatusFail)
+	if err != nil {
+		return err
+	}
+
+	account.Balance += charged
+	payment.Refunded = payment.Amount
+
+	err = s.payments.Save(payment)
+	if err != nil {
+		return err
+	}
+
+	return s.accounts.Save(account)
 }
 
 func (s *Service) Repeat(paymentID string) (*types.Payment, error) {
-	payment, err := s.FindPaymentByID(paymentID)
+	s.lock()
+	defer s.mu.Unlock()
+
+	if err := s.begin(walRepeat, walArgs{PaymentID: paymentID}); err != nil {
+		return nil, err
+	}
+	defer s.end()
+
+	payment, err := s.repeat(paymentID)
 	if err != nil {
 		return nil, err
 	}
 
-	newPayment, err := s.Pay(payment.AccountID, payment.Amount, payment.Category)
+	return copyPayment(payment), nil
+}
+
+func (s *Service) repeat(paymentID string) (*types.Payment, error) {
+	payment, err := s.findPaymentByID(paymentID)
 	if err != nil {
 		return nil, err
 	}
 
-	return newPayment, nil
+	if payment.Kind != types.PaymentKindRegular {
+		return s.repeatTransfer(payment)
+	}
+
+	return s.pay(payment.AccountID, payment.Amount, payment.Category)
 }
 
 func (s *Service) FavoritePayment(paymentID string, name string) (*types.Favorite, error) {
-	payment, err := s.FindPaymentByID(paymentID)
+	s.lock()
+	defer s.mu.Unlock()
+
+	if err := s.begin(walFavoritePayment, walArgs{PaymentID: paymentID, Name: name}); err != nil {
+		return nil, err
+	}
+	defer s.end()
+
+	payment, err := s.findPaymentByID(paymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	favorites, err := s.accountFavorites(payment.AccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkFavoriteName(favorites, "", name)
 	if err != nil {
 		return nil, err
 	}
 
 	favorite := &types.Favorite{
-		ID:			uuid.New().String(),
+		ID:			s.newID(),
 		AccountID: 	payment.AccountID,
 		Name: 		name,
 		Amount: 	payment.Amount,
 		Category: 	payment.Category,
+		Position:	nextFavoritePosition(favorites),
 	}
 
-	s.favorites = append(s.favorites, favorite)
-	return favorite, nil
+	err = s.favorites.Save(favorite)
+	if err != nil {
+		return nil, err
+	}
+
+	return copyFavorite(favorite), nil
 }
 
 func (s *Service) PayFromFavorite(favoriteID string) (*types.Payment, error) {
-	var targetFavorite *types.Favorite
+	s.lock()
+	defer s.mu.Unlock()
 
-	for _, favorite := range s.favorites {
-		if favorite.ID == favoriteID {
-			targetFavorite = favorite
-			break
-		}
+	if err := s.begin(walPayFromFavorite, walArgs{FavoriteID: favoriteID}); err != nil {
+		return nil, err
 	}
+	defer s.end()
 
-	if targetFavorite == nil {
-		return nil, ErrFavoriteNotFound
+	payment, err := s.payFromFavorite(favoriteID)
+	if err != nil {
+		return nil, err
 	}
 
-	payment, err := s.Pay(targetFavorite.AccountID, targetFavorite.Amount, targetFavorite.Category)
+	return copyPayment(payment), nil
+}
+
+func (s *Service) payFromFavorite(favoriteID string) (*types.Payment, error) {
+	targetFavorite, err := s.favorites.FindByID(favoriteID)
 	if err != nil {
 		return nil, err
 	}
 
-	return payment, nil
+	return s.pay(targetFavorite.AccountID, targetFavorite.Amount, targetFavorite.Category)
 }
 
 func (s *Service) ExportToFile(path string) error {
@@ -203,11 +505,15 @@ func (s *Service) ExportToFile(path string) error {
 		}
 	} ()
 
+	s.rlock()
+	accounts, err := s.accounts.All()
 	content := make([]byte, 0)
-	for _, account := range s.accounts {
-		content = append(content, []byte(strconv.FormatInt(account.ID, 10) + ";")...)
-		content = append(content, []byte(account.Phone + ";")...)
-		content = append(content, []byte(strconv.FormatInt(int64(account.Balance), 10) + "|")...)
+	for _, account := range accounts {
+		content = append(content, []byte(encodeAccount(account) + "|")...)
+	}
+	s.mu.RUnlock()
+	if err != nil {
+		return err
 	}
 
 	_, err = file.Write(content)
@@ -218,7 +524,16 @@ func (s *Service) ExportToFile(path string) error {
 	return nil
 }
 
-func (s *Service) ImportFromFile(path string) error {
+// ImportFromFile registers the accounts of a file written by ExportToFile, rows that aren't
+// "id;phone;balance" and phones that can't be registered are invalid records. Invalid records
+// fail it with an *ImportReport and leave the wallet untouched, unless WithLenient is given.
+// Like Import it checkpoints a wallet with a write-ahead log.
+func (s *Service) ImportFromFile(path string, options ...DumpOption) error {
+	opts, err := newDumpOptions(options)
+	if err != nil {
+		return err
+	}
+
 	file, err := os.Open(path)
 	if err != nil {
 		return err
@@ -236,25 +551,51 @@ func (s *Service) ImportFromFile(path string) error {
 	buf := make([]byte, 4)
 	for {
 		read, err := file.Read(buf)
+		content = append(content, buf[:read]...)
 		if err == io.EOF {
-			content = append(content, buf[:read]...)
 			break
 		}
+		if err != nil {
+			return err
+		}
+	}
 
-		content = append(content, buf[:read]...)
+	s.lock()
+	defer s.mu.Unlock()
+
+	err = s.checkImport()
+	if err != nil {
+		return err
 	}
 
-	for _, row := range strings.Split(string(content), "|") {
-		col := strings.Split(row, ";")
-		if len(col) == 3 {
-			_, err = s.RegisterAccount(types.Phone(col[1]))
+	name := filepath.Base(path)
+	err = s.importRecords(opts, func(s *Service, report *ImportReport) error {
+		for i, row := range strings.Split(string(content), "|") {
+			if row == "" {
+				continue
+			}
+
+			col := strings.Split(row, ";")
+			if len(col) < 3 {
+				report.add(name, i + 1, ErrMalformedRecord)
+				continue
+			}
+
+			_, err := s.registerAccount(types.Phone(col[1]))
+			if errors.Is(err, ErrInvalidPhone) || err == ErrPhoneRegistered {
+				err = &columnError{column: "phone", err: err}
+			}
+			err = report.reject(name, i + 1, err)
 			if err != nil {
 				return err
 			}
 		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
-
-	return nil
+	return s.checkpointImport()
 }
 
 func create(p string) (*os.File, error) {
@@ -264,31 +605,14 @@ func create(p string) (*os.File, error) {
 	return os.Create(p)
 }
 
-func (s *Service) Export(dir string) error {
-	err := ExportAccounts(s, dir)
-	if err != nil {
-		return err
-	}
-
-	err = ExportPayments(s, dir)
-	if err != nil {
-		return err
-	}
-
-	err = ExportFavorites(s, dir)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func ExportAccounts(s *Service, dir string) (err error) {
-	if len(s.accounts) == 0 {
+// writeRecords creates path and fills it with write. In every format a file of records
+// is not created for no records, so a missing file and an empty one read the same.
+func writeRecords(path string, records int, write func(w io.Writer) error) (err error) {
+	if records == 0 {
 		return nil
 	}
 
-	file, err := create(dir + "/" + "accounts.dump")
+	file, err := create(path)
 	if err != nil {
 		return err
 	}
@@ -299,299 +623,396 @@ func ExportAccounts(s *Service, dir string) (err error) {
 			}
 		}
 	}()
-	data := make([]byte, 0)
 
-	for _, account := range s.accounts {
+	return write(file)
+}
 
-		data = append(data, []byte(strconv.FormatInt(account.ID, 10) + ";")...)
-		data = append(data, []byte(account.Phone + ";")...)
-		data = append(data, []byte(strconv.FormatInt(int64(account.Balance), 10) + "\n")...)
-	}
+// writeDumpFile writes the version header of name and the sealed lines to dir/name.
+// Lines are written in the given order, callers sort them so the same state gives the same file.
+func writeDumpFile(dir string, name string, lines []string) error {
+	return writeRecords(dir + "/" + name, len(lines), func(w io.Writer) error {
+		data := []byte(dumpHeader(name) + "\n")
+		for _, line := range lines {
+			data = append(data, []byte(sealRecord(line) + "\n")...)
+		}
+		_, err := w.Write(data)
+		return err
+	})
+}
 
-	_, err = file.Write(data)
+// Export writes the wallet to dir in the format selected by options, FormatDump by default.
+// Every Export writes a new generation directory under dir/generations and then atomically
+// replaces dir/MANIFEST naming it, so a crash in the middle of Export leaves the previous snapshot current.
+func (s *Service) Export(dir string, options ...DumpOption) error {
+	opts, err := newDumpOptions(options)
 	if err != nil {
 		return err
 	}
-	return nil
+
+	s.rlock()
+	defer s.mu.RUnlock()
+
+	return s.export(dir, opts)
 }
-func ExportPayments(s *Service, dir string) (err error) {
-	if len(s.payments) == 0 {
-		return nil
-	}
 
-	file, err := create(dir + "/" + "payments.dump")
-	if err != nil {
+func (s *Service) export(dir string, opts *dumpOptions) error {
+	s.exportMu.Lock()
+	defer s.exportMu.Unlock()
+
+	previous, err := readManifest(dir)
+	if err != nil && !errors.Is(err, ErrCorruptedSnapshot) {
 		return err
 	}
-	defer func() {
-		if cerr := file.Close(); cerr != nil {
-			if err == nil {
-				err = cerr
-			}
-		}
-	}()
-	data := make([]byte, 0)
 
-	for _, payment := range s.payments {
+	next := &manifest{
+		Generation:	1,
+		Format:		opts.format,
+		Delimiter:	string(opts.delimiter),
+		CreatedAt:	s.timestamp(),
+		LSN:		s.lsn,
+	}
+	if previous != nil {
+		next.Generation = previous.Generation + 1
+	}
 
-		data = append(data, []byte(payment.ID + ";")...)
-		data = append(data, []byte(strconv.FormatInt(payment.AccountID, 10) + ";")...)
-		data = append(data, []byte(strconv.FormatInt(int64(payment.Amount), 10) + ";")...)
-		data = append(data, []byte(payment.Category + ";")...)
-		data = append(data, []byte(payment.Status + "\n")...)
+	// a generation directory left by a crashed Export is not referenced by the manifest
+	path := generationPath(dir, next.Generation)
+	err = os.RemoveAll(path)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(path, 0770)
+	if err != nil {
+		return err
 	}
 
-	_, err = file.Write(data)
+	err = exportFiles(s, path, opts)
 	if err != nil {
 		return err
 	}
-	return nil
+
+	return commitGeneration(dir, next, previous)
 }
-func ExportFavorites(s *Service, dir string) (err error) {
-	if len(s.favorites) == 0 {
-		return nil
-	}
 
-	file, err := create(dir + "/" + "favorites.dump")
+func exportFiles(s *Service, dir string, opts *dumpOptions) error {
+	var err error
+	switch opts.format {
+	case FormatJSON:
+		err = exportJSON(s, dir)
+	case FormatJSONLines:
+		err = exportJSONLines(s, dir)
+	case FormatCSV:
+		err = exportCSV(s, dir, opts.delimiter)
+	default:
+		err = exportDump(s, dir)
+	}
 	if err != nil {
 		return err
 	}
-	defer func() {
-		if cerr := file.Close(); cerr != nil {
-			if err == nil {
-				err = cerr
-			}
-		}
-	}()
-	data := make([]byte, 0)
 
-	for _, favorite := range s.favorites {
+	err = exportIdempotency(s, dir)
+	if err != nil {
+		return err
+	}
 
-		data = append(data, []byte(favorite.ID + ";")...)
-		data = append(data, []byte(strconv.FormatInt(favorite.AccountID, 10) + ";")...)
-		data = append(data, []byte(favorite.Name + ";")...)
-		data = append(data, []byte(strconv.FormatInt(int64(favorite.Amount), 10) + ";")...)
-		data = append(data, []byte(favorite.Category + "\n")...)
+	err = exportLimits(s, dir)
+	if err != nil {
+		return err
 	}
 
-	_, err = file.Write(data)
+	err = exportTransitions(s, dir)
 	if err != nil {
 		return err
 	}
+
 	return nil
 }
 
-func (s *Service) Import(dir string) error {
-	err := ImportAccounts(s, dir)
+func exportDump(s *Service, dir string) error {
+	err := exportAccounts(s, dir)
 	if err != nil {
 		return err
 	}
 
-	err = ImportPayments(s, dir)
+	err = exportPayments(s, dir)
 	if err != nil {
 		return err
 	}
 
-	err = ImportFavorites(s, dir)
+	return exportFavorites(s, dir)
+}
+
+// ExportAccounts writes accounts.dump to dir in the legacy layout,
+// the file is replaced atomically.
+func ExportAccounts(s *Service, dir string) error {
+	s.rlock()
+	defer s.mu.RUnlock()
+
+	return replaceFiles(dir, func(tmp string) error {
+		return exportAccounts(s, tmp)
+	})
+}
+
+func exportAccounts(s *Service, dir string) error {
+	accounts, err := s.accounts.All()
 	if err != nil {
 		return err
 	}
-	return nil
+
+	lines := make([]string, 0, len(accounts))
+	for _, account := range accounts {
+		lines = append(lines, encodeAccount(account))
+	}
+	return writeDumpFile(dir, "accounts.dump", lines)
 }
 
-func ImportAccounts(s *Service, dir string) (err error) {
-	_, err = os.Stat(dir + "/" + "accounts.dump")
-	if !os.IsNotExist(err) {
-		src, err := os.Open(dir + "/" + "accounts.dump")
-		if err != nil {
-			return err
-		}
-		defer func() {
-			if cerr := src.Close(); cerr != nil {
-				if err == nil {
-					err = cerr
-				}
-			}
-		}()
+// ExportPayments writes payments.dump to dir in the legacy layout,
+// the file is replaced atomically.
+func ExportPayments(s *Service, dir string) error {
+	s.rlock()
+	defer s.mu.RUnlock()
 
-		reader := bufio.NewReader(src)
+	return replaceFiles(dir, func(tmp string) error {
+		return exportPayments(s, tmp)
+	})
+}
 
-		for {
-			line, err := reader.ReadString('\n')
-			if err == io.EOF {
-				break
-			}
-			if err != nil {
-				return err
-			}
+func exportPayments(s *Service, dir string) error {
+	payments, err := s.payments.All()
+	if err != nil {
+		return err
+	}
 
-			line = strings.Replace(line, "\n", "", 1)
-			col := strings.Split(line, ";")
-			newAccount := &types.Account{
-				Phone: types.Phone(col[1]),
-			}
-			num, err := strconv.Atoi(col[0])
-			if  err != nil {
-				return err
-			}
-			newAccount.ID = int64(num)
+	lines := make([]string, 0, len(payments))
+	for _, payment := range payments {
+		lines = append(lines, encodePayment(payment))
+	}
+	return writeDumpFile(dir, "payments.dump", lines)
+}
 
-			balance, err := strconv.Atoi(col[2])
-			if err != nil {
-				return err
-			}
-			newAccount.Balance = types.Money(balance)
+// ExportFavorites writes favorites.dump to dir in the legacy layout,
+// the file is replaced atomically.
+func ExportFavorites(s *Service, dir string) error {
+	s.rlock()
+	defer s.mu.RUnlock()
 
-			isFind := false
-			for _, account := range s.accounts {
-				if account.ID == newAccount.ID {
-					isFind = true
-					break
-				}
-			}
+	return replaceFiles(dir, func(tmp string) error {
+		return exportFavorites(s, tmp)
+	})
+}
 
-			if !isFind {
-				s.accounts = append(s.accounts, newAccount)
-			}
-		}
-		return nil
+func exportFavorites(s *Service, dir string) error {
+	favorites, err := s.favorites.All()
+	if err != nil {
+		return err
 	}
-	return nil
+
+	lines := make([]string, 0, len(favorites))
+	for _, favorite := range favorites {
+		lines = append(lines, encodeFavorite(favorite))
+	}
+	return writeDumpFile(dir, "favorites.dump", lines)
 }
-func ImportPayments(s *Service, dir string) (err error) {
-	_, err = os.Stat(dir + "/" + "payments.dump")
-	if !os.IsNotExist(err) {
-		src, err := os.Open(dir + "/" + "payments.dump")
+
+// Import reads the wallet from dir in the format selected by options, FormatDump by default.
+// Directories written by Export are read from the generation named by their MANIFEST
+// in the format it was written in, directories without a MANIFEST are read in the legacy layout.
+// Records whose ids are already in the wallet are kept as they are.
+// Invalid records fail Import with an *ImportReport listing all of them and leave the wallet
+// untouched, unless WithLenient is given. A file that doesn't match the MANIFEST fails Import
+// with ErrCorruptedSnapshot, with WithLenient or WithReport it is reported as a whole and its
+// records are checked one by one. Imported records are not logged, a wallet with a write-ahead log
+// is checkpointed after Import to the directory of its last Restore or Checkpoint.
+func (s *Service) Import(dir string, options ...DumpOption) error {
+	opts, err := newDumpOptions(options)
+	if err != nil {
+		return err
+	}
+
+	m, err := readManifest(dir)
+	if err != nil {
+		return err
+	}
+	var corruptions []Corruption
+	if m != nil {
+		corruptions, err = m.corruptions(dir)
 		if err != nil {
 			return err
 		}
-		defer func() {
-			if cerr := src.Close(); cerr != nil {
-				if err == nil {
-					err = cerr
-				}
-			}
-		}()
+		if len(corruptions) != 0 && !opts.lenient && opts.report == nil {
+			return fmt.Errorf("%w: %s", ErrCorruptedSnapshot, corruptions[0])
+		}
+		written, err := m.options()
+		if err != nil {
+			return err
+		}
+		opts.format, opts.delimiter = written.format, written.delimiter
+		dir = generationPath(dir, m.Generation)
+	}
 
-		reader := bufio.NewReader(src)
+	s.lock()
+	defer s.mu.Unlock()
 
-		for {
-			line, err := reader.ReadString('\n')
-			if err == io.EOF {
-				break
-			}
-			if err != nil {
-				return err
-			}
+	err = s.checkImport()
+	if err != nil {
+		return err
+	}
 
-			line = strings.Replace(line, "\n", "", 1)
-			col := strings.Split(line, ";")
-			newPayment := &types.Payment{
-				ID: col[0],
-			}
-			num, err := strconv.Atoi(col[1])
-			if  err != nil {
-				return err
-			}
-			newPayment.AccountID = int64(num)
-			amount, err := strconv.Atoi(col[2])
-			if  err != nil {
-				return err
-			}
-			newPayment.Amount = types.Money(int64(amount))
+	err = s.importRecords(opts, func(s *Service, report *ImportReport) error {
+		for _, corruption := range corruptions {
+			report.add(corruption.File, 0, fmt.Errorf("%w: %s", ErrCorruptedSnapshot, corruption.Reason))
+		}
+		return importFiles(s, dir, opts, report)
+	})
+	if err != nil {
+		return err
+	}
+	return s.checkpointImport()
+}
 
-			newPayment.Category = types.PaymentCategory(col[3])
-			newPayment.Status = types.PaymentStatus(col[4])
+func importFiles(s *Service, dir string, opts *dumpOptions, report *ImportReport) error {
+	var err error
+	switch opts.format {
+	case FormatJSON:
+		err = importJSON(s, dir, report)
+	case FormatJSONLines:
+		err = importJSONLines(s, dir, report)
+	case FormatCSV:
+		err = importCSV(s, dir, opts.delimiter, report)
+	default:
+		err = importDump(s, dir, report)
+	}
+	if err != nil {
+		return err
+	}
 
-			isFind := false
-			for _, payment := range s.payments {
-				if payment.ID == newPayment.ID {
-					isFind = true
-					break
-				}
-			}
+	err = importIdempotency(s, dir, report)
+	if err != nil {
+		return err
+	}
 
-			if !isFind {
-				s.payments = append(s.payments, newPayment)
-			}
-		}
-		return nil
+	err = importLimits(s, dir, report)
+	if err != nil {
+		return err
 	}
 
+	err = importTransitions(s, dir, report)
+	if err != nil {
+		return err
+	}
 	return nil
 }
-func ImportFavorites(s *Service, dir string) (err error) {
-	_, err = os.Stat(dir + "/" + "favorites.dump")
-	if !os.IsNotExist(err) {
-		src, err := os.Open(dir + "/" + "favorites.dump")
+
+func importDump(s *Service, dir string, report *ImportReport) error {
+	err := importAccounts(s, dir, report)
+	if err != nil {
+		return err
+	}
+
+	err = importPayments(s, dir, report)
+	if err != nil {
+		return err
+	}
+
+	return importFavorites(s, dir, report)
+}
+
+// ImportAccounts reads accounts.dump of dir, invalid records fail it
+// with an *ImportReport and leave the wallet untouched.
+func ImportAccounts(s *Service, dir string) error {
+	s.lock()
+	defer s.mu.Unlock()
+
+	return s.importRecords(&dumpOptions{}, func(s *Service, report *ImportReport) error {
+		return importAccounts(s, dir, report)
+	})
+}
+
+func importAccounts(s *Service, dir string, report *ImportReport) error {
+	return readDump(dir, "accounts.dump", report, func(record string) error {
+		newAccount, err := decodeAccount(record)
 		if err != nil {
 			return err
 		}
-		defer func() {
-			if cerr := src.Close(); cerr != nil {
-				if err == nil {
-					err = cerr
-				}
-			}
-		}()
+		return s.restoreAccount(newAccount)
+	})
+}
 
-		reader := bufio.NewReader(src)
+// importAccount opens an account read from a dump, the phone is normalized
+// so it is compared with phones of existing accounts the same way RegisterAccount does.
+func (s *Service) importAccount(account *types.Account) error {
+	phone, err := NormalizePhone(account.Phone)
+	if err != nil {
+		return &columnError{column: "phone", err: err}
+	}
 
-		for {
-			line, err := reader.ReadString('\n')
-			if err == io.EOF {
-				break
-			}
-			if err != nil {
-				return err
-			}
+	_, err = s.accounts.FindByPhone(phone)
+	if err == nil {
+		return &columnError{column: "phone", err: ErrPhoneRegistered}
+	}
+	if err != ErrAccountNotFound {
+		return err
+	}
 
-			line = strings.Replace(line, "\n", "", 1)
-			col := strings.Split(line, ";")
-			newFavorite := &types.Favorite{
-				ID: col[0],
-			}
-			num, err := strconv.Atoi(col[1])
-			if  err != nil {
-				return err
-			}
-			newFavorite.AccountID = int64(num)
+	account.Phone = phone
+	return s.openAccount(account)
+}
 
-			newFavorite.Name = col[2]
+// ImportPayments reads payments.dump of dir, invalid records fail it
+// with an *ImportReport and leave the wallet untouched.
+func ImportPayments(s *Service, dir string) error {
+	s.lock()
+	defer s.mu.Unlock()
 
-			amount, err := strconv.Atoi(col[3])
-			if  err != nil {
-				return err
-			}
-			newFavorite.Amount = types.Money(int64(amount))
+	return s.importRecords(&dumpOptions{}, func(s *Service, report *ImportReport) error {
+		return importPayments(s, dir, report)
+	})
+}
 
-			newFavorite.Category = types.PaymentCategory(col[4])
+func importPayments(s *Service, dir string, report *ImportReport) error {
+	return readDump(dir, "payments.dump", report, func(record string) error {
+		newPayment, err := decodePayment(record)
+		if err != nil {
+			return err
+		}
+		return s.restorePayment(newPayment)
+	})
+}
 
-			isFind := false
-			for _, favorite := range s.favorites {
-				if favorite.ID == newFavorite.ID {
-					isFind = true
-					break
-				}
-			}
+// ImportFavorites reads favorites.dump of dir, invalid records fail it
+// with an *ImportReport and leave the wallet untouched.
+func ImportFavorites(s *Service, dir string) error {
+	s.lock()
+	defer s.mu.Unlock()
 
-			if !isFind {
-				s.favorites = append(s.favorites, newFavorite)
-			}
-		}
-		return nil
-	}
+	return s.importRecords(&dumpOptions{}, func(s *Service, report *ImportReport) error {
+		return importFavorites(s, dir, report)
+	})
+}
 
-	return nil
+func importFavorites(s *Service, dir string, report *ImportReport) error {
+	return readDump(dir, "favorites.dump", report, func(record string) error {
+		newFavorite, err := decodeFavorite(record)
+		if err != nil {
+			return err
+		}
+		return s.restoreFavorite(newFavorite)
+	})
 }
 
 func (s *Service) ExportAccountHistory(accountID int64) ([]types.Payment, error) {
+	s.rlock()
+	defer s.mu.RUnlock()
+
+	payments, err := s.payments.FindByAccountID(accountID)
+	if err != nil {
+		return nil, err
+	}
+
 	res := make([]types.Payment, 0)
 
-	for _, payment := range s.payments {
-		if payment.AccountID == accountID {
-			res = append(res, *payment)
-		}
+	for _, payment := range payments {
+		res = append(res, *payment)
 	}
 
 	if len(res) == 0 {
@@ -600,8 +1021,42 @@ func (s *Service) ExportAccountHistory(accountID int64) ([]types.Payment, error)
 	return res, nil
 }
 
-func ExportToFileFrom(dir string, payments []types.Payment, start int, end int, idx string) error {
-	file, err := create(dir + "/" + "payments" + idx + ".dump")
+// ExportToFileFrom writes payments from start to end inclusive to the payments<idx> file of dir,
+// in the format selected by options, FormatDump by default.
+func ExportToFileFrom(dir string, payments []types.Payment, start int, end int, idx string, options ...DumpOption) error {
+	opts, err := newDumpOptions(options)
+	if err != nil {
+		return err
+	}
+
+	path := dir + "/" + "payments" + idx + opts.format.extension()
+	part := make([]*types.Payment, 0, end - start + 1)
+	for i := start; i <= end; i++ {
+		part = append(part, &payments[i])
+	}
+
+	switch opts.format {
+	case FormatCSV:
+		records := make([][]string, 0, len(part))
+		for _, payment := range part {
+			records = append(records, encodeCSVPayment(payment))
+		}
+		return writeCSV(path, opts.delimiter, paymentColumns, records)
+	case FormatJSONLines:
+		records := make([]interface{}, 0, len(part))
+		for _, payment := range part {
+			records = append(records, newJSONPayment(payment))
+		}
+		return writeJSONLines(path, records)
+	case FormatJSON:
+		records := make([]jsonPayment, 0, len(part))
+		for _, payment := range part {
+			records = append(records, newJSONPayment(payment))
+		}
+		return writeJSON(path, records)
+	}
+
+	file, err := create(path)
 	if err != nil {
 		return err
 	}
@@ -615,12 +1070,8 @@ func ExportToFileFrom(dir string, payments []types.Payment, start int, end int,
 
 	data := make([]byte, 0)
 
-	for i := start; i <= end; i++ {
-		data = append(data, []byte(payments[i].ID + ";")...)
-		data = append(data, []byte(strconv.FormatInt(payments[i].AccountID, 10) + ";")...)
-		data = append(data, []byte(strconv.FormatInt(int64(payments[i].Amount), 10) + ";")...)
-		data = append(data, []byte(payments[i].Category + ";")...)
-		data = append(data, []byte(payments[i].Status + "\n")...)
+	for _, payment := range part {
+		data = append(data, []byte(encodePayment(payment) + "\n")...)
 	}
 
 	_, err = file.Write(data)
@@ -631,19 +1082,21 @@ func ExportToFileFrom(dir string, payments []types.Payment, start int, end int,
 	return nil
 }
 
-func (s *Service) HistoryToFiles(payments []types.Payment, dir string, records int) error {
+// HistoryToFiles writes payments to dir, at most records payments per file,
+// in the format selected by options, FormatDump by default.
+func (s *Service) HistoryToFiles(payments []types.Payment, dir string, records int, options ...DumpOption) error {
 	if records <= 0 {
 		return errors.New("records must be non zero")
 	}
 	if len(payments) > 0 && len(payments) <= records {
-		return ExportToFileFrom(dir, payments, 0, len(payments) - 1, "")
+		return ExportToFileFrom(dir, payments, 0, len(payments) - 1, "", options...)
 	} else {
 		for i := 1; i <= int(math.Ceil(float64(len(payments)) / float64(records))); i++ {
 			end := i * records - 1
 			if end >= len(payments) {
 				end = len(payments) - 1
 			}
-			err := ExportToFileFrom(dir, payments, records * (i - 1), end, strconv.FormatInt(int64(i), 10))
+			err := ExportToFileFrom(dir, payments, records * (i - 1), end, strconv.FormatInt(int64(i), 10), options...)
 			if err != nil {
 				return err
 			}
@@ -654,6 +1107,14 @@ func (s *Service) HistoryToFiles(payments []types.Payment, dir string, records i
 }
 
 func (s *Service) SumPayments(goroutines int) types.Money {
+	s.rlock()
+	defer s.mu.RUnlock()
+
+	payments, err := s.payments.All()
+	if err != nil {
+		return 0
+	}
+
 	wg := sync.WaitGroup{}
 	if goroutines == 0 {
 		goroutines = 1
@@ -663,7 +1124,7 @@ func (s *Service) SumPayments(goroutines int) types.Money {
 	mu := sync.Mutex{}
 	sum := types.Money(0)
 
-	lenPayments := len(s.payments)
+	lenPayments := len(payments)
 	for i := 0; i < goroutines; i++ {
 		remainder := 0
 		if lenPayments % goroutines != 0 {
@@ -683,12 +1144,14 @@ func (s *Service) SumPayments(goroutines int) types.Money {
 			sumPart := types.Money(0)
 			defer wg.Done()
 			for _, payment := range partPayments {
-				sumPart += payment.Amount
+				if payment.Kind != types.PaymentKindTransferIn {
+					sumPart += payment.Charged()
+				}
 			}
 			mu.Lock()
 			defer mu.Unlock()
 			sum += sumPart
-		}(s.payments[start:end])
+		}(payments[start:end])
 	}
 
 	wg.Wait()
@@ -696,7 +1159,15 @@ func (s *Service) SumPayments(goroutines int) types.Money {
 }
 
 func (s *Service) FilterPayments(accountID int64, goroutines int) ([]types.Payment, error) {
-	_, err := s.FindAccountByID(accountID)
+	s.rlock()
+	defer s.mu.RUnlock()
+
+	_, err := s.findAccountByID(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	payments, err := s.payments.All()
 	if err != nil {
 		return nil, err
 	}
@@ -710,7 +1181,7 @@ func (s *Service) FilterPayments(accountID int64, goroutines int) ([]types.Payme
 	mu := sync.Mutex{}
 	filteredPayments := make([]types.Payment, 0)
 
-	lenPayments := len(s.payments)
+	lenPayments := len(payments)
 
 	for i := 0; i < goroutines; i++ {
 		remainder := 0
@@ -738,14 +1209,23 @@ func (s *Service) FilterPayments(accountID int64, goroutines int) ([]types.Payme
 			mu.Lock()
 			defer mu.Unlock()
 			filteredPayments = append(filteredPayments, filtered...)
-		}(s.payments[start:end])
+		}(payments[start:end])
 	}
 
 	wg.Wait()
 	return filteredPayments, nil
 }
 
+// FilterPaymentsByFn holds the read lock while filter runs, so filter must not call back into the Service.
 func (s *Service) FilterPaymentsByFn(filter func(payment types.Payment) bool, goroutines int) ([]types.Payment, error) {
+	s.rlock()
+	defer s.mu.RUnlock()
+
+	payments, err := s.payments.All()
+	if err != nil {
+		return nil, err
+	}
+
 	wg := sync.WaitGroup{}
 	if goroutines == 0 {
 		goroutines = 1
@@ -755,7 +1235,7 @@ func (s *Service) FilterPaymentsByFn(filter func(payment types.Payment) bool, go
 	mu := sync.Mutex{}
 	filteredPayments := make([]types.Payment, 0)
 
-	lenPayments := len(s.payments)
+	lenPayments := len(payments)
 
 	for i := 0; i < goroutines; i++ {
 		remainder := 0
@@ -785,7 +1265,7 @@ func (s *Service) FilterPaymentsByFn(filter func(payment types.Payment) bool, go
 			mu.Lock()
 			defer mu.Unlock()
 			filteredPayments = append(filteredPayments, filtered...)
-		}(s.payments[start:end])
+		}(payments[start:end])
 	}
 
 	wg.Wait()
@@ -794,7 +1274,18 @@ func (s *Service) FilterPaymentsByFn(filter func(payment types.Payment) bool, go
 
 func (s *Service) SumPaymentsWithProgress() <- chan types.Progress {
 	limit := 100_000
-	paymentsLen := len(s.payments)
+
+	s.rlock()
+	payments, _ := s.payments.All()
+	amounts := make([]types.Money, len(payments))
+	for i, payment := range payments {
+		if payment.Kind != types.PaymentKindTransferIn {
+			amounts[i] = payment.Charged()
+		}
+	}
+	s.mu.RUnlock()
+
+	paymentsLen := len(amounts)
 
 	countGoroutines := paymentsLen / limit
 	if paymentsLen % limit != 0 {
@@ -814,11 +1305,11 @@ func (s *Service) SumPaymentsWithProgress() <- chan types.Progress {
 		}
 
 		part := i
-		go func(payments []*types.Payment) {
+		go func(amounts []types.Money) {
 			sum := types.Money(0)
 
-			for _, payment := range payments {
-				sum += payment.Amount
+			for _, amount := range amounts {
+				sum += amount
 			}
 
 			ch <- types.Progress{
@@ -826,7 +1317,7 @@ func (s *Service) SumPaymentsWithProgress() <- chan types.Progress {
 				Result: sum,
 			}
 			defer wg.Done()
-		}(s.payments[start:end])
+		}(amounts[start:end])
 	}
 
 	go func() {
@@ -836,3 +1327,19 @@ func (s *Service) SumPaymentsWithProgress() <- chan types.Progress {
 
 	return ch
 }
+
+
+func copyAccount(account *types.Account) *types.Account {
+	clone := *account
+	return &clone
+}
+
+func copyPayment(payment *types.Payment) *types.Payment {
+	clone := *payment
+	return &clone
+}
+
+func copyFavorite(favorite *types.Favorite) *types.Favorite {
+	clone := *favorite
+	return &clone
+}
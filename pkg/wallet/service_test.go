@@ -7,6 +7,7 @@ import (
 	"log"
 	"reflect"
 	"sort"
+	"sync"
 	"testing"
 )
 
@@ -35,7 +36,15 @@ var defaultTestAccount = testAccount{
 }
 
 func newTestService() *testService {
-	return &testService{Service: &Service{}}
+	return &testService{Service: NewService()}
+}
+
+func (s *testService) allPayments() []*types.Payment {
+	payments, err := s.payments.All()
+	if err != nil {
+		panic(err)
+	}
+	return payments
 }
 
 func (s *testService) addAccount(data testAccount) (*types.Account, []*types.Payment, error) {
@@ -308,7 +317,7 @@ func TestService_ImportFromFile_success(t *testing.T) {
 		t.Error(err)
 	}
 
-	s.accounts = s.accounts[:0]
+	s.accounts = NewMemoryAccountRepository()
 	err = s.ImportFromFile(path)
 	if err != nil {
 		t.Error(err)
@@ -329,7 +338,7 @@ func TestService_ImportFromFile_fail(t *testing.T) {
 		t.Error(err)
 	}
 
-	s.accounts = s.accounts[:0]
+	s.accounts = NewMemoryAccountRepository()
 	err = s.ImportFromFile("data/" + path)
 	if err == nil {
 		t.Error(err)
@@ -444,9 +453,9 @@ func TestService_Import_clearAfterExport(t *testing.T) {
 		t.Error(err)
 	}
 
-	s.accounts = s.accounts[:0]
-	s.payments = s.payments[:0]
-	s.favorites = s.favorites[:0]
+	s.accounts = NewMemoryAccountRepository()
+	s.payments = NewMemoryPaymentRepository()
+	s.favorites = NewMemoryFavoriteRepository()
 
 	err = s.Import(path)
 	if err != nil {
@@ -470,7 +479,7 @@ func TestService_Import_emptySlices(t *testing.T) {
 	}
 }
 
-func TestService_ExportAccountHistory_success(t *testing.T)  {
+func TestService_ExportAccountHistory_success(t *testing.T) {
 	s := newTestService()
 
 	account, err := s.RegisterAccount("+992000000001")
@@ -496,14 +505,14 @@ func TestService_ExportAccountHistory_success(t *testing.T)  {
 		t.Error(err)
 	}
 
-	for i := 0; i < len(s.payments); i++ {
-		if *s.payments[i] != payments[i] {
-			t.Errorf("payments is not matches, got %v, want %v", payments, s.payments)
+	for i := 0; i < len(s.allPayments()); i++ {
+		if *s.allPayments()[i] != payments[i] {
+			t.Errorf("payments is not matches, got %v, want %v", payments, s.allPayments())
 		}
 	}
 }
 
-func TestService_ExportAccountHistory_fail(t *testing.T)  {
+func TestService_ExportAccountHistory_fail(t *testing.T) {
 	s := newTestService()
 
 	account, err := s.RegisterAccount("+992000000001")
@@ -521,9 +530,9 @@ func TestService_ExportAccountHistory_fail(t *testing.T)  {
 		t.Error(err)
 	}
 
-	for i := 0; i < len(s.payments); i++ {
-		if *s.payments[i] == payments[i] {
-			t.Errorf("payments is not matches, got %v, want %v", payments, s.payments)
+	for i := 0; i < len(s.allPayments()); i++ {
+		if *s.allPayments()[i] == payments[i] {
+			t.Errorf("payments is not matches, got %v, want %v", payments, s.allPayments())
 		}
 	}
 }
@@ -533,7 +542,7 @@ func TestService_HistoryToFiles_noData(t *testing.T) {
 
 	payments := make([]types.Payment, 0)
 
-	for _, payment := range s.payments {
+	for _, payment := range s.allPayments() {
 		payments = append(payments, *payment)
 	}
 
@@ -548,7 +557,7 @@ func TestService_HistoryToFiles_negativeRecords(t *testing.T) {
 
 	payments := make([]types.Payment, 0)
 
-	for _, payment := range s.payments {
+	for _, payment := range s.allPayments() {
 		payments = append(payments, *payment)
 	}
 
@@ -583,7 +592,7 @@ func TestService_HistoryToFiles_OneFile(t *testing.T) {
 
 	payments := make([]types.Payment, 0)
 
-	for _, payment := range s.payments {
+	for _, payment := range s.allPayments() {
 		payments = append(payments, *payment)
 	}
 
@@ -618,7 +627,7 @@ func TestService_HistoryToFiles_multiFile(t *testing.T) {
 
 	payments := make([]types.Payment, 0)
 
-	for _, payment := range s.payments {
+	for _, payment := range s.allPayments() {
 		payments = append(payments, *payment)
 	}
 
@@ -754,7 +763,7 @@ func BenchmarkService_FilterPaymentsByFn(b *testing.B) {
 
 	wantPayments := make([]types.Payment, 0)
 
-	for _, payment := range s.payments {
+	for _, payment := range s.allPayments() {
 		if filter(*payment) {
 			wantPayments = append(wantPayments, *payment)
 		}
@@ -766,11 +775,11 @@ func BenchmarkService_FilterPaymentsByFn(b *testing.B) {
 			b.Fatal(err)
 		}
 
-		sort.Slice(wantPayments, func (i, j int) bool {
+		sort.Slice(wantPayments, func(i, j int) bool {
 			return wantPayments[i].ID < wantPayments[j].ID
 		})
 
-		sort.Slice(result, func (i, j int) bool {
+		sort.Slice(result, func(i, j int) bool {
 			return result[i].ID < result[j].ID
 		})
 
@@ -778,4 +787,423 @@ func BenchmarkService_FilterPaymentsByFn(b *testing.B) {
 			b.Fatalf("invalid result, want %v, got %v", wantPayments, result)
 		}
 	}
-}
\ No newline at end of file
+}
+
+func TestService_concurrentPayAndDeposit(t *testing.T) {
+	s := newTestService()
+
+	account, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.Deposit(account.ID, types.Money(1_000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const workers = 50
+	const operations = 100
+
+	wg := sync.WaitGroup{}
+	wg.Add(workers * 2)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for j := 0; j < operations; j++ {
+				if err := s.Deposit(account.ID, types.Money(1)); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < operations; j++ {
+				if _, err := s.Pay(account.ID, types.Money(1), "foo"); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	got, err := s.FindAccountByID(account.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Balance != types.Money(1_000) {
+		t.Errorf("lost update, got balance %v, want %v", got.Balance, types.Money(1_000))
+	}
+
+	if sum := s.SumPayments(4); sum != types.Money(workers * operations) {
+		t.Errorf("invalid sum, got %v, want %v", sum, workers * operations)
+	}
+}
+
+func TestService_concurrentPayNeverOverdraws(t *testing.T) {
+	s := newTestService()
+
+	account, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.Deposit(account.ID, types.Money(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mu := sync.Mutex{}
+	succeeded := 0
+
+	wg := sync.WaitGroup{}
+	wg.Add(200)
+	for i := 0; i < 200; i++ {
+		go func() {
+			defer wg.Done()
+			_, err := s.Pay(account.ID, types.Money(1), "foo")
+			if err == ErrNotEnoughBalance {
+				return
+			}
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			succeeded++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if succeeded != 100 {
+		t.Errorf("invalid count of payments, got %v, want %v", succeeded, 100)
+	}
+
+	got, err := s.FindAccountByID(account.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Balance != 0 {
+		t.Errorf("invalid balance, got %v, want 0", got.Balance)
+	}
+}
+
+func TestService_concurrentRegisterAccount(t *testing.T) {
+	s := newTestService()
+
+	const workers = 100
+
+	mu := sync.Mutex{}
+	ids := make(map[int64]bool)
+
+	wg := sync.WaitGroup{}
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func(i int) {
+			defer wg.Done()
+			account, err := s.RegisterAccount(types.Phone(fmt.Sprintf("+992%09d", i)))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			ids[account.ID] = true
+			mu.Unlock()
+		}(i)
+	}
+	wg.Wait()
+
+	if len(ids) != workers {
+		t.Errorf("account ids are not unique, got %v unique, want %v", len(ids), workers)
+	}
+}
+
+func TestService_concurrentMixedOperations(t *testing.T) {
+	s := newTestService()
+
+	data := testAccount{
+		phone:		"+992000000001",
+		balance:	10_000,
+		payments:	[]struct{
+			amount		types.Money
+			category	types.PaymentCategory
+		}{
+			{amount: 10, category: "auto"},
+		},
+	}
+	account, payments, err := s.addAccount(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	favorite, err := s.FavoritePayment(payments[0].ID, "osh")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+
+	wg := sync.WaitGroup{}
+	wg.Add(8)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			if _, err := s.Pay(account.ID, types.Money(1), "foo"); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			if _, err := s.Repeat(payments[0].ID); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			if _, err := s.PayFromFavorite(favorite.ID); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			payment, err := s.Pay(account.ID, types.Money(2), "bar")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if err := s.Reject(payment.ID); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			s.SumPayments(3)
+			for range s.SumPaymentsWithProgress() {
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			if _, err := s.FilterPayments(account.ID, 3); err != nil {
+				t.Error(err)
+				return
+			}
+			if _, err := s.FilterPaymentsByFn(filter, 3); err != nil {
+				t.Error(err)
+				return
+			}
+			if _, err := s.ExportAccountHistory(account.ID); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 10; i++ {
+			if err := s.Export(dir); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 10; i++ {
+			if _, err := s.FavoritePayment(payments[0].ID, fmt.Sprintf("osh-%d", i)); err != nil {
+				t.Error(err)
+				return
+			}
+			if _, err := s.FindAccountByID(account.ID); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	wg.Wait()
+
+	spent := 50 * types.Money(1) + 101 * payments[0].Amount
+	got, err := s.FindAccountByID(account.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Balance != data.balance - spent {
+		t.Errorf("invalid balance, got %v, want %v", got.Balance, data.balance - spent)
+	}
+}
+
+// scanAccountRepository and scanPaymentRepository look records up by walking all of them,
+// the way the Service did before the indexes, to compare with in the benchmarks.
+type scanAccountRepository struct {
+	*MemoryAccountRepository
+}
+
+func (r scanAccountRepository) FindByID(id int64) (*types.Account, error) {
+	for _, account := range r.accounts {
+		if account.ID == id {
+			return copyAccount(account), nil
+		}
+	}
+	return nil, ErrAccountNotFound
+}
+
+func (r scanAccountRepository) FindByPhone(phone types.Phone) (*types.Account, error) {
+	for _, account := range r.accounts {
+		if account.Phone == phone {
+			return copyAccount(account), nil
+		}
+	}
+	return nil, ErrAccountNotFound
+}
+
+type scanPaymentRepository struct {
+	*MemoryPaymentRepository
+}
+
+func (r scanPaymentRepository) FindByID(id string) (*types.Payment, error) {
+	for _, payment := range r.payments {
+		if payment.ID == id {
+			return copyPayment(payment), nil
+		}
+	}
+	return nil, ErrPaymentNotFound
+}
+
+func (r scanPaymentRepository) FindByAccountID(accountID int64) ([]*types.Payment, error) {
+	res := make([]*types.Payment, 0)
+	for _, payment := range r.payments {
+		if payment.AccountID == accountID {
+			res = append(res, copyPayment(payment))
+		}
+	}
+	return res, nil
+}
+
+var benchmarkStorages = []struct{
+	name	string
+	options	func() []Option
+}{
+	{name: "indexed", options: func() []Option { return nil }},
+	{name: "scan", options: func() []Option {
+		return []Option{
+			WithAccountRepository(scanAccountRepository{NewMemoryAccountRepository()}),
+			WithPaymentRepository(scanPaymentRepository{NewMemoryPaymentRepository()}),
+		}
+	}},
+}
+
+func newBenchmarkService(b *testing.B, options []Option, accounts int, payments int) (*Service, []string) {
+	s := NewService(options...)
+
+	paymentIDs := make([]string, 0, payments)
+	for i := 0; i < accounts; i++ {
+		account, err := s.RegisterAccount(types.Phone(fmt.Sprintf("+992%09d", i)))
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		if payments == 0 {
+			continue
+		}
+
+		err = s.Deposit(account.ID, types.Money(payments))
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		for j := 0; j < payments / accounts; j++ {
+			payment, err := s.Pay(account.ID, 1, "foo")
+			if err != nil {
+				b.Fatal(err)
+			}
+			paymentIDs = append(paymentIDs, payment.ID)
+		}
+	}
+
+	return s, paymentIDs
+}
+
+func BenchmarkService_RegisterAccount(b *testing.B) {
+	for _, storage := range benchmarkStorages {
+		b.Run(storage.name, func(b *testing.B) {
+			s, _ := newBenchmarkService(b, storage.options(), 10_000, 0)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, err := s.RegisterAccount(types.Phone(fmt.Sprintf("+991%09d", i)))
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkService_FindPaymentByID(b *testing.B) {
+	for _, storage := range benchmarkStorages {
+		b.Run(storage.name, func(b *testing.B) {
+			s, paymentIDs := newBenchmarkService(b, storage.options(), 10, 100_000)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, err := s.FindPaymentByID(paymentIDs[i * 7919 % len(paymentIDs)])
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkService_ExportAccountHistory(b *testing.B) {
+	for _, storage := range benchmarkStorages {
+		b.Run(storage.name, func(b *testing.B) {
+			s, _ := newBenchmarkService(b, storage.options(), 1_000, 100_000)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, err := s.ExportAccountHistory(int64(i % 1_000 + 1))
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkService_Import(b *testing.B) {
+	for _, storage := range benchmarkStorages {
+		b.Run(storage.name, func(b *testing.B) {
+			s, _ := newBenchmarkService(b, storage.options(), 10, 5_000)
+			dir := b.TempDir()
+			err := s.Export(dir)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				err := NewService(storage.options()...).Import(dir)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
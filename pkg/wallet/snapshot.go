@@ -0,0 +1,277 @@
+package wallet
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+var ErrCorruptedSnapshot = errors.New("snapshot doesn't match its manifest")
+
+const manifestName = "MANIFEST"
+const generationsDir = "generations"
+
+// manifest names the current generation of an export directory and the files it consists of.
+type manifest struct {
+	Generation	int64			`json:"generation"`
+	Format		Format			`json:"format"`
+	Delimiter	string			`json:"delimiter,omitempty"`
+	CreatedAt	time.Time		`json:"createdAt"`
+	LSN			uint64			`json:"lsn,omitempty"`
+	Files		[]manifestFile	`json:"files"`
+}
+
+type manifestFile struct {
+	Name		string	`json:"name"`
+	Size		int64	`json:"size"`
+	Checksum	string	`json:"checksum,omitempty"`
+}
+
+func generationPath(dir string, generation int64) string {
+	return filepath.Join(dir, generationsDir, strconv.FormatInt(generation, 10))
+}
+
+// readManifest returns nil for a directory in the legacy layout.
+func readManifest(dir string) (*manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, manifestName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	m := &manifest{}
+	err = json.Unmarshal(data, m)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorruptedSnapshot, err)
+	}
+	if m.Generation <= 0 {
+		return nil, fmt.Errorf("%w: invalid generation %d", ErrCorruptedSnapshot, m.Generation)
+	}
+	return m, nil
+}
+
+// options restores the format the generation was written in.
+func (m *manifest) options() (*dumpOptions, error) {
+	options := []DumpOption{WithFormat(m.Format)}
+	if delimiter := []rune(m.Delimiter); len(delimiter) == 1 {
+		options = append(options, WithDelimiter(delimiter[0]))
+	}
+	return newDumpOptions(options)
+}
+
+// verify checks that every file of the generation is complete and matches its checksum.
+func (m *manifest) verify(dir string) error {
+	corruptions, err := m.corruptions(dir)
+	if err != nil {
+		return err
+	}
+	if len(corruptions) != 0 {
+		return fmt.Errorf("%w: %s", ErrCorruptedSnapshot, corruptions[0])
+	}
+	return nil
+}
+
+// corruptions returns the files of the generation that are missing or don't match their checksum.
+func (m *manifest) corruptions(dir string) ([]Corruption, error) {
+	var corruptions []Corruption
+	path := generationPath(dir, m.Generation)
+	for _, file := range m.Files {
+		corruption, err := verifyFile(path, file)
+		if err != nil {
+			return nil, err
+		}
+		if corruption != nil {
+			corruptions = append(corruptions, *corruption)
+		}
+	}
+	return corruptions, nil
+}
+
+// commitGeneration makes the written generation of m current: it syncs its files,
+// replaces the MANIFEST and removes generations older than the previous one.
+func commitGeneration(dir string, m *manifest, previous *manifest) error {
+	path := generationPath(dir, m.Generation)
+	files, err := syncFiles(path)
+	if err != nil {
+		return err
+	}
+	m.Files = files
+
+	err = syncDir(filepath.Join(dir, generationsDir))
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = writeFileAtomic(filepath.Join(dir, manifestName), data)
+	if err != nil {
+		return err
+	}
+
+	return removeGenerations(dir, m, previous)
+}
+
+// removeGenerations removes generation directories except the current and the previous one,
+// the previous one may still be read by an Import that started before the swap.
+func removeGenerations(dir string, current *manifest, previous *manifest) error {
+	entries, err := os.ReadDir(filepath.Join(dir, generationsDir))
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		generation, err := strconv.ParseInt(entry.Name(), 10, 64)
+		if err != nil || generation == current.Generation {
+			continue
+		}
+		if previous != nil && generation == previous.Generation {
+			continue
+		}
+
+		err = os.RemoveAll(filepath.Join(dir, generationsDir, entry.Name()))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncFiles flushes the files of dir and dir itself to disk and returns their sizes and checksums.
+func syncFiles(dir string) ([]manifestFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]manifestFile, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		size, err := syncFile(path)
+		if err != nil {
+			return nil, err
+		}
+		checksum, err := fileChecksum(path)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, manifestFile{Name: entry.Name(), Size: size, Checksum: checksum})
+	}
+
+	return files, syncDir(dir)
+}
+
+func syncFile(path string) (size int64, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if cerr := file.Close(); cerr != nil {
+			if err == nil {
+				err = cerr
+			}
+		}
+	}()
+
+	err = file.Sync()
+	if err != nil {
+		return 0, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// syncDir makes renames and new entries of the directory durable.
+func syncDir(dir string) error {
+	file, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+
+	err = file.Sync()
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// writeFileAtomic replaces path with data through a synced temporary file,
+// so readers see either the old or the new content.
+func writeFileAtomic(path string, data []byte) (err error) {
+	dir := filepath.Dir(path)
+	file, err := os.CreateTemp(dir, "." + filepath.Base(path) + "-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(file.Name())
+		}
+	}()
+
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(file.Name(), path)
+	if err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// replaceFiles lets write fill a temporary directory inside dir
+// and moves every written file into dir through a synced rename.
+func replaceFiles(dir string, write func(tmp string) error) error {
+	err := os.MkdirAll(dir, 0770)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.MkdirTemp(dir, ".export-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	err = write(tmp)
+	if err != nil {
+		return err
+	}
+
+	files, err := syncFiles(tmp)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		err = os.Rename(filepath.Join(tmp, file.Name), filepath.Join(dir, file.Name))
+		if err != nil {
+			return err
+		}
+	}
+	return syncDir(dir)
+}
@@ -0,0 +1,256 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aminjonshermatov/wallet/pkg/types"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// currentGeneration returns the generation directory the MANIFEST of dir names.
+func currentGeneration(t *testing.T, dir string) string {
+	t.Helper()
+	m, err := readManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m == nil {
+		t.Fatalf("%s has no manifest", dir)
+	}
+	return generationPath(dir, m.Generation)
+}
+
+func importedAccounts(t *testing.T, dir string) int {
+	t.Helper()
+	s := NewService()
+	err := s.Import(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	accounts, _ := s.accounts.All()
+	return len(accounts)
+}
+
+func TestService_Export_generations(t *testing.T) {
+	dir := t.TempDir()
+	s := NewService()
+	for i, phone := range []string{"+992000000001", "+992000000002", "+992000000003"} {
+		_, err := s.RegisterAccount(types.Phone(phone))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = s.Export(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		m, err := readManifest(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.Generation != int64(i + 1) {
+			t.Errorf("Export(): got generation %d, want %d", m.Generation, i + 1)
+		}
+		if got := importedAccounts(t, dir); got != i + 1 {
+			t.Errorf("Import(): got %d accounts, want %d", got, i + 1)
+		}
+	}
+
+	entries, err := os.ReadDir(filepath.Join(dir, generationsDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("Export(): got %d generations, only the current and the previous one must be kept", len(entries))
+	}
+}
+
+func TestService_Export_concurrent(t *testing.T) {
+	dir := t.TempDir()
+	s := newTestService()
+	_, _, err := s.addAccount(defaultTestAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const rounds = 10
+	for i := 0; i < rounds; i++ {
+		errs := make(chan error, 2)
+		for j := 0; j < 2; j++ {
+			go func() {
+				errs <- s.Export(dir)
+			}()
+		}
+		for j := 0; j < 2; j++ {
+			if err := <-errs; err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		corruptions, err := Verify(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(corruptions) != 0 {
+			t.Fatalf("Verify(): got %v after concurrent exports", corruptions)
+		}
+	}
+
+	m, err := readManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Generation != 2 * rounds {
+		t.Errorf("Export(): got generation %d, want %d", m.Generation, 2 * rounds)
+	}
+}
+
+func TestService_Export_reproducible(t *testing.T) {
+	s := NewService()
+	for i := 1; i <= 10; i++ {
+		account, err := s.RegisterAccount(types.Phone("+9920000000" + fmt.Sprintf("%02d", i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = s.DepositWithKey(fmt.Sprintf("deposit-%d", i), account.ID, 1_000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = s.SetSpendingLimit(account.ID, LimitPeriodDaily, "", 500)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = s.SetSpendingLimit(account.ID, LimitPeriodMonthly, "food", 900)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	checksums := make([]map[string]string, 0, 2)
+	for i := 0; i < 2; i++ {
+		dir := t.TempDir()
+		err := s.Export(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := readManifest(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files := make(map[string]string, len(m.Files))
+		for _, file := range m.Files {
+			files[file.Name] = file.Checksum
+		}
+		checksums = append(checksums, files)
+	}
+
+	if !reflect.DeepEqual(checksums[0], checksums[1]) || checksums[0]["limits.dump"] == "" || checksums[0]["idempotency.dump"] == "" {
+		t.Errorf("Export(): the same wallet must give the same files, got %v and %v", checksums[0], checksums[1])
+	}
+}
+
+func TestService_Export_crashKeepsPreviousSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	s := NewService()
+	_, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Export(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// an Export that crashed before the manifest swap leaves a half written generation
+	// and a temporary manifest behind
+	half := generationPath(dir, 2)
+	err = os.MkdirAll(half, 0770)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(half, "accounts.dump"), []byte("1;+992000000001;0\n2;+9920"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(dir, ".MANIFEST-1.tmp"), []byte("{\"generation\":"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := importedAccounts(t, dir); got != 1 {
+		t.Errorf("Import(): got %d accounts, want the previous snapshot with 1", got)
+	}
+
+	_, err = s.RegisterAccount("+992000000002")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Export(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := importedAccounts(t, dir); got != 2 {
+		t.Errorf("Import(): got %d accounts, want 2 after the next Export", got)
+	}
+}
+
+func TestService_Import_corruptedSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	s := NewService()
+	_, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Export(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.Truncate(filepath.Join(currentGeneration(t, dir), "accounts.dump"), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = NewService().Import(dir)
+	if !errors.Is(err, ErrCorruptedSnapshot) {
+		t.Errorf("Import(): must return ErrCorruptedSnapshot, returned = %v", err)
+	}
+}
+
+func TestService_Import_manifestFormat(t *testing.T) {
+	dir := t.TempDir()
+	s := newDumpTestService(t)
+	err := s.Export(dir, WithFormat(FormatCSV), WithDelimiter(';'))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	imported := NewService()
+	err = imported.Import(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSameState(t, imported, s.Service)
+}
+
+func TestService_Import_legacyLayout(t *testing.T) {
+	dir := t.TempDir()
+	err := ExportAccounts(newDumpTestService(t).Service, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "accounts.dump" {
+		t.Errorf("ExportAccounts(): got %v, want only accounts.dump", entries)
+	}
+	if got := importedAccounts(t, dir); got != 2 {
+		t.Errorf("Import(): got %d accounts, want 2", got)
+	}
+}
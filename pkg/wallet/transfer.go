@@ -0,0 +1,240 @@
+package wallet
+
+import (
+	"errors"
+	"github.com/aminjonshermatov/wallet/pkg/types"
+)
+
+const TransferCategory types.PaymentCategory = "transfer"
+
+var ErrSameAccount = errors.New("can't transfer to the same account")
+
+// Transfer moves amount from one account to another.
+// Both sides get a payment record linked to each other, the outgoing one is returned.
+func (s *Service) Transfer(fromID int64, toID int64, amount types.Money) (*types.Payment, error) {
+	s.lock()
+	defer s.mu.Unlock()
+
+	if err := s.begin(walTransfer, walArgs{AccountID: fromID, ToID: toID, Amount: amount}); err != nil {
+		return nil, err
+	}
+	defer s.end()
+
+	out, err := s.transfer(fromID, toID, amount)
+	if err != nil {
+		return nil, err
+	}
+
+	return copyPayment(out), nil
+}
+
+func (s *Service) transfer(fromID int64, toID int64, amount types.Money) (*types.Payment, error) {
+	if amount <= 0 {
+		return nil, ErrAmountMustBePositive
+	}
+
+	if fromID == toID {
+		return nil, ErrSameAccount
+	}
+
+	from, err := s.findAccountByID(fromID)
+	if err != nil {
+		return nil, err
+	}
+
+	to, err := s.findAccountByID(toID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkActive(from)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkActive(to)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.checkLimits(fromID, amount, TransferCategory)
+	if err != nil {
+		return nil, err
+	}
+
+	if from.Available() < amount {
+		return nil, ErrNotEnoughBalance
+	}
+
+	from.Balance -= amount
+	to.Balance += amount
+	now := s.timestamp()
+
+	out := &types.Payment{
+		ID:			s.newID(),
+		AccountID:	fromID,
+		Amount:		amount,
+		Category:	TransferCategory,
+		Status:		types.PaymentStatusInProgress,
+		Kind:		types.PaymentKindTransferOut,
+		CreatedAt:	now,
+		UpdatedAt:	now,
+	}
+	in := &types.Payment{
+		ID:			s.newID(),
+		AccountID:	toID,
+		Amount:		amount,
+		Category:	TransferCategory,
+		Status:		types.PaymentStatusInProgress,
+		Kind:		types.PaymentKindTransferIn,
+		CreatedAt:	now,
+		UpdatedAt:	now,
+	}
+	out.LinkedID = in.ID
+	in.LinkedID = out.ID
+
+	err = s.post(LedgerOperationTransfer, WalletLedgerAccount(fromID), WalletLedgerAccount(toID), amount, out.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.saveTransfer(out, in, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	s.recordTransition(out.ID, "", out.Status)
+	s.recordTransition(in.ID, "", in.Status)
+	return out, nil
+}
+
+// transferSides returns the outgoing and incoming records of the transfer the payment belongs to.
+func (s *Service) transferSides(payment *types.Payment) (*types.Payment, *types.Payment, error) {
+	linked, err := s.findPaymentByID(payment.LinkedID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if payment.Kind == types.PaymentKindTransferIn {
+		return linked, payment, nil
+	}
+	return payment, linked, nil
+}
+
+func (s *Service) confirmTransfer(payment *types.Payment) error {
+	out, in, err := s.transferSides(payment)
+	if err != nil {
+		return err
+	}
+
+	err = s.transitionTransfer(out, in, types.PaymentStatusOk)
+	if err != nil {
+		return err
+	}
+
+	err = s.payments.Save(out)
+	if err != nil {
+		return err
+	}
+
+	return s.payments.Save(in)
+}
+
+// rejectTransfer reverses both sides of the transfer.
+func (s *Service) rejectTransfer(payment *types.Payment) error {
+	out, in, err := s.transferSides(payment)
+	if err != nil {
+		return err
+	}
+
+	from, err := s.findAccountByID(out.AccountID)
+	if err != nil {
+		return err
+	}
+
+	to, err := s.findAccountByID(in.AccountID)
+	if err != nil {
+		return err
+	}
+
+	if from.Status == types.AccountStatusClosed || to.Status == types.AccountStatusClosed {
+		return ErrAccountClosed
+	}
+
+	err = checkTransfer(out, in, types.PaymentStatusFail)
+	if err != nil {
+		return err
+	}
+
+	if to.Available() < in.Amount {
+		return ErrNotEnoughBalance
+	}
+
+	err = s.post(LedgerOperationReject, WalletLedgerAccount(to.ID), WalletLedgerAccount(from.ID), in.Amount, out.ID)
+	if err != nil {
+		return err
+	}
+
+	err = s.transitionTransfer(out, in, types.PaymentStatusFail)
+	if err != nil {
+		return err
+	}
+
+	from.Balance += out.Amount
+	to.Balance -= in.Amount
+	out.Refunded = out.Amount
+	in.Refunded = in.Amount
+
+	return s.saveTransfer(out, in, from, to)
+}
+
+func (s *Service) repeatTransfer(payment *types.Payment) (*types.Payment, error) {
+	out, in, err := s.transferSides(payment)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.transfer(out.AccountID, in.AccountID, out.Amount)
+}
+
+func checkTransfer(out *types.Payment, in *types.Payment, to types.PaymentStatus) error {
+	err := checkTransition(out, to)
+	if err != nil {
+		return err
+	}
+
+	return checkTransition(in, to)
+}
+
+func (s *Service) transitionTransfer(out *types.Payment, in *types.Payment, to types.PaymentStatus) error {
+	err := checkTransfer(out, in, to)
+	if err != nil {
+		return err
+	}
+
+	err = s.transition(out, to)
+	if err != nil {
+		return err
+	}
+
+	return s.transition(in, to)
+}
+
+func (s *Service) saveTransfer(out *types.Payment, in *types.Payment, from *types.Account, to *types.Account) error {
+	err := s.payments.Save(out)
+	if err != nil {
+		return err
+	}
+
+	err = s.payments.Save(in)
+	if err != nil {
+		return err
+	}
+
+	err = s.accounts.Save(from)
+	if err != nil {
+		return err
+	}
+
+	return s.accounts.Save(to)
+}
@@ -0,0 +1,194 @@
+package wallet
+
+import (
+	"errors"
+	"github.com/aminjonshermatov/wallet/pkg/types"
+	"testing"
+)
+
+func (s *testService) addTransferAccounts(t *testing.T) (*types.Account, *types.Account) {
+	from, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Deposit(from.ID, 1_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	to, err := s.RegisterAccount("+992000000002")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return from, to
+}
+
+func (s *testService) checkBalance(t *testing.T, accountID int64, want types.Money) {
+	t.Helper()
+
+	account, err := s.FindAccountByID(accountID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.Balance != want {
+		t.Errorf("invalid balance of account %v, got %v, want %v", accountID, account.Balance, want)
+	}
+}
+
+func TestService_Transfer_success(t *testing.T) {
+	s := newTestService()
+	from, to := s.addTransferAccounts(t)
+
+	out, err := s.Transfer(from.ID, to.ID, 300)
+	if err != nil {
+		t.Fatalf("Transfer(): error = %v", err)
+	}
+
+	s.checkBalance(t, from.ID, 700)
+	s.checkBalance(t, to.ID, 300)
+
+	in, err := s.FindPaymentByID(out.LinkedID)
+	if err != nil {
+		t.Fatalf("Transfer(): linked payment not found, error = %v", err)
+	}
+	if out.Kind != types.PaymentKindTransferOut || in.Kind != types.PaymentKindTransferIn {
+		t.Errorf("Transfer(): invalid kinds, out = %v, in = %v", out, in)
+	}
+	if in.LinkedID != out.ID || in.AccountID != to.ID || in.Amount != 300 {
+		t.Errorf("Transfer(): invalid incoming payment = %v", in)
+	}
+
+	if sum := s.SumPayments(2); sum != 300 {
+		t.Errorf("SumPayments(): incoming transfer must not be counted, got %v", sum)
+	}
+}
+
+func TestService_Transfer_fail(t *testing.T) {
+	s := newTestService()
+	from, to := s.addTransferAccounts(t)
+
+	tests := []struct{
+		name	string
+		fromID	int64
+		toID	int64
+		amount	types.Money
+		want	error
+	}{
+		{"notEnoughBalance", from.ID, to.ID, 1_001, ErrNotEnoughBalance},
+		{"zeroAmount", from.ID, to.ID, 0, ErrAmountMustBePositive},
+		{"sameAccount", from.ID, from.ID, 1, ErrSameAccount},
+		{"unknownFrom", 100, to.ID, 1, ErrAccountNotFound},
+		{"unknownTo", from.ID, 100, 1, ErrAccountNotFound},
+	}
+
+	for _, tt := range tests {
+		_, err := s.Transfer(tt.fromID, tt.toID, tt.amount)
+		if err != tt.want {
+			t.Errorf("%s: Transfer() error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	s.checkBalance(t, from.ID, 1_000)
+	s.checkBalance(t, to.ID, 0)
+}
+
+func TestService_Transfer_rejectReversesBothSides(t *testing.T) {
+	s := newTestService()
+	from, to := s.addTransferAccounts(t)
+
+	out, err := s.Transfer(from.ID, to.ID, 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.Reject(out.LinkedID)
+	if err != nil {
+		t.Fatalf("Reject(): error = %v", err)
+	}
+
+	s.checkBalance(t, from.ID, 1_000)
+	s.checkBalance(t, to.ID, 0)
+
+	for _, id := range []string{out.ID, out.LinkedID} {
+		payment, err := s.FindPaymentByID(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if payment.Status != types.PaymentStatusFail {
+			t.Errorf("Reject(): status didn't changed, payment = %v", payment)
+		}
+	}
+
+	err = s.Reject(out.ID)
+	if !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("Reject(): must return ErrInvalidTransition, returned = %v", err)
+	}
+	s.checkBalance(t, from.ID, 1_000)
+}
+
+func TestService_Transfer_rejectSpentTransfer(t *testing.T) {
+	s := newTestService()
+	from, to := s.addTransferAccounts(t)
+
+	out, err := s.Transfer(from.ID, to.ID, 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.Pay(to.ID, 200, "food")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.Reject(out.ID)
+	if err != ErrNotEnoughBalance {
+		t.Fatalf("Reject(): must return ErrNotEnoughBalance, returned = %v", err)
+	}
+
+	s.checkBalance(t, from.ID, 700)
+	s.checkBalance(t, to.ID, 100)
+
+	payment, err := s.FindPaymentByID(out.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payment.Status != types.PaymentStatusInProgress {
+		t.Errorf("Reject(): status must not change, payment = %v", payment)
+	}
+}
+
+func TestService_Transfer_confirmAndRepeat(t *testing.T) {
+	s := newTestService()
+	from, to := s.addTransferAccounts(t)
+
+	out, err := s.Transfer(from.ID, to.ID, 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.Confirm(out.LinkedID)
+	if err != nil {
+		t.Fatalf("Confirm(): error = %v", err)
+	}
+	for _, id := range []string{out.ID, out.LinkedID} {
+		payment, err := s.FindPaymentByID(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if payment.Status != types.PaymentStatusOk {
+			t.Errorf("Confirm(): status didn't changed, payment = %v", payment)
+		}
+	}
+
+	repeated, err := s.Repeat(out.LinkedID)
+	if err != nil {
+		t.Fatalf("Repeat(): error = %v", err)
+	}
+	if repeated.Kind != types.PaymentKindTransferOut || repeated.AccountID != from.ID || repeated.ID == out.ID {
+		t.Errorf("Repeat(): invalid payment = %v", repeated)
+	}
+
+	s.checkBalance(t, from.ID, 400)
+	s.checkBalance(t, to.ID, 600)
+}
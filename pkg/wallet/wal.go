@@ -0,0 +1,492 @@
+package wallet
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aminjonshermatov/wallet/pkg/types"
+	"github.com/google/uuid"
+	"hash/crc32"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var ErrCorruptedWAL = errors.New("write-ahead log is corrupted")
+var ErrNoSnapshotDir = errors.New("write-ahead log has no snapshot directory, restore or checkpoint the wallet first")
+
+// walHeaderSize is the size of the record header: the payload length and its CRC-32.
+const walHeaderSize = 8
+
+// walMaxRecordSize bounds the payload of a record, a longer length in a header is damage.
+const walMaxRecordSize = 1 << 20
+
+// WAL is an append-only log of the operations that change a Service.
+// Every operation is written and synced before it is applied, so together with
+// the last snapshot the log restores the Service after a crash.
+// Records are framed by their length and CRC-32, a torn last record is dropped on open.
+type WAL struct {
+	mu		sync.Mutex
+	file	*os.File
+	records	[]*walRecord
+}
+
+type walOperation string
+
+const (
+	walRegisterAccount			walOperation = "REGISTER_ACCOUNT"
+	walDeposit					walOperation = "DEPOSIT"
+	walDepositWithKey			walOperation = "DEPOSIT_WITH_KEY"
+	walPay						walOperation = "PAY"
+	walPayWithKey				walOperation = "PAY_WITH_KEY"
+	walReject					walOperation = "REJECT"
+	walRepeat					walOperation = "REPEAT"
+	walRepeatWithKey			walOperation = "REPEAT_WITH_KEY"
+	walConfirm					walOperation = "CONFIRM"
+	walRefund					walOperation = "REFUND"
+	walTransfer					walOperation = "TRANSFER"
+	walFavoritePayment			walOperation = "FAVORITE_PAYMENT"
+	walPayFromFavorite			walOperation = "PAY_FROM_FAVORITE"
+	walPayFromFavoriteWithKey	walOperation = "PAY_FROM_FAVORITE_WITH_KEY"
+	walRenameFavorite			walOperation = "RENAME_FAVORITE"
+	walUpdateFavoriteAmount		walOperation = "UPDATE_FAVORITE_AMOUNT"
+	walReorderFavorites			walOperation = "REORDER_FAVORITES"
+	walRemoveFavorite			walOperation = "REMOVE_FAVORITE"
+	walSetAccountStatus			walOperation = "SET_ACCOUNT_STATUS"
+	walClose					walOperation = "CLOSE"
+	walCloseWithPayout			walOperation = "CLOSE_WITH_PAYOUT"
+	walSetCreditLimit			walOperation = "SET_CREDIT_LIMIT"
+	walSetSpendingLimit			walOperation = "SET_SPENDING_LIMIT"
+	walRemoveSpendingLimit		walOperation = "REMOVE_SPENDING_LIMIT"
+)
+
+// walRecord is one logged operation. The operation takes its timestamps from At
+// and derives the ids it generates from Seed, so replaying it gives the same records.
+type walRecord struct {
+	LSN		uint64			`json:"lsn"`
+	Op		walOperation	`json:"op"`
+	At		time.Time		`json:"at"`
+	Seed	uuid.UUID		`json:"seed"`
+	Args	walArgs			`json:"args"`
+
+	ids		int
+}
+
+type walArgs struct {
+	Key			string					`json:"key,omitempty"`
+	Phone		types.Phone				`json:"phone,omitempty"`
+	AccountID	int64					`json:"accountId,omitempty"`
+	ToID		int64					`json:"toId,omitempty"`
+	PaymentID	string					`json:"paymentId,omitempty"`
+	FavoriteID	string					`json:"favoriteId,omitempty"`
+	Name		string					`json:"name,omitempty"`
+	Amount		types.Money				`json:"amount,omitempty"`
+	Category	types.PaymentCategory	`json:"category,omitempty"`
+	Status		types.AccountStatus		`json:"status,omitempty"`
+	Period		LimitPeriod				`json:"period,omitempty"`
+	IDs			[]string				`json:"ids,omitempty"`
+}
+
+// WithWAL makes the Service log every change to wal before applying it.
+func WithWAL(wal *WAL) Option {
+	return func(s *Service) {
+		s.wal = wal
+	}
+}
+
+// OpenWAL opens or creates the log at path. A torn last record, left by a crash
+// in the middle of a write, is cut off, other damage is reported as ErrCorruptedWAL
+// and the log is left as it is.
+func OpenWAL(path string) (*WAL, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0660)
+	if err != nil {
+		return nil, err
+	}
+
+	records, size, err := readWAL(file)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	if info.Size() != size {
+		err = file.Truncate(size)
+		if err == nil {
+			err = file.Sync()
+		}
+		if err != nil {
+			_ = file.Close()
+			return nil, err
+		}
+	}
+
+	return &WAL{file: file, records: records}, nil
+}
+
+// readWAL returns the complete records of the log and the size they take.
+func readWAL(file *os.File) ([]*walRecord, int64, error) {
+	reader := bufio.NewReader(file)
+	records := make([]*walRecord, 0)
+	size := int64(0)
+
+	for {
+		header := make([]byte, walHeaderSize)
+		_, err := io.ReadFull(reader, header)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return records, size, nil
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+
+		length := binary.BigEndian.Uint32(header[:4])
+		if length == 0 || length > walMaxRecordSize {
+			return nil, 0, fmt.Errorf("%w: invalid record length %d at offset %d", ErrCorruptedWAL, length, size)
+		}
+		payload := make([]byte, length)
+		read, err := io.ReadFull(reader, payload)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			if tornPayload(payload[:read]) {
+				return records, size, nil
+			}
+			return nil, 0, fmt.Errorf("%w: record length %d at offset %d runs past the end", ErrCorruptedWAL, length, size)
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:]) {
+			// only the last record may be torn
+			_, err = reader.Peek(1)
+			if err == io.EOF {
+				return records, size, nil
+			}
+			return nil, 0, fmt.Errorf("%w: checksum mismatch at offset %d", ErrCorruptedWAL, size)
+		}
+
+		record := &walRecord{}
+		err = json.Unmarshal(payload, record)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%w: offset %d: %v", ErrCorruptedWAL, size, err)
+		}
+
+		records = append(records, record)
+		size += walHeaderSize + int64(length)
+	}
+}
+
+// tornPayload tells the last record cut off in the middle of its write from a damaged length
+// that runs past the end: what was written of a torn record is the beginning of one JSON object,
+// while a damaged length swallows complete records that follow it.
+func tornPayload(partial []byte) bool {
+	var record json.RawMessage
+	err := json.NewDecoder(bytes.NewReader(partial)).Decode(&record)
+	return err == io.EOF || err == io.ErrUnexpectedEOF
+}
+
+// append writes the record and syncs it to disk.
+func (w *WAL) append(record *walRecord) error {
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if len(payload) > walMaxRecordSize {
+		return fmt.Errorf("write-ahead log record of %d bytes exceeds %d", len(payload), walMaxRecordSize)
+	}
+
+	data := make([]byte, walHeaderSize, walHeaderSize + len(payload))
+	binary.BigEndian.PutUint32(data[:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(data[4:], crc32.ChecksumIEEE(payload))
+	data = append(data, payload...)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	_, err = w.file.Write(data)
+	if err != nil {
+		return err
+	}
+	return w.file.Sync()
+}
+
+// pending returns the records read on open.
+func (w *WAL) pending() []*walRecord {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.records
+}
+
+// truncate drops every record, they are in a snapshot now.
+func (w *WAL) truncate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.records = nil
+	err := w.file.Truncate(0)
+	if err != nil {
+		return err
+	}
+	return w.file.Sync()
+}
+
+func (w *WAL) Close() error {
+	return w.file.Close()
+}
+
+// begin logs the operation before it is applied, the caller holds the lock
+// and calls end when the operation is done. While a logged record is replayed
+// it is used instead of writing a new one.
+func (s *Service) begin(op walOperation, args walArgs) error {
+	if s.replaying != nil {
+		s.op = s.replaying
+		return nil
+	}
+	if s.wal == nil {
+		return nil
+	}
+
+	record := &walRecord{
+		LSN:	s.lsn + 1,
+		Op:		op,
+		At:		s.now().Round(0),
+		Seed:	uuid.New(),
+		Args:	args,
+	}
+	err := s.wal.append(record)
+	if err != nil {
+		return err
+	}
+
+	s.lsn = record.LSN
+	s.op = record
+	return nil
+}
+
+func (s *Service) end() {
+	s.op = nil
+}
+
+// newID returns a random id, within a logged operation the id is derived from the record
+// so a replay generates the same ids.
+func (s *Service) newID() string {
+	if s.op == nil {
+		return uuid.New().String()
+	}
+
+	s.op.ids++
+	return uuid.NewSHA1(s.op.Seed, []byte(strconv.Itoa(s.op.ids))).String()
+}
+
+// Replay applies the operations logged after the imported snapshot. It must be called
+// after Import and before the Service is used, operations that failed when they were logged
+// fail the same way again and are skipped.
+func (s *Service) Replay() error {
+	s.rlock()
+	wal := s.wal
+	s.mu.RUnlock()
+
+	if wal == nil {
+		return nil
+	}
+
+	for _, record := range wal.pending() {
+		s.lock()
+		skip := record.LSN <= s.lsn
+		if !skip {
+			record.ids = 0
+			s.replaying = record
+		}
+		s.mu.Unlock()
+		if skip {
+			continue
+		}
+
+		err := s.apply(record)
+
+		s.lock()
+		s.replaying = nil
+		s.lsn = record.LSN
+		s.mu.Unlock()
+
+		if errors.Is(err, ErrCorruptedWAL) {
+			return err
+		}
+	}
+	return nil
+}
+
+// apply calls the logged operation.
+func (s *Service) apply(record *walRecord) error {
+	a := record.Args
+	var err error
+	switch record.Op {
+	case walRegisterAccount:
+		_, err = s.RegisterAccount(a.Phone)
+	case walDeposit:
+		err = s.Deposit(a.AccountID, a.Amount)
+	case walDepositWithKey:
+		err = s.DepositWithKey(a.Key, a.AccountID, a.Amount)
+	case walPay:
+		_, err = s.Pay(a.AccountID, a.Amount, a.Category)
+	case walPayWithKey:
+		_, err = s.PayWithKey(a.Key, a.AccountID, a.Amount, a.Category)
+	case walReject:
+		err = s.Reject(a.PaymentID)
+	case walRepeat:
+		_, err = s.Repeat(a.PaymentID)
+	case walRepeatWithKey:
+		_, err = s.RepeatWithKey(a.Key, a.PaymentID)
+	case walConfirm:
+		err = s.Confirm(a.PaymentID)
+	case walRefund:
+		_, err = s.Refund(a.PaymentID, a.Amount)
+	case walTransfer:
+		_, err = s.Transfer(a.AccountID, a.ToID, a.Amount)
+	case walFavoritePayment:
+		_, err = s.FavoritePayment(a.PaymentID, a.Name)
+	case walPayFromFavorite:
+		_, err = s.PayFromFavorite(a.FavoriteID)
+	case walPayFromFavoriteWithKey:
+		_, err = s.PayFromFavoriteWithKey(a.Key, a.FavoriteID)
+	case walRenameFavorite:
+		_, err = s.RenameFavorite(a.FavoriteID, a.Name)
+	case walUpdateFavoriteAmount:
+		_, err = s.UpdateFavoriteAmount(a.FavoriteID, a.Amount)
+	case walReorderFavorites:
+		err = s.ReorderFavorites(a.AccountID, a.IDs)
+	case walRemoveFavorite:
+		err = s.RemoveFavorite(a.FavoriteID)
+	case walSetAccountStatus:
+		err = s.setAccountStatus(a.AccountID, a.Status)
+	case walClose:
+		err = s.Close(a.AccountID)
+	case walCloseWithPayout:
+		_, err = s.CloseWithPayout(a.AccountID)
+	case walSetCreditLimit:
+		err = s.SetCreditLimit(a.AccountID, a.Amount)
+	case walSetSpendingLimit:
+		err = s.SetSpendingLimit(a.AccountID, a.Period, a.Category, a.Amount)
+	case walRemoveSpendingLimit:
+		err = s.RemoveSpendingLimit(a.AccountID, a.Period, a.Category)
+	default:
+		return fmt.Errorf("%w: unknown operation %s", ErrCorruptedWAL, record.Op)
+	}
+	return err
+}
+
+// Restore builds a Service the way a restarted process does: it opens the log at walPath,
+// imports the snapshot in dir and replays the log over it. An empty dir starts an empty wallet,
+// an empty walPath runs without a log, a log requires dir. The returned WAL is nil without a log,
+// otherwise the caller closes it when the Service is no longer used.
+func Restore(dir string, walPath string, options ...Option) (*Service, *WAL, error) {
+	if walPath == "" {
+		s := NewService(options...)
+		if dir == "" {
+			return s, nil, nil
+		}
+		return s, nil, s.Import(dir)
+	}
+	if dir == "" {
+		return nil, nil, errors.New("write-ahead log requires a snapshot directory")
+	}
+
+	wal, err := OpenWAL(walPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the snapshot is imported before the log is attached, so the import isn't checkpointed
+	// and the log continues from the operation the snapshot was taken at
+	s := NewService(options...)
+	err = s.Import(dir)
+	if err == nil {
+		err = s.attachWAL(wal, dir)
+	}
+	if err == nil {
+		err = s.Replay()
+	}
+	if err != nil {
+		_ = wal.Close()
+		return nil, nil, err
+	}
+	return s, wal, nil
+}
+
+// attachWAL makes s log to wal from the LSN of the snapshot in dir.
+func (s *Service) attachWAL(wal *WAL, dir string) error {
+	m, err := readManifest(dir)
+	if err != nil {
+		return err
+	}
+
+	s.lock()
+	defer s.mu.Unlock()
+
+	s.wal = wal
+	s.snapshotDir = dir
+	if m != nil && m.LSN > s.lsn {
+		s.lsn = m.LSN
+	}
+	return nil
+}
+
+// Checkpoint exports the wallet to dir and truncates the log, no operation runs in between.
+func (s *Service) Checkpoint(dir string, options ...DumpOption) error {
+	opts, err := newDumpOptions(options)
+	if err != nil {
+		return err
+	}
+
+	s.lock()
+	defer s.mu.Unlock()
+
+	return s.checkpoint(dir, opts)
+}
+
+// checkpoint exports the wallet to dir and truncates the log, the caller holds the lock.
+func (s *Service) checkpoint(dir string, opts *dumpOptions) error {
+	err := s.export(dir, opts)
+	if err != nil {
+		return err
+	}
+
+	if s.wal == nil {
+		return nil
+	}
+	s.snapshotDir = dir
+	return s.wal.truncate()
+}
+
+// checkImport fails an import into a logged wallet that has no snapshot to checkpoint it to,
+// the caller holds the lock.
+func (s *Service) checkImport() error {
+	if s.wal != nil && s.snapshotDir == "" {
+		return ErrNoSnapshotDir
+	}
+	return nil
+}
+
+// checkpointImport checkpoints the wallet after an import, imported records are not
+// in the log. The caller holds the lock.
+func (s *Service) checkpointImport() error {
+	if s.wal == nil {
+		return nil
+	}
+
+	opts, err := newDumpOptions(nil)
+	if err != nil {
+		return err
+	}
+	return s.checkpoint(s.snapshotDir, opts)
+}
@@ -0,0 +1,307 @@
+package wallet
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// openTestWAL opens the log at path and closes it when the test ends.
+func openTestWAL(t *testing.T, path string) *WAL {
+	t.Helper()
+	wal, err := OpenWAL(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = wal.Close() })
+	return wal
+}
+
+// restart builds a Service the way a restarted process does: import the snapshot, replay the log.
+func restart(t *testing.T, dir string, walPath string) *Service {
+	t.Helper()
+	s, wal, err := Restore(dir, walPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = wal.Close() })
+	return s
+}
+
+// runWALOperations changes s through every kind of logged operation, some of them fail.
+func runWALOperations(t *testing.T, s *Service) {
+	t.Helper()
+	account, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := s.RegisterAccount("+992000000002")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Deposit(account.ID, 10_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.DepositWithKey("deposit-1", other.ID, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payment, err := s.Pay(account.ID, 1_000, "food")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.Pay(account.ID, 1_000_000, "food")
+	if err != ErrNotEnoughBalance {
+		t.Fatalf("Pay(): must return ErrNotEnoughBalance, returned = %v", err)
+	}
+	repeated, err := s.Repeat(payment.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Reject(repeated.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	favorite, err := s.FavoritePayment(payment.ID, "osh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.PayFromFavoriteWithKey("favorite-1", favorite.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.RenameFavorite(favorite.ID, "plov")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.Transfer(account.ID, other.ID, 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Freeze(other.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestService_Replay(t *testing.T) {
+	walPath := filepath.Join(t.TempDir(), "wallet.wal")
+	s := NewService(WithWAL(openTestWAL(t, walPath)))
+	runWALOperations(t, s)
+
+	restarted := restart(t, t.TempDir(), walPath)
+	assertSameState(t, restarted, s)
+
+	want, _ := s.Journal()
+	got, _ := restarted.Journal()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Replay(): got journal %v, want %v", got, want)
+	}
+
+	err := restarted.DepositWithKey("deposit-1", 2, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	account, _ := restarted.FindAccountByID(2)
+	if account.Balance != 800 {
+		t.Errorf("Replay(): idempotency keys must be restored, balance = %v", account.Balance)
+	}
+}
+
+func TestService_Checkpoint(t *testing.T) {
+	dir := t.TempDir()
+	walPath := filepath.Join(t.TempDir(), "wallet.wal")
+	s := NewService(WithWAL(openTestWAL(t, walPath)))
+	runWALOperations(t, s)
+
+	err := s.Checkpoint(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(walPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != 0 {
+		t.Errorf("Checkpoint(): log must be truncated, size = %d", info.Size())
+	}
+
+	_, err = s.Pay(1, 100, "auto")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restarted := restart(t, dir, walPath)
+	assertSameState(t, restarted, s)
+}
+
+func TestService_Replay_skipsExported(t *testing.T) {
+	dir := t.TempDir()
+	walPath := filepath.Join(t.TempDir(), "wallet.wal")
+	s := NewService(WithWAL(openTestWAL(t, walPath)))
+	runWALOperations(t, s)
+
+	// an Export without a checkpoint keeps the log, its records must not be applied twice
+	err := s.Export(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.Pay(1, 100, "auto")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restarted := restart(t, dir, walPath)
+	assertSameState(t, restarted, s)
+}
+
+func TestService_Import_checkpointed(t *testing.T) {
+	source := t.TempDir()
+	other := NewService(WithWAL(openTestWAL(t, filepath.Join(t.TempDir(), "other.wal"))))
+	runWALOperations(t, other)
+	err := other.Checkpoint(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	walPath := filepath.Join(t.TempDir(), "wallet.wal")
+	s := restart(t, dir, walPath)
+	err = s.Import(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the LSN of an imported snapshot belongs to another log
+	if s.lsn != 0 {
+		t.Errorf("Import(): got lsn %d, want 0", s.lsn)
+	}
+	_, err = s.Pay(1, 100, "auto")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restarted := restart(t, dir, walPath)
+	assertSameState(t, restarted, s)
+
+	unrestored := NewService(WithWAL(openTestWAL(t, filepath.Join(t.TempDir(), "wallet.wal"))))
+	err = unrestored.Import(source)
+	if err != ErrNoSnapshotDir {
+		t.Errorf("Import(): must return ErrNoSnapshotDir, returned = %v", err)
+	}
+	accounts, _ := unrestored.accounts.All()
+	if len(accounts) != 0 {
+		t.Errorf("Import(): must leave the wallet untouched, got %d accounts", len(accounts))
+	}
+}
+
+func TestOpenWAL_tornLastRecord(t *testing.T) {
+	walPath := filepath.Join(t.TempDir(), "wallet.wal")
+	s := NewService(WithWAL(openTestWAL(t, walPath)))
+	_, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(walPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Deposit(1, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(walPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, size := range []int64{info.Size() + 3, info.Size() + walHeaderSize + 5, int64(len(content)) - 1} {
+		err = os.WriteFile(walPath, content[:size], 0660)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		restarted := restart(t, t.TempDir(), walPath)
+		account, err := restarted.FindAccountByID(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if account.Balance != 0 {
+			t.Errorf("Replay(): torn deposit must be dropped, balance = %v", account.Balance)
+		}
+
+		truncated, err := os.Stat(walPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if truncated.Size() != info.Size() {
+			t.Errorf("OpenWAL(): got size %d, want the torn record cut off to %d", truncated.Size(), info.Size())
+		}
+	}
+}
+
+func TestOpenWAL_corrupted(t *testing.T) {
+	walPath := filepath.Join(t.TempDir(), "wallet.wal")
+	s := NewService(WithWAL(openTestWAL(t, walPath)))
+	_, err := s.RegisterAccount("+992000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Deposit(1, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(walPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content[walHeaderSize + 2] ^= 0xFF
+	err = os.WriteFile(walPath, content, 0660)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = OpenWAL(walPath)
+	if !errors.Is(err, ErrCorruptedWAL) {
+		t.Errorf("OpenWAL(): must return ErrCorruptedWAL, returned = %v", err)
+	}
+}
+
+func TestOpenWAL_corruptedLength(t *testing.T) {
+	walPath := filepath.Join(t.TempDir(), "wallet.wal")
+	s := NewService(WithWAL(openTestWAL(t, walPath)))
+	runWALOperations(t, s)
+
+	content, err := os.ReadFile(walPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// lengths past the limit, and a plausible one that swallows the records after the first
+	for _, length := range []uint32{0x00FFFFFF, 0xFFFFFFF0, 0, uint32(len(content))} {
+		damaged := append([]byte(nil), content...)
+		binary.BigEndian.PutUint32(damaged[:4], length)
+		err = os.WriteFile(walPath, damaged, 0660)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = OpenWAL(walPath)
+		if !errors.Is(err, ErrCorruptedWAL) {
+			t.Errorf("OpenWAL(): length %#x must return ErrCorruptedWAL, returned = %v", length, err)
+		}
+		info, err := os.Stat(walPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size() != int64(len(content)) {
+			t.Errorf("OpenWAL(): length %#x must not truncate the log, size = %d", length, info.Size())
+		}
+	}
+}
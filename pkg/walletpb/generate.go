@@ -0,0 +1,4 @@
+// Package walletpb holds the protobuf messages and the gRPC service generated from proto/wallet/v1/wallet.proto.
+package walletpb
+
+//go:generate protoc -I ../../proto --go_out=. --go_opt=module=github.com/aminjonshermatov/wallet/pkg/walletpb --go-grpc_out=. --go-grpc_opt=module=github.com/aminjonshermatov/wallet/pkg/walletpb wallet/v1/wallet.proto
@@ -0,0 +1,1151 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             (unknown)
+// source: wallet/v1/wallet.proto
+
+package walletpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// WalletServiceClient is the client API for WalletService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type WalletServiceClient interface {
+	RegisterAccount(ctx context.Context, in *RegisterAccountRequest, opts ...grpc.CallOption) (*Account, error)
+	FindAccountByID(ctx context.Context, in *FindAccountByIDRequest, opts ...grpc.CallOption) (*Account, error)
+	Deposit(ctx context.Context, in *DepositRequest, opts ...grpc.CallOption) (*Account, error)
+	FreezeAccount(ctx context.Context, in *FreezeAccountRequest, opts ...grpc.CallOption) (*Account, error)
+	UnfreezeAccount(ctx context.Context, in *UnfreezeAccountRequest, opts ...grpc.CallOption) (*Account, error)
+	// CloseAccount closes an account with zero balance, or pays the balance out first when payout is set.
+	CloseAccount(ctx context.Context, in *CloseAccountRequest, opts ...grpc.CallOption) (*CloseAccountResponse, error)
+	SetCreditLimit(ctx context.Context, in *SetCreditLimitRequest, opts ...grpc.CallOption) (*Account, error)
+	Pay(ctx context.Context, in *PayRequest, opts ...grpc.CallOption) (*Payment, error)
+	FindPaymentByID(ctx context.Context, in *FindPaymentByIDRequest, opts ...grpc.CallOption) (*Payment, error)
+	Reject(ctx context.Context, in *RejectRequest, opts ...grpc.CallOption) (*Payment, error)
+	Repeat(ctx context.Context, in *RepeatRequest, opts ...grpc.CallOption) (*Payment, error)
+	Confirm(ctx context.Context, in *ConfirmRequest, opts ...grpc.CallOption) (*Payment, error)
+	Refund(ctx context.Context, in *RefundRequest, opts ...grpc.CallOption) (*Payment, error)
+	// Transfer returns the outgoing payment, linked to the incoming one.
+	Transfer(ctx context.Context, in *TransferRequest, opts ...grpc.CallOption) (*Payment, error)
+	FindPaymentsByAccount(ctx context.Context, in *FindPaymentsByAccountRequest, opts ...grpc.CallOption) (*FindPaymentsByAccountResponse, error)
+	FavoritePayment(ctx context.Context, in *FavoritePaymentRequest, opts ...grpc.CallOption) (*Favorite, error)
+	FindFavoriteByID(ctx context.Context, in *FindFavoriteByIDRequest, opts ...grpc.CallOption) (*Favorite, error)
+	ListFavorites(ctx context.Context, in *ListFavoritesRequest, opts ...grpc.CallOption) (*ListFavoritesResponse, error)
+	PayFromFavorite(ctx context.Context, in *PayFromFavoriteRequest, opts ...grpc.CallOption) (*Payment, error)
+	RenameFavorite(ctx context.Context, in *RenameFavoriteRequest, opts ...grpc.CallOption) (*Favorite, error)
+	UpdateFavoriteAmount(ctx context.Context, in *UpdateFavoriteAmountRequest, opts ...grpc.CallOption) (*Favorite, error)
+	// ReorderFavorites returns the favorites of the account in the new order.
+	ReorderFavorites(ctx context.Context, in *ReorderFavoritesRequest, opts ...grpc.CallOption) (*ListFavoritesResponse, error)
+	RemoveFavorite(ctx context.Context, in *RemoveFavoriteRequest, opts ...grpc.CallOption) (*RemoveFavoriteResponse, error)
+	// SetSpendingLimit adds the limit or replaces the amount of the limit with the same period and category.
+	SetSpendingLimit(ctx context.Context, in *SetSpendingLimitRequest, opts ...grpc.CallOption) (*SpendingLimit, error)
+	RemoveSpendingLimit(ctx context.Context, in *RemoveSpendingLimitRequest, opts ...grpc.CallOption) (*RemoveSpendingLimitResponse, error)
+	ListSpendingLimits(ctx context.Context, in *ListSpendingLimitsRequest, opts ...grpc.CallOption) (*ListSpendingLimitsResponse, error)
+	ExportAccountHistory(ctx context.Context, in *ExportAccountHistoryRequest, opts ...grpc.CallOption) (*ExportAccountHistoryResponse, error)
+	SumPayments(ctx context.Context, in *SumPaymentsRequest, opts ...grpc.CallOption) (*SumPaymentsResponse, error)
+	// SumPaymentsWithProgress streams partial sums as they are computed.
+	SumPaymentsWithProgress(ctx context.Context, in *SumPaymentsWithProgressRequest, opts ...grpc.CallOption) (WalletService_SumPaymentsWithProgressClient, error)
+}
+
+type walletServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewWalletServiceClient(cc grpc.ClientConnInterface) WalletServiceClient {
+	return &walletServiceClient{cc}
+}
+
+func (c *walletServiceClient) RegisterAccount(ctx context.Context, in *RegisterAccountRequest, opts ...grpc.CallOption) (*Account, error) {
+	out := new(Account)
+	err := c.cc.Invoke(ctx, "/wallet.v1.WalletService/RegisterAccount", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletServiceClient) FindAccountByID(ctx context.Context, in *FindAccountByIDRequest, opts ...grpc.CallOption) (*Account, error) {
+	out := new(Account)
+	err := c.cc.Invoke(ctx, "/wallet.v1.WalletService/FindAccountByID", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletServiceClient) Deposit(ctx context.Context, in *DepositRequest, opts ...grpc.CallOption) (*Account, error) {
+	out := new(Account)
+	err := c.cc.Invoke(ctx, "/wallet.v1.WalletService/Deposit", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletServiceClient) FreezeAccount(ctx context.Context, in *FreezeAccountRequest, opts ...grpc.CallOption) (*Account, error) {
+	out := new(Account)
+	err := c.cc.Invoke(ctx, "/wallet.v1.WalletService/FreezeAccount", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletServiceClient) UnfreezeAccount(ctx context.Context, in *UnfreezeAccountRequest, opts ...grpc.CallOption) (*Account, error) {
+	out := new(Account)
+	err := c.cc.Invoke(ctx, "/wallet.v1.WalletService/UnfreezeAccount", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletServiceClient) CloseAccount(ctx context.Context, in *CloseAccountRequest, opts ...grpc.CallOption) (*CloseAccountResponse, error) {
+	out := new(CloseAccountResponse)
+	err := c.cc.Invoke(ctx, "/wallet.v1.WalletService/CloseAccount", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletServiceClient) SetCreditLimit(ctx context.Context, in *SetCreditLimitRequest, opts ...grpc.CallOption) (*Account, error) {
+	out := new(Account)
+	err := c.cc.Invoke(ctx, "/wallet.v1.WalletService/SetCreditLimit", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletServiceClient) Pay(ctx context.Context, in *PayRequest, opts ...grpc.CallOption) (*Payment, error) {
+	out := new(Payment)
+	err := c.cc.Invoke(ctx, "/wallet.v1.WalletService/Pay", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletServiceClient) FindPaymentByID(ctx context.Context, in *FindPaymentByIDRequest, opts ...grpc.CallOption) (*Payment, error) {
+	out := new(Payment)
+	err := c.cc.Invoke(ctx, "/wallet.v1.WalletService/FindPaymentByID", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletServiceClient) Reject(ctx context.Context, in *RejectRequest, opts ...grpc.CallOption) (*Payment, error) {
+	out := new(Payment)
+	err := c.cc.Invoke(ctx, "/wallet.v1.WalletService/Reject", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletServiceClient) Repeat(ctx context.Context, in *RepeatRequest, opts ...grpc.CallOption) (*Payment, error) {
+	out := new(Payment)
+	err := c.cc.Invoke(ctx, "/wallet.v1.WalletService/Repeat", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletServiceClient) Confirm(ctx context.Context, in *ConfirmRequest, opts ...grpc.CallOption) (*Payment, error) {
+	out := new(Payment)
+	err := c.cc.Invoke(ctx, "/wallet.v1.WalletService/Confirm", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletServiceClient) Refund(ctx context.Context, in *RefundRequest, opts ...grpc.CallOption) (*Payment, error) {
+	out := new(Payment)
+	err := c.cc.Invoke(ctx, "/wallet.v1.WalletService/Refund", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletServiceClient) Transfer(ctx context.Context, in *TransferRequest, opts ...grpc.CallOption) (*Payment, error) {
+	out := new(Payment)
+	err := c.cc.Invoke(ctx, "/wallet.v1.WalletService/Transfer", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletServiceClient) FindPaymentsByAccount(ctx context.Context, in *FindPaymentsByAccountRequest, opts ...grpc.CallOption) (*FindPaymentsByAccountResponse, error) {
+	out := new(FindPaymentsByAccountResponse)
+	err := c.cc.Invoke(ctx, "/wallet.v1.WalletService/FindPaymentsByAccount", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletServiceClient) FavoritePayment(ctx context.Context, in *FavoritePaymentRequest, opts ...grpc.CallOption) (*Favorite, error) {
+	out := new(Favorite)
+	err := c.cc.Invoke(ctx, "/wallet.v1.WalletService/FavoritePayment", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletServiceClient) FindFavoriteByID(ctx context.Context, in *FindFavoriteByIDRequest, opts ...grpc.CallOption) (*Favorite, error) {
+	out := new(Favorite)
+	err := c.cc.Invoke(ctx, "/wallet.v1.WalletService/FindFavoriteByID", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletServiceClient) ListFavorites(ctx context.Context, in *ListFavoritesRequest, opts ...grpc.CallOption) (*ListFavoritesResponse, error) {
+	out := new(ListFavoritesResponse)
+	err := c.cc.Invoke(ctx, "/wallet.v1.WalletService/ListFavorites", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletServiceClient) PayFromFavorite(ctx context.Context, in *PayFromFavoriteRequest, opts ...grpc.CallOption) (*Payment, error) {
+	out := new(Payment)
+	err := c.cc.Invoke(ctx, "/wallet.v1.WalletService/PayFromFavorite", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletServiceClient) RenameFavorite(ctx context.Context, in *RenameFavoriteRequest, opts ...grpc.CallOption) (*Favorite, error) {
+	out := new(Favorite)
+	err := c.cc.Invoke(ctx, "/wallet.v1.WalletService/RenameFavorite", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletServiceClient) UpdateFavoriteAmount(ctx context.Context, in *UpdateFavoriteAmountRequest, opts ...grpc.CallOption) (*Favorite, error) {
+	out := new(Favorite)
+	err := c.cc.Invoke(ctx, "/wallet.v1.WalletService/UpdateFavoriteAmount", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletServiceClient) ReorderFavorites(ctx context.Context, in *ReorderFavoritesRequest, opts ...grpc.CallOption) (*ListFavoritesResponse, error) {
+	out := new(ListFavoritesResponse)
+	err := c.cc.Invoke(ctx, "/wallet.v1.WalletService/ReorderFavorites", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletServiceClient) RemoveFavorite(ctx context.Context, in *RemoveFavoriteRequest, opts ...grpc.CallOption) (*RemoveFavoriteResponse, error) {
+	out := new(RemoveFavoriteResponse)
+	err := c.cc.Invoke(ctx, "/wallet.v1.WalletService/RemoveFavorite", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletServiceClient) SetSpendingLimit(ctx context.Context, in *SetSpendingLimitRequest, opts ...grpc.CallOption) (*SpendingLimit, error) {
+	out := new(SpendingLimit)
+	err := c.cc.Invoke(ctx, "/wallet.v1.WalletService/SetSpendingLimit", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletServiceClient) RemoveSpendingLimit(ctx context.Context, in *RemoveSpendingLimitRequest, opts ...grpc.CallOption) (*RemoveSpendingLimitResponse, error) {
+	out := new(RemoveSpendingLimitResponse)
+	err := c.cc.Invoke(ctx, "/wallet.v1.WalletService/RemoveSpendingLimit", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletServiceClient) ListSpendingLimits(ctx context.Context, in *ListSpendingLimitsRequest, opts ...grpc.CallOption) (*ListSpendingLimitsResponse, error) {
+	out := new(ListSpendingLimitsResponse)
+	err := c.cc.Invoke(ctx, "/wallet.v1.WalletService/ListSpendingLimits", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletServiceClient) ExportAccountHistory(ctx context.Context, in *ExportAccountHistoryRequest, opts ...grpc.CallOption) (*ExportAccountHistoryResponse, error) {
+	out := new(ExportAccountHistoryResponse)
+	err := c.cc.Invoke(ctx, "/wallet.v1.WalletService/ExportAccountHistory", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletServiceClient) SumPayments(ctx context.Context, in *SumPaymentsRequest, opts ...grpc.CallOption) (*SumPaymentsResponse, error) {
+	out := new(SumPaymentsResponse)
+	err := c.cc.Invoke(ctx, "/wallet.v1.WalletService/SumPayments", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletServiceClient) SumPaymentsWithProgress(ctx context.Context, in *SumPaymentsWithProgressRequest, opts ...grpc.CallOption) (WalletService_SumPaymentsWithProgressClient, error) {
+	stream, err := c.cc.NewStream(ctx, &WalletService_ServiceDesc.Streams[0], "/wallet.v1.WalletService/SumPaymentsWithProgress", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &walletServiceSumPaymentsWithProgressClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type WalletService_SumPaymentsWithProgressClient interface {
+	Recv() (*Progress, error)
+	grpc.ClientStream
+}
+
+type walletServiceSumPaymentsWithProgressClient struct {
+	grpc.ClientStream
+}
+
+func (x *walletServiceSumPaymentsWithProgressClient) Recv() (*Progress, error) {
+	m := new(Progress)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// WalletServiceServer is the server API for WalletService service.
+// All implementations must embed UnimplementedWalletServiceServer
+// for forward compatibility
+type WalletServiceServer interface {
+	RegisterAccount(context.Context, *RegisterAccountRequest) (*Account, error)
+	FindAccountByID(context.Context, *FindAccountByIDRequest) (*Account, error)
+	Deposit(context.Context, *DepositRequest) (*Account, error)
+	FreezeAccount(context.Context, *FreezeAccountRequest) (*Account, error)
+	UnfreezeAccount(context.Context, *UnfreezeAccountRequest) (*Account, error)
+	// CloseAccount closes an account with zero balance, or pays the balance out first when payout is set.
+	CloseAccount(context.Context, *CloseAccountRequest) (*CloseAccountResponse, error)
+	SetCreditLimit(context.Context, *SetCreditLimitRequest) (*Account, error)
+	Pay(context.Context, *PayRequest) (*Payment, error)
+	FindPaymentByID(context.Context, *FindPaymentByIDRequest) (*Payment, error)
+	Reject(context.Context, *RejectRequest) (*Payment, error)
+	Repeat(context.Context, *RepeatRequest) (*Payment, error)
+	Confirm(context.Context, *ConfirmRequest) (*Payment, error)
+	Refund(context.Context, *RefundRequest) (*Payment, error)
+	// Transfer returns the outgoing payment, linked to the incoming one.
+	Transfer(context.Context, *TransferRequest) (*Payment, error)
+	FindPaymentsByAccount(context.Context, *FindPaymentsByAccountRequest) (*FindPaymentsByAccountResponse, error)
+	FavoritePayment(context.Context, *FavoritePaymentRequest) (*Favorite, error)
+	FindFavoriteByID(context.Context, *FindFavoriteByIDRequest) (*Favorite, error)
+	ListFavorites(context.Context, *ListFavoritesRequest) (*ListFavoritesResponse, error)
+	PayFromFavorite(context.Context, *PayFromFavoriteRequest) (*Payment, error)
+	RenameFavorite(context.Context, *RenameFavoriteRequest) (*Favorite, error)
+	UpdateFavoriteAmount(context.Context, *UpdateFavoriteAmountRequest) (*Favorite, error)
+	// ReorderFavorites returns the favorites of the account in the new order.
+	ReorderFavorites(context.Context, *ReorderFavoritesRequest) (*ListFavoritesResponse, error)
+	RemoveFavorite(context.Context, *RemoveFavoriteRequest) (*RemoveFavoriteResponse, error)
+	// SetSpendingLimit adds the limit or replaces the amount of the limit with the same period and category.
+	SetSpendingLimit(context.Context, *SetSpendingLimitRequest) (*SpendingLimit, error)
+	RemoveSpendingLimit(context.Context, *RemoveSpendingLimitRequest) (*RemoveSpendingLimitResponse, error)
+	ListSpendingLimits(context.Context, *ListSpendingLimitsRequest) (*ListSpendingLimitsResponse, error)
+	ExportAccountHistory(context.Context, *ExportAccountHistoryRequest) (*ExportAccountHistoryResponse, error)
+	SumPayments(context.Context, *SumPaymentsRequest) (*SumPaymentsResponse, error)
+	// SumPaymentsWithProgress streams partial sums as they are computed.
+	SumPaymentsWithProgress(*SumPaymentsWithProgressRequest, WalletService_SumPaymentsWithProgressServer) error
+	mustEmbedUnimplementedWalletServiceServer()
+}
+
+// UnimplementedWalletServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedWalletServiceServer struct {
+}
+
+func (UnimplementedWalletServiceServer) RegisterAccount(context.Context, *RegisterAccountRequest) (*Account, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RegisterAccount not implemented")
+}
+func (UnimplementedWalletServiceServer) FindAccountByID(context.Context, *FindAccountByIDRequest) (*Account, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FindAccountByID not implemented")
+}
+func (UnimplementedWalletServiceServer) Deposit(context.Context, *DepositRequest) (*Account, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Deposit not implemented")
+}
+func (UnimplementedWalletServiceServer) FreezeAccount(context.Context, *FreezeAccountRequest) (*Account, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FreezeAccount not implemented")
+}
+func (UnimplementedWalletServiceServer) UnfreezeAccount(context.Context, *UnfreezeAccountRequest) (*Account, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnfreezeAccount not implemented")
+}
+func (UnimplementedWalletServiceServer) CloseAccount(context.Context, *CloseAccountRequest) (*CloseAccountResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CloseAccount not implemented")
+}
+func (UnimplementedWalletServiceServer) SetCreditLimit(context.Context, *SetCreditLimitRequest) (*Account, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetCreditLimit not implemented")
+}
+func (UnimplementedWalletServiceServer) Pay(context.Context, *PayRequest) (*Payment, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Pay not implemented")
+}
+func (UnimplementedWalletServiceServer) FindPaymentByID(context.Context, *FindPaymentByIDRequest) (*Payment, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FindPaymentByID not implemented")
+}
+func (UnimplementedWalletServiceServer) Reject(context.Context, *RejectRequest) (*Payment, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Reject not implemented")
+}
+func (UnimplementedWalletServiceServer) Repeat(context.Context, *RepeatRequest) (*Payment, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Repeat not implemented")
+}
+func (UnimplementedWalletServiceServer) Confirm(context.Context, *ConfirmRequest) (*Payment, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Confirm not implemented")
+}
+func (UnimplementedWalletServiceServer) Refund(context.Context, *RefundRequest) (*Payment, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Refund not implemented")
+}
+func (UnimplementedWalletServiceServer) Transfer(context.Context, *TransferRequest) (*Payment, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Transfer not implemented")
+}
+func (UnimplementedWalletServiceServer) FindPaymentsByAccount(context.Context, *FindPaymentsByAccountRequest) (*FindPaymentsByAccountResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FindPaymentsByAccount not implemented")
+}
+func (UnimplementedWalletServiceServer) FavoritePayment(context.Context, *FavoritePaymentRequest) (*Favorite, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FavoritePayment not implemented")
+}
+func (UnimplementedWalletServiceServer) FindFavoriteByID(context.Context, *FindFavoriteByIDRequest) (*Favorite, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FindFavoriteByID not implemented")
+}
+func (UnimplementedWalletServiceServer) ListFavorites(context.Context, *ListFavoritesRequest) (*ListFavoritesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListFavorites not implemented")
+}
+func (UnimplementedWalletServiceServer) PayFromFavorite(context.Context, *PayFromFavoriteRequest) (*Payment, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PayFromFavorite not implemented")
+}
+func (UnimplementedWalletServiceServer) RenameFavorite(context.Context, *RenameFavoriteRequest) (*Favorite, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RenameFavorite not implemented")
+}
+func (UnimplementedWalletServiceServer) UpdateFavoriteAmount(context.Context, *UpdateFavoriteAmountRequest) (*Favorite, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateFavoriteAmount not implemented")
+}
+func (UnimplementedWalletServiceServer) ReorderFavorites(context.Context, *ReorderFavoritesRequest) (*ListFavoritesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReorderFavorites not implemented")
+}
+func (UnimplementedWalletServiceServer) RemoveFavorite(context.Context, *RemoveFavoriteRequest) (*RemoveFavoriteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveFavorite not implemented")
+}
+func (UnimplementedWalletServiceServer) SetSpendingLimit(context.Context, *SetSpendingLimitRequest) (*SpendingLimit, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetSpendingLimit not implemented")
+}
+func (UnimplementedWalletServiceServer) RemoveSpendingLimit(context.Context, *RemoveSpendingLimitRequest) (*RemoveSpendingLimitResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveSpendingLimit not implemented")
+}
+func (UnimplementedWalletServiceServer) ListSpendingLimits(context.Context, *ListSpendingLimitsRequest) (*ListSpendingLimitsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSpendingLimits not implemented")
+}
+func (UnimplementedWalletServiceServer) ExportAccountHistory(context.Context, *ExportAccountHistoryRequest) (*ExportAccountHistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ExportAccountHistory not implemented")
+}
+func (UnimplementedWalletServiceServer) SumPayments(context.Context, *SumPaymentsRequest) (*SumPaymentsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SumPayments not implemented")
+}
+func (UnimplementedWalletServiceServer) SumPaymentsWithProgress(*SumPaymentsWithProgressRequest, WalletService_SumPaymentsWithProgressServer) error {
+	return status.Errorf(codes.Unimplemented, "method SumPaymentsWithProgress not implemented")
+}
+func (UnimplementedWalletServiceServer) mustEmbedUnimplementedWalletServiceServer() {}
+
+// UnsafeWalletServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to WalletServiceServer will
+// result in compilation errors.
+type UnsafeWalletServiceServer interface {
+	mustEmbedUnimplementedWalletServiceServer()
+}
+
+func RegisterWalletServiceServer(s grpc.ServiceRegistrar, srv WalletServiceServer) {
+	s.RegisterService(&WalletService_ServiceDesc, srv)
+}
+
+func _WalletService_RegisterAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterAccountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServiceServer).RegisterAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/wallet.v1.WalletService/RegisterAccount",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServiceServer).RegisterAccount(ctx, req.(*RegisterAccountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WalletService_FindAccountByID_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FindAccountByIDRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServiceServer).FindAccountByID(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/wallet.v1.WalletService/FindAccountByID",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServiceServer).FindAccountByID(ctx, req.(*FindAccountByIDRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WalletService_Deposit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DepositRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServiceServer).Deposit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/wallet.v1.WalletService/Deposit",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServiceServer).Deposit(ctx, req.(*DepositRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WalletService_FreezeAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FreezeAccountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServiceServer).FreezeAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/wallet.v1.WalletService/FreezeAccount",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServiceServer).FreezeAccount(ctx, req.(*FreezeAccountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WalletService_UnfreezeAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnfreezeAccountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServiceServer).UnfreezeAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/wallet.v1.WalletService/UnfreezeAccount",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServiceServer).UnfreezeAccount(ctx, req.(*UnfreezeAccountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WalletService_CloseAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CloseAccountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServiceServer).CloseAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/wallet.v1.WalletService/CloseAccount",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServiceServer).CloseAccount(ctx, req.(*CloseAccountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WalletService_SetCreditLimit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetCreditLimitRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServiceServer).SetCreditLimit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/wallet.v1.WalletService/SetCreditLimit",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServiceServer).SetCreditLimit(ctx, req.(*SetCreditLimitRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WalletService_Pay_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PayRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServiceServer).Pay(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/wallet.v1.WalletService/Pay",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServiceServer).Pay(ctx, req.(*PayRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WalletService_FindPaymentByID_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FindPaymentByIDRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServiceServer).FindPaymentByID(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/wallet.v1.WalletService/FindPaymentByID",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServiceServer).FindPaymentByID(ctx, req.(*FindPaymentByIDRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WalletService_Reject_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RejectRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServiceServer).Reject(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/wallet.v1.WalletService/Reject",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServiceServer).Reject(ctx, req.(*RejectRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WalletService_Repeat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RepeatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServiceServer).Repeat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/wallet.v1.WalletService/Repeat",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServiceServer).Repeat(ctx, req.(*RepeatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WalletService_Confirm_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ConfirmRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServiceServer).Confirm(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/wallet.v1.WalletService/Confirm",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServiceServer).Confirm(ctx, req.(*ConfirmRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WalletService_Refund_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RefundRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServiceServer).Refund(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/wallet.v1.WalletService/Refund",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServiceServer).Refund(ctx, req.(*RefundRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WalletService_Transfer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TransferRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServiceServer).Transfer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/wallet.v1.WalletService/Transfer",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServiceServer).Transfer(ctx, req.(*TransferRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WalletService_FindPaymentsByAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FindPaymentsByAccountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServiceServer).FindPaymentsByAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/wallet.v1.WalletService/FindPaymentsByAccount",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServiceServer).FindPaymentsByAccount(ctx, req.(*FindPaymentsByAccountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WalletService_FavoritePayment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FavoritePaymentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServiceServer).FavoritePayment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/wallet.v1.WalletService/FavoritePayment",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServiceServer).FavoritePayment(ctx, req.(*FavoritePaymentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WalletService_FindFavoriteByID_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FindFavoriteByIDRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServiceServer).FindFavoriteByID(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/wallet.v1.WalletService/FindFavoriteByID",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServiceServer).FindFavoriteByID(ctx, req.(*FindFavoriteByIDRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WalletService_ListFavorites_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListFavoritesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServiceServer).ListFavorites(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/wallet.v1.WalletService/ListFavorites",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServiceServer).ListFavorites(ctx, req.(*ListFavoritesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WalletService_PayFromFavorite_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PayFromFavoriteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServiceServer).PayFromFavorite(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/wallet.v1.WalletService/PayFromFavorite",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServiceServer).PayFromFavorite(ctx, req.(*PayFromFavoriteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WalletService_RenameFavorite_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RenameFavoriteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServiceServer).RenameFavorite(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/wallet.v1.WalletService/RenameFavorite",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServiceServer).RenameFavorite(ctx, req.(*RenameFavoriteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WalletService_UpdateFavoriteAmount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateFavoriteAmountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServiceServer).UpdateFavoriteAmount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/wallet.v1.WalletService/UpdateFavoriteAmount",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServiceServer).UpdateFavoriteAmount(ctx, req.(*UpdateFavoriteAmountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WalletService_ReorderFavorites_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReorderFavoritesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServiceServer).ReorderFavorites(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/wallet.v1.WalletService/ReorderFavorites",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServiceServer).ReorderFavorites(ctx, req.(*ReorderFavoritesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WalletService_RemoveFavorite_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveFavoriteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServiceServer).RemoveFavorite(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/wallet.v1.WalletService/RemoveFavorite",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServiceServer).RemoveFavorite(ctx, req.(*RemoveFavoriteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WalletService_SetSpendingLimit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetSpendingLimitRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServiceServer).SetSpendingLimit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/wallet.v1.WalletService/SetSpendingLimit",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServiceServer).SetSpendingLimit(ctx, req.(*SetSpendingLimitRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WalletService_RemoveSpendingLimit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveSpendingLimitRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServiceServer).RemoveSpendingLimit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/wallet.v1.WalletService/RemoveSpendingLimit",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServiceServer).RemoveSpendingLimit(ctx, req.(*RemoveSpendingLimitRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WalletService_ListSpendingLimits_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSpendingLimitsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServiceServer).ListSpendingLimits(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/wallet.v1.WalletService/ListSpendingLimits",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServiceServer).ListSpendingLimits(ctx, req.(*ListSpendingLimitsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WalletService_ExportAccountHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExportAccountHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServiceServer).ExportAccountHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/wallet.v1.WalletService/ExportAccountHistory",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServiceServer).ExportAccountHistory(ctx, req.(*ExportAccountHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WalletService_SumPayments_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SumPaymentsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletServiceServer).SumPayments(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/wallet.v1.WalletService/SumPayments",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletServiceServer).SumPayments(ctx, req.(*SumPaymentsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WalletService_SumPaymentsWithProgress_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SumPaymentsWithProgressRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(WalletServiceServer).SumPaymentsWithProgress(m, &walletServiceSumPaymentsWithProgressServer{stream})
+}
+
+type WalletService_SumPaymentsWithProgressServer interface {
+	Send(*Progress) error
+	grpc.ServerStream
+}
+
+type walletServiceSumPaymentsWithProgressServer struct {
+	grpc.ServerStream
+}
+
+func (x *walletServiceSumPaymentsWithProgressServer) Send(m *Progress) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// WalletService_ServiceDesc is the grpc.ServiceDesc for WalletService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var WalletService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "wallet.v1.WalletService",
+	HandlerType: (*WalletServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "RegisterAccount",
+			Handler:    _WalletService_RegisterAccount_Handler,
+		},
+		{
+			MethodName: "FindAccountByID",
+			Handler:    _WalletService_FindAccountByID_Handler,
+		},
+		{
+			MethodName: "Deposit",
+			Handler:    _WalletService_Deposit_Handler,
+		},
+		{
+			MethodName: "FreezeAccount",
+			Handler:    _WalletService_FreezeAccount_Handler,
+		},
+		{
+			MethodName: "UnfreezeAccount",
+			Handler:    _WalletService_UnfreezeAccount_Handler,
+		},
+		{
+			MethodName: "CloseAccount",
+			Handler:    _WalletService_CloseAccount_Handler,
+		},
+		{
+			MethodName: "SetCreditLimit",
+			Handler:    _WalletService_SetCreditLimit_Handler,
+		},
+		{
+			MethodName: "Pay",
+			Handler:    _WalletService_Pay_Handler,
+		},
+		{
+			MethodName: "FindPaymentByID",
+			Handler:    _WalletService_FindPaymentByID_Handler,
+		},
+		{
+			MethodName: "Reject",
+			Handler:    _WalletService_Reject_Handler,
+		},
+		{
+			MethodName: "Repeat",
+			Handler:    _WalletService_Repeat_Handler,
+		},
+		{
+			MethodName: "Confirm",
+			Handler:    _WalletService_Confirm_Handler,
+		},
+		{
+			MethodName: "Refund",
+			Handler:    _WalletService_Refund_Handler,
+		},
+		{
+			MethodName: "Transfer",
+			Handler:    _WalletService_Transfer_Handler,
+		},
+		{
+			MethodName: "FindPaymentsByAccount",
+			Handler:    _WalletService_FindPaymentsByAccount_Handler,
+		},
+		{
+			MethodName: "FavoritePayment",
+			Handler:    _WalletService_FavoritePayment_Handler,
+		},
+		{
+			MethodName: "FindFavoriteByID",
+			Handler:    _WalletService_FindFavoriteByID_Handler,
+		},
+		{
+			MethodName: "ListFavorites",
+			Handler:    _WalletService_ListFavorites_Handler,
+		},
+		{
+			MethodName: "PayFromFavorite",
+			Handler:    _WalletService_PayFromFavorite_Handler,
+		},
+		{
+			MethodName: "RenameFavorite",
+			Handler:    _WalletService_RenameFavorite_Handler,
+		},
+		{
+			MethodName: "UpdateFavoriteAmount",
+			Handler:    _WalletService_UpdateFavoriteAmount_Handler,
+		},
+		{
+			MethodName: "ReorderFavorites",
+			Handler:    _WalletService_ReorderFavorites_Handler,
+		},
+		{
+			MethodName: "RemoveFavorite",
+			Handler:    _WalletService_RemoveFavorite_Handler,
+		},
+		{
+			MethodName: "SetSpendingLimit",
+			Handler:    _WalletService_SetSpendingLimit_Handler,
+		},
+		{
+			MethodName: "RemoveSpendingLimit",
+			Handler:    _WalletService_RemoveSpendingLimit_Handler,
+		},
+		{
+			MethodName: "ListSpendingLimits",
+			Handler:    _WalletService_ListSpendingLimits_Handler,
+		},
+		{
+			MethodName: "ExportAccountHistory",
+			Handler:    _WalletService_ExportAccountHistory_Handler,
+		},
+		{
+			MethodName: "SumPayments",
+			Handler:    _WalletService_SumPayments_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SumPaymentsWithProgress",
+			Handler:       _WalletService_SumPaymentsWithProgress_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "wallet/v1/wallet.proto",
+}